@@ -10,6 +10,7 @@ package api
 
 import (
 	"unicode/utf16"
+	"unicode/utf8"
 )
 
 type (
@@ -32,6 +33,16 @@ type (
 		Fraction SQLUINTEGER
 	}
 
+	// SQL_NUMERIC_STRUCT stores exact decimal value. Val is unscaled
+	// value in little endian order, Sign is 1 for positive values
+	// and 0 for negative.
+	SQL_NUMERIC_STRUCT struct {
+		Precision uint8
+		Scale     int8
+		Sign      uint8
+		Val       [SQL_MAX_NUMERIC_LEN]uint8
+	}
+
 	SQL_TIMESTAMP_STRUCT struct {
 		Year     SQLSMALLINT
 		Month    SQLUSMALLINT
@@ -46,36 +57,76 @@ type (
 //sys	SQLAllocHandle(handleType SQLSMALLINT, inputHandle SQLHANDLE, outputHandle *SQLHANDLE) (ret SQLRETURN) = odbc32.SQLAllocHandle
 //sys	SQLBindCol(statementHandle SQLHSTMT, columnNumber SQLUSMALLINT, targetType SQLSMALLINT, targetValuePtr SQLPOINTER, bufferLength SQLLEN, vallen *SQLLEN) (ret SQLRETURN) = odbc32.SQLBindCol
 //sys	SQLBindParameter(statementHandle SQLHSTMT, parameterNumber SQLUSMALLINT, inputOutputType SQLSMALLINT, valueType SQLSMALLINT, parameterType SQLSMALLINT, columnSize SQLULEN, decimalDigits SQLSMALLINT, parameterValue SQLPOINTER, bufferLength SQLLEN, ind *SQLLEN) (ret SQLRETURN) = odbc32.SQLBindParameter
+//sys	SQLBrowseConnect(connectionHandle SQLHDBC, inConnectionString *SQLWCHAR, stringLength1 SQLSMALLINT, outConnectionString *SQLWCHAR, bufferLength SQLSMALLINT, stringLength2Ptr *SQLSMALLINT) (ret SQLRETURN) = odbc32.SQLBrowseConnectW
+//sys	SQLBulkOperations(statementHandle SQLHSTMT, operation SQLSMALLINT) (ret SQLRETURN) = odbc32.SQLBulkOperations
+//sys	SQLCancel(statementHandle SQLHSTMT) (ret SQLRETURN) = odbc32.SQLCancel
 //sys	SQLCloseCursor(statementHandle SQLHSTMT) (ret SQLRETURN) = odbc32.SQLCloseCursor
+//sys	SQLColAttribute(statementHandle SQLHSTMT, columnNumber SQLUSMALLINT, fieldIdentifier SQLUSMALLINT, characterAttributePtr SQLPOINTER, bufferLength SQLSMALLINT, stringLengthPtr *SQLSMALLINT, numericAttributePtr *SQLLEN) (ret SQLRETURN) = odbc32.SQLColAttributeW
+//sys	SQLConnect(connectionHandle SQLHDBC, serverName *SQLWCHAR, nameLength1 SQLSMALLINT, userName *SQLWCHAR, nameLength2 SQLSMALLINT, authentication *SQLWCHAR, nameLength3 SQLSMALLINT) (ret SQLRETURN) = odbc32.SQLConnectW
+//sys	SQLDataSources(environmentHandle SQLHENV, direction SQLUSMALLINT, serverName *SQLWCHAR, bufferLength1 SQLSMALLINT, nameLength1Ptr *SQLSMALLINT, description *SQLWCHAR, bufferLength2 SQLSMALLINT, nameLength2Ptr *SQLSMALLINT) (ret SQLRETURN) = odbc32.SQLDataSourcesW
 //sys	SQLDescribeCol(statementHandle SQLHSTMT, columnNumber SQLUSMALLINT, columnName *SQLWCHAR, bufferLength SQLSMALLINT, nameLengthPtr *SQLSMALLINT, dataTypePtr *SQLSMALLINT, columnSizePtr *SQLULEN, decimalDigitsPtr *SQLSMALLINT, nullablePtr *SQLSMALLINT) (ret SQLRETURN) = odbc32.SQLDescribeColW
 //sys	SQLDescribeParam(statementHandle SQLHSTMT, parameterNumber SQLUSMALLINT, dataTypePtr *SQLSMALLINT, parameterSizePtr *SQLULEN, decimalDigitsPtr *SQLSMALLINT, nullablePtr *SQLSMALLINT) (ret SQLRETURN) = odbc32.SQLDescribeParam
 //sys	SQLDisconnect(connectionHandle SQLHDBC) (ret SQLRETURN) = odbc32.SQLDisconnect
 //sys	SQLDriverConnect(connectionHandle SQLHDBC, windowHandle SQLHWND, inConnectionString *SQLWCHAR, stringLength1 SQLSMALLINT, outConnectionString *SQLWCHAR, bufferLength SQLSMALLINT, stringLength2Ptr *SQLSMALLINT, driverCompletion SQLUSMALLINT) (ret SQLRETURN) = odbc32.SQLDriverConnectW
+//sys	SQLDrivers(environmentHandle SQLHENV, direction SQLUSMALLINT, driverDescription *SQLWCHAR, bufferLength1 SQLSMALLINT, descriptionLengthPtr *SQLSMALLINT, driverAttributes *SQLWCHAR, bufferLength2 SQLSMALLINT, attributesLengthPtr *SQLSMALLINT) (ret SQLRETURN) = odbc32.SQLDriversW
 //sys	SQLEndTran(handleType SQLSMALLINT, handle SQLHANDLE, completionType SQLSMALLINT) (ret SQLRETURN) = odbc32.SQLEndTran
 //sys	SQLExecute(statementHandle SQLHSTMT) (ret SQLRETURN) = odbc32.SQLExecute
 //sys	SQLFetch(statementHandle SQLHSTMT) (ret SQLRETURN) = odbc32.SQLFetch
 //sys	SQLFreeHandle(handleType SQLSMALLINT, handle SQLHANDLE) (ret SQLRETURN) = odbc32.SQLFreeHandle
+//sys	SQLFreeStmt(statementHandle SQLHSTMT, option SQLUSMALLINT) (ret SQLRETURN) = odbc32.SQLFreeStmt
+//sys	SQLGetConnectAttr(connectionHandle SQLHDBC, attribute SQLINTEGER, valuePtr SQLPOINTER, bufferLength SQLINTEGER, stringLengthPtr *SQLINTEGER) (ret SQLRETURN) = odbc32.SQLGetConnectAttrW
 //sys	SQLGetData(statementHandle SQLHSTMT, colOrParamNum SQLUSMALLINT, targetType SQLSMALLINT, targetValuePtr SQLPOINTER, bufferLength SQLLEN, vallen *SQLLEN) (ret SQLRETURN) = odbc32.SQLGetData
 //sys	SQLGetDiagRec(handleType SQLSMALLINT, handle SQLHANDLE, recNumber SQLSMALLINT, sqlState *SQLWCHAR, nativeErrorPtr *SQLINTEGER, messageText *SQLWCHAR, bufferLength SQLSMALLINT, textLengthPtr *SQLSMALLINT) (ret SQLRETURN) = odbc32.SQLGetDiagRecW
+//sys	SQLGetFunctions(connectionHandle SQLHDBC, functionId SQLUSMALLINT, supportedPtr *SQLUSMALLINT) (ret SQLRETURN) = odbc32.SQLGetFunctions
+//sys	SQLGetInfo(connectionHandle SQLHDBC, infoType SQLUSMALLINT, infoValuePtr SQLPOINTER, bufferLength SQLSMALLINT, stringLengthPtr *SQLSMALLINT) (ret SQLRETURN) = odbc32.SQLGetInfoW
+//sys	SQLGetStmtAttr(statementHandle SQLHSTMT, attribute SQLINTEGER, valuePtr SQLPOINTER, bufferLength SQLINTEGER, stringLengthPtr *SQLINTEGER) (ret SQLRETURN) = odbc32.SQLGetStmtAttrW
 //sys	SQLNumParams(statementHandle SQLHSTMT, parameterCountPtr *SQLSMALLINT) (ret SQLRETURN) = odbc32.SQLNumParams
 //sys	SQLMoreResults(statementHandle SQLHSTMT) (ret SQLRETURN) = odbc32.SQLMoreResults
 //sys	SQLNumResultCols(statementHandle SQLHSTMT, columnCountPtr *SQLSMALLINT)  (ret SQLRETURN) = odbc32.SQLNumResultCols
 //sys	SQLPrepare(statementHandle SQLHSTMT, statementText *SQLWCHAR, textLength SQLINTEGER) (ret SQLRETURN) = odbc32.SQLPrepareW
+//sys	SQLProcedureColumns(statementHandle SQLHSTMT, catalogName *SQLWCHAR, nameLength1 SQLSMALLINT, schemaName *SQLWCHAR, nameLength2 SQLSMALLINT, procName *SQLWCHAR, nameLength3 SQLSMALLINT, columnName *SQLWCHAR, nameLength4 SQLSMALLINT) (ret SQLRETURN) = odbc32.SQLProcedureColumnsW
 //sys	SQLRowCount(statementHandle SQLHSTMT, rowCountPtr *SQLLEN) (ret SQLRETURN) = odbc32.SQLRowCount
 //sys	SQLSetEnvAttr(environmentHandle SQLHENV, attribute SQLINTEGER, valuePtr SQLPOINTER, stringLength SQLINTEGER) (ret SQLRETURN) = odbc32.SQLSetEnvAttr
+//sys	SQLSetDescField(descriptorHandle SQLHDESC, recNumber SQLSMALLINT, fieldIdentifier SQLSMALLINT, valuePtr SQLPOINTER, bufferLength SQLINTEGER) (ret SQLRETURN) = odbc32.SQLSetDescFieldW
 //sys	SQLSetConnectAttr(connectionHandle SQLHDBC, attribute SQLINTEGER, valuePtr SQLPOINTER, stringLength SQLINTEGER) (ret SQLRETURN) = odbc32.SQLSetConnectAttrW
-//sys	SQLCancel(statementHandle SQLHSTMT) (ret SQLRETURN) = odbc32.SQLCancel
+//sys	SQLSetPos(statementHandle SQLHSTMT, rowNumber SQLSETPOSIROW, operation SQLUSMALLINT, lockType SQLUSMALLINT) (ret SQLRETURN) = odbc32.SQLSetPos
+//sys	SQLSetStmtAttr(statementHandle SQLHSTMT, attribute SQLINTEGER, valuePtr SQLPOINTER, stringLength SQLINTEGER) (ret SQLRETURN) = odbc32.SQLSetStmtAttrW
 
 // UTF16ToString returns the UTF-8 encoding of the UTF-16 sequence s,
 // with a terminating NUL removed.
 func UTF16ToString(s []uint16) string {
+	return string(UTF16ToUTF8(s))
+}
+
+// UTF16ToUTF8 is like UTF16ToString, but returns []byte. Characters
+// outside of the Basic Multilingual Plane (like emoji) are decoded
+// from surrogate pairs. Unpaired surrogates are replaced with
+// U+FFFD, as utf16.Decode does.
+func UTF16ToUTF8(s []uint16) []byte {
 	for i, v := range s {
 		if v == 0 {
 			s = s[0:i]
 			break
 		}
 	}
-	return string(utf16.Decode(s))
+	buf := make([]byte, 0, len(s)*2) // allow 2 bytes for every rune
+	var b [utf8.UTFMax]byte
+	for i := 0; i < len(s); i++ {
+		r := rune(s[i])
+		if utf16.IsSurrogate(r) {
+			if i+1 < len(s) {
+				r = utf16.DecodeRune(r, rune(s[i+1]))
+			} else {
+				r = utf8.RuneError
+			}
+			if r != utf8.RuneError {
+				i++
+			}
+		}
+		n := utf8.EncodeRune(b[:], r)
+		buf = append(buf, b[:n]...)
+	}
+	return buf
 }
 
 // StringToUTF16 returns the UTF-16 encoding of the UTF-8 string s,
@@ -23,6 +23,14 @@ SQLRETURN sqlSetEnvUIntPtrAttr(SQLHENV environmentHandle, SQLINTEGER attribute,
 SQLRETURN sqlSetConnectUIntPtrAttr(SQLHDBC connectionHandle, SQLINTEGER attribute, uintptr_t valuePtr, SQLINTEGER stringLength) {
 	return SQLSetConnectAttr(connectionHandle, attribute, (SQLPOINTER)valuePtr, stringLength);
 }
+
+SQLRETURN sqlSetDescUIntPtrField(SQLHDESC descriptorHandle, SQLSMALLINT recNumber, SQLSMALLINT fieldIdentifier, uintptr_t valuePtr, SQLINTEGER bufferLength) {
+	return SQLSetDescField(descriptorHandle, recNumber, fieldIdentifier, (SQLPOINTER)valuePtr, bufferLength);
+}
+
+SQLRETURN sqlSetStmtUIntPtrAttr(SQLHSTMT statementHandle, SQLINTEGER attribute, uintptr_t valuePtr, SQLINTEGER stringLength) {
+	return SQLSetStmtAttr(statementHandle, attribute, (SQLPOINTER)valuePtr, stringLength);
+}
 */
 import "C"
 
@@ -36,11 +44,14 @@ const (
 	SQL_HANDLE_ENV  = C.SQL_HANDLE_ENV
 	SQL_HANDLE_DBC  = C.SQL_HANDLE_DBC
 	SQL_HANDLE_STMT = C.SQL_HANDLE_STMT
+	SQL_HANDLE_DESC = C.SQL_HANDLE_DESC
 
 	SQL_SUCCESS            = C.SQL_SUCCESS
 	SQL_SUCCESS_WITH_INFO  = C.SQL_SUCCESS_WITH_INFO
 	SQL_INVALID_HANDLE     = C.SQL_INVALID_HANDLE
 	SQL_NO_DATA            = C.SQL_NO_DATA
+	SQL_NEED_DATA          = C.SQL_NEED_DATA
+	SQL_STILL_EXECUTING    = C.SQL_STILL_EXECUTING
 	SQL_NO_TOTAL           = C.SQL_NO_TOTAL
 	SQL_NTS                = C.SQL_NTS
 	SQL_MAX_MESSAGE_LENGTH = C.SQL_MAX_MESSAGE_LENGTH
@@ -49,10 +60,19 @@ const (
 	SQL_NULL_HDBC          = uintptr(C.SQL_NULL_HDBC)
 	SQL_NULL_HSTMT         = uintptr(C.SQL_NULL_HSTMT)
 
-	SQL_PARAM_INPUT = C.SQL_PARAM_INPUT
+	SQL_PARAM_INPUT        = C.SQL_PARAM_INPUT
+	SQL_PARAM_INPUT_OUTPUT = C.SQL_PARAM_INPUT_OUTPUT
+	SQL_PARAM_OUTPUT       = C.SQL_PARAM_OUTPUT
+	SQL_RESULT_COL         = C.SQL_RESULT_COL
+	SQL_RETURN_VALUE       = C.SQL_RETURN_VALUE
 
-	SQL_NULL_DATA    = C.SQL_NULL_DATA
-	SQL_DATA_AT_EXEC = C.SQL_DATA_AT_EXEC
+	SQL_NO_NULLS         = C.SQL_NO_NULLS
+	SQL_NULLABLE         = C.SQL_NULLABLE
+	SQL_NULLABLE_UNKNOWN = C.SQL_NULLABLE_UNKNOWN
+
+	SQL_NULL_DATA     = C.SQL_NULL_DATA
+	SQL_DATA_AT_EXEC  = C.SQL_DATA_AT_EXEC
+	SQL_DEFAULT_PARAM = C.SQL_DEFAULT_PARAM
 
 	SQL_UNKNOWN_TYPE    = C.SQL_UNKNOWN_TYPE
 	SQL_CHAR            = C.SQL_CHAR
@@ -110,13 +130,105 @@ const (
 	SQL_COMMIT   = C.SQL_COMMIT
 	SQL_ROLLBACK = C.SQL_ROLLBACK
 
+	SQL_FETCH_NEXT     = C.SQL_FETCH_NEXT
+	SQL_FETCH_FIRST    = C.SQL_FETCH_FIRST
+	SQL_MAX_DSN_LENGTH = C.SQL_MAX_DSN_LENGTH
+
+	SQL_CLOSE  = C.SQL_CLOSE
+	SQL_UNBIND = C.SQL_UNBIND
+
+	SQL_DBMS_NAME   = C.SQL_DBMS_NAME
+	SQL_DRIVER_NAME = C.SQL_DRIVER_NAME
+
+	SQL_IDENTIFIER_QUOTE_CHAR = C.SQL_IDENTIFIER_QUOTE_CHAR
+
+	SQL_API_SQLDESCRIBEPARAM    = C.SQL_API_SQLDESCRIBEPARAM
+	SQL_API_SQLMORERESULTS      = C.SQL_API_SQLMORERESULTS
+	SQL_API_SQLPROCEDURECOLUMNS = C.SQL_API_SQLPROCEDURECOLUMNS
+
 	SQL_AUTOCOMMIT         = C.SQL_AUTOCOMMIT
 	SQL_ATTR_AUTOCOMMIT    = C.SQL_ATTR_AUTOCOMMIT
 	SQL_AUTOCOMMIT_OFF     = C.SQL_AUTOCOMMIT_OFF
 	SQL_AUTOCOMMIT_ON      = C.SQL_AUTOCOMMIT_ON
 	SQL_AUTOCOMMIT_DEFAULT = C.SQL_AUTOCOMMIT_DEFAULT
 
+	SQL_ATTR_TXN_ISOLATION   = C.SQL_ATTR_TXN_ISOLATION
+	SQL_TXN_READ_UNCOMMITTED = uintptr(C.SQL_TXN_READ_UNCOMMITTED)
+	SQL_TXN_READ_COMMITTED   = uintptr(C.SQL_TXN_READ_COMMITTED)
+	SQL_TXN_REPEATABLE_READ  = uintptr(C.SQL_TXN_REPEATABLE_READ)
+	SQL_TXN_SERIALIZABLE     = uintptr(C.SQL_TXN_SERIALIZABLE)
+	SQL_TXN_SS_SNAPSHOT      = uintptr(0x20) // SQL Server specific
+
+	SQL_ATTR_LOGIN_TIMEOUT = C.SQL_ATTR_LOGIN_TIMEOUT
+
+	SQL_ATTR_CONNECTION_DEAD = C.SQL_ATTR_CONNECTION_DEAD
+	SQL_CD_TRUE              = uintptr(C.SQL_CD_TRUE)
+	SQL_ATTR_PACKET_SIZE     = C.SQL_ATTR_PACKET_SIZE
+	SQL_ATTR_CURRENT_CATALOG = C.SQL_ATTR_CURRENT_CATALOG
+
+	SQL_ATTR_ACCESS_MODE = C.SQL_ATTR_ACCESS_MODE
+	SQL_MODE_READ_WRITE  = uintptr(C.SQL_MODE_READ_WRITE)
+	SQL_MODE_READ_ONLY   = uintptr(C.SQL_MODE_READ_ONLY)
+
+	SQL_ATTR_QUERY_TIMEOUT  = C.SQL_ATTR_QUERY_TIMEOUT
+	SQL_ATTR_MAX_ROWS       = C.SQL_ATTR_MAX_ROWS
+	SQL_ATTR_CURSOR_TYPE    = C.SQL_ATTR_CURSOR_TYPE
+	SQL_ATTR_ROW_ARRAY_SIZE = C.SQL_ATTR_ROW_ARRAY_SIZE
+	SQL_ATTR_CONCURRENCY    = C.SQL_ATTR_CONCURRENCY
+	SQL_ATTR_ROW_NUMBER     = C.SQL_ATTR_ROW_NUMBER
+
+	SQL_ATTR_ASYNC_ENABLE = C.SQL_ATTR_ASYNC_ENABLE
+	SQL_ASYNC_ENABLE_OFF  = uintptr(C.SQL_ASYNC_ENABLE_OFF)
+	SQL_ASYNC_ENABLE_ON   = uintptr(C.SQL_ASYNC_ENABLE_ON)
+
+	SQL_CURSOR_KEYSET_DRIVEN = uintptr(C.SQL_CURSOR_KEYSET_DRIVEN)
+	SQL_CONCUR_VALUES        = uintptr(C.SQL_CONCUR_VALUES)
+
+	// SQLSetPos and SQLBulkOperations
+	SQL_POSITION       = C.SQL_POSITION
+	SQL_UPDATE         = C.SQL_UPDATE
+	SQL_DELETE         = C.SQL_DELETE
+	SQL_ADD            = C.SQL_ADD
+	SQL_LOCK_NO_CHANGE = C.SQL_LOCK_NO_CHANGE
+	SQL_COLUMN_IGNORE  = C.SQL_COLUMN_IGNORE
+
+	SQL_DESC_TYPE_NAME         = C.SQL_DESC_TYPE_NAME
+	SQL_DESC_UNSIGNED          = C.SQL_DESC_UNSIGNED
+	SQL_DESC_BASE_TABLE_NAME   = C.SQL_DESC_BASE_TABLE_NAME
+	SQL_DESC_BASE_COLUMN_NAME  = C.SQL_DESC_BASE_COLUMN_NAME
+	SQL_DESC_SCHEMA_NAME       = C.SQL_DESC_SCHEMA_NAME
+	SQL_DESC_CATALOG_NAME      = C.SQL_DESC_CATALOG_NAME
+	SQL_DESC_AUTO_UNIQUE_VALUE = C.SQL_DESC_AUTO_UNIQUE_VALUE
+	SQL_DESC_CASE_SENSITIVE    = C.SQL_DESC_CASE_SENSITIVE
+	SQL_DESC_SEARCHABLE        = C.SQL_DESC_SEARCHABLE
+
+	SQL_PRED_NONE       = C.SQL_PRED_NONE
+	SQL_PRED_CHAR       = C.SQL_PRED_CHAR
+	SQL_PRED_BASIC      = C.SQL_PRED_BASIC
+	SQL_PRED_SEARCHABLE = C.SQL_PRED_SEARCHABLE
+
+	SQL_ATTR_APP_ROW_DESC   = C.SQL_ATTR_APP_ROW_DESC
+	SQL_ATTR_APP_PARAM_DESC = C.SQL_ATTR_APP_PARAM_DESC
+	SQL_ATTR_IMP_PARAM_DESC = C.SQL_ATTR_IMP_PARAM_DESC
+	SQL_DESC_TYPE           = C.SQL_DESC_TYPE
+	SQL_DESC_PRECISION      = C.SQL_DESC_PRECISION
+	SQL_DESC_SCALE          = C.SQL_DESC_SCALE
+	SQL_DESC_DATA_PTR       = C.SQL_DESC_DATA_PTR
+	SQL_ARD_TYPE            = C.SQL_ARD_TYPE
+
+	SQL_MAX_NUMERIC_LEN = C.SQL_MAX_NUMERIC_LEN
+
 	SQL_IS_UINTEGER = C.SQL_IS_UINTEGER
+	SQL_IS_POINTER  = C.SQL_IS_POINTER
+	SQL_IS_INTEGER  = C.SQL_IS_INTEGER
+
+	SQL_COPT_SS_ACCESS_TOKEN = 1256 // SQL Server specific
+
+	// SQL Server table-valued parameters
+	SQL_SS_TABLE            = -153
+	SQL_SOPT_SS_PARAM_FOCUS = 1236
+	SQL_CA_SS_SCHEMA_NAME   = 1226
+	SQL_CA_SS_TYPE_NAME     = 1227
 
 	//Connection pooling
 	SQL_ATTR_CONNECTION_POOLING = C.SQL_ATTR_CONNECTION_POOLING
@@ -134,6 +246,7 @@ type (
 	SQLHENV   C.SQLHENV
 	SQLHDBC   C.SQLHDBC
 	SQLHSTMT  C.SQLHSTMT
+	SQLHDESC  C.SQLHDESC
 	SQLHWND   uintptr
 
 	SQLWCHAR     C.SQLWCHAR
@@ -145,8 +258,9 @@ type (
 	SQLPOINTER   C.SQLPOINTER
 	SQLRETURN    C.SQLRETURN
 
-	SQLLEN  C.SQLLEN
-	SQLULEN C.SQLULEN
+	SQLLEN        C.SQLLEN
+	SQLULEN       C.SQLULEN
+	SQLSETPOSIROW C.SQLSETPOSIROW
 
 	SQLGUID C.SQLGUID
 )
@@ -160,3 +274,13 @@ func SQLSetConnectUIntPtrAttr(connectionHandle SQLHDBC, attribute SQLINTEGER, va
 	r := C.sqlSetConnectUIntPtrAttr(C.SQLHDBC(connectionHandle), C.SQLINTEGER(attribute), C.uintptr_t(valuePtr), C.SQLINTEGER(stringLength))
 	return SQLRETURN(r)
 }
+
+func SQLSetDescUIntPtrField(descriptorHandle SQLHDESC, recNumber SQLSMALLINT, fieldIdentifier SQLSMALLINT, valuePtr uintptr, bufferLength SQLINTEGER) (ret SQLRETURN) {
+	r := C.sqlSetDescUIntPtrField(C.SQLHDESC(descriptorHandle), C.SQLSMALLINT(recNumber), C.SQLSMALLINT(fieldIdentifier), C.uintptr_t(valuePtr), C.SQLINTEGER(bufferLength))
+	return SQLRETURN(r)
+}
+
+func SQLSetStmtUIntPtrAttr(statementHandle SQLHSTMT, attribute SQLINTEGER, valuePtr uintptr, stringLength SQLINTEGER) (ret SQLRETURN) {
+	r := C.sqlSetStmtUIntPtrAttr(C.SQLHSTMT(statementHandle), C.SQLINTEGER(attribute), C.uintptr_t(valuePtr), C.SQLINTEGER(stringLength))
+	return SQLRETURN(r)
+}
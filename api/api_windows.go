@@ -19,11 +19,14 @@ const (
 	SQL_HANDLE_ENV  = 1
 	SQL_HANDLE_DBC  = 2
 	SQL_HANDLE_STMT = 3
+	SQL_HANDLE_DESC = 4
 
 	SQL_SUCCESS            = 0
 	SQL_SUCCESS_WITH_INFO  = 1
 	SQL_INVALID_HANDLE     = -2
 	SQL_NO_DATA            = 100
+	SQL_NEED_DATA          = 99
+	SQL_STILL_EXECUTING    = 2
 	SQL_NO_TOTAL           = -4
 	SQL_NTS                = -3
 	SQL_MAX_MESSAGE_LENGTH = 512
@@ -32,10 +35,19 @@ const (
 	SQL_NULL_HDBC          = 0
 	SQL_NULL_HSTMT         = 0
 
-	SQL_PARAM_INPUT = 1
+	SQL_PARAM_INPUT        = 1
+	SQL_PARAM_INPUT_OUTPUT = 2
+	SQL_PARAM_OUTPUT       = 4
+	SQL_RESULT_COL         = 3
+	SQL_RETURN_VALUE       = 5
 
-	SQL_NULL_DATA    = -1
-	SQL_DATA_AT_EXEC = -2
+	SQL_NO_NULLS         = 0
+	SQL_NULLABLE         = 1
+	SQL_NULLABLE_UNKNOWN = 2
+
+	SQL_NULL_DATA     = -1
+	SQL_DATA_AT_EXEC  = -2
+	SQL_DEFAULT_PARAM = -5
 
 	SQL_UNKNOWN_TYPE    = 0
 	SQL_CHAR            = 1
@@ -91,13 +103,105 @@ const (
 	SQL_COMMIT   = 0
 	SQL_ROLLBACK = 1
 
+	SQL_FETCH_NEXT     = 1
+	SQL_FETCH_FIRST    = 2
+	SQL_MAX_DSN_LENGTH = 32
+
+	SQL_CLOSE  = 0
+	SQL_UNBIND = 2
+
+	SQL_DBMS_NAME   = 17
+	SQL_DRIVER_NAME = 6
+
+	SQL_IDENTIFIER_QUOTE_CHAR = 29
+
+	SQL_API_SQLDESCRIBEPARAM    = 58
+	SQL_API_SQLMORERESULTS      = 61
+	SQL_API_SQLPROCEDURECOLUMNS = 66
+
 	SQL_AUTOCOMMIT         = 102
 	SQL_ATTR_AUTOCOMMIT    = SQL_AUTOCOMMIT
 	SQL_AUTOCOMMIT_OFF     = 0
 	SQL_AUTOCOMMIT_ON      = 1
 	SQL_AUTOCOMMIT_DEFAULT = SQL_AUTOCOMMIT_ON
 
+	SQL_ATTR_TXN_ISOLATION   = 108
+	SQL_TXN_READ_UNCOMMITTED = uintptr(1)
+	SQL_TXN_READ_COMMITTED   = uintptr(2)
+	SQL_TXN_REPEATABLE_READ  = uintptr(4)
+	SQL_TXN_SERIALIZABLE     = uintptr(8)
+	SQL_TXN_SS_SNAPSHOT      = uintptr(0x20) // SQL Server specific
+
+	SQL_ATTR_LOGIN_TIMEOUT = 103
+
+	SQL_ATTR_CONNECTION_DEAD = 1209
+	SQL_CD_TRUE              = uintptr(1)
+	SQL_ATTR_PACKET_SIZE     = 112
+	SQL_ATTR_CURRENT_CATALOG = 109
+
+	SQL_ATTR_ACCESS_MODE = 101
+	SQL_MODE_READ_WRITE  = uintptr(0)
+	SQL_MODE_READ_ONLY   = uintptr(1)
+
+	SQL_ATTR_QUERY_TIMEOUT  = 0
+	SQL_ATTR_MAX_ROWS       = 1
+	SQL_ATTR_CURSOR_TYPE    = 6
+	SQL_ATTR_ROW_ARRAY_SIZE = 27
+	SQL_ATTR_CONCURRENCY    = 7
+	SQL_ATTR_ROW_NUMBER     = 14
+
+	SQL_ATTR_ASYNC_ENABLE = 4
+	SQL_ASYNC_ENABLE_OFF  = uintptr(0)
+	SQL_ASYNC_ENABLE_ON   = uintptr(1)
+
+	SQL_CURSOR_KEYSET_DRIVEN = uintptr(1)
+	SQL_CONCUR_VALUES        = uintptr(4)
+
+	// SQLSetPos and SQLBulkOperations
+	SQL_POSITION       = 0
+	SQL_UPDATE         = 2
+	SQL_DELETE         = 3
+	SQL_ADD            = 4
+	SQL_LOCK_NO_CHANGE = 0
+	SQL_COLUMN_IGNORE  = -6
+
+	SQL_DESC_TYPE_NAME         = 14
+	SQL_DESC_UNSIGNED          = 8
+	SQL_DESC_BASE_TABLE_NAME   = 23
+	SQL_DESC_BASE_COLUMN_NAME  = 22
+	SQL_DESC_SCHEMA_NAME       = 16
+	SQL_DESC_CATALOG_NAME      = 17
+	SQL_DESC_AUTO_UNIQUE_VALUE = 11
+	SQL_DESC_CASE_SENSITIVE    = 12
+	SQL_DESC_SEARCHABLE        = 13
+
+	SQL_PRED_NONE       = 0
+	SQL_PRED_CHAR       = 1
+	SQL_PRED_BASIC      = 2
+	SQL_PRED_SEARCHABLE = 3
+
+	SQL_ATTR_APP_ROW_DESC   = 10010
+	SQL_ATTR_APP_PARAM_DESC = 10011
+	SQL_ATTR_IMP_PARAM_DESC = 10013
+	SQL_DESC_TYPE           = 1002
+	SQL_DESC_PRECISION      = 1005
+	SQL_DESC_SCALE          = 1006
+	SQL_DESC_DATA_PTR       = 1010
+	SQL_ARD_TYPE            = -99
+
+	SQL_MAX_NUMERIC_LEN = 16
+
 	SQL_IS_UINTEGER = -5
+	SQL_IS_POINTER  = -4
+	SQL_IS_INTEGER  = -6
+
+	SQL_COPT_SS_ACCESS_TOKEN = 1256 // SQL Server specific
+
+	// SQL Server table-valued parameters
+	SQL_SS_TABLE            = -153
+	SQL_SOPT_SS_PARAM_FOCUS = 1236
+	SQL_CA_SS_SCHEMA_NAME   = 1226
+	SQL_CA_SS_TYPE_NAME     = 1227
 
 	//Connection pooling
 	SQL_ATTR_CONNECTION_POOLING = 201
@@ -115,6 +219,7 @@ type (
 	SQLHENV   SQLHANDLE
 	SQLHDBC   SQLHANDLE
 	SQLHSTMT  SQLHANDLE
+	SQLHDESC  SQLHANDLE
 	SQLHWND   uintptr
 
 	SQLWCHAR     uint16
@@ -145,3 +250,15 @@ func SQLSetConnectUIntPtrAttr(connectionHandle SQLHDBC, attribute SQLINTEGER, va
 	ret = SQLRETURN(r0)
 	return
 }
+
+func SQLSetDescUIntPtrField(descriptorHandle SQLHDESC, recNumber SQLSMALLINT, fieldIdentifier SQLSMALLINT, valuePtr uintptr, bufferLength SQLINTEGER) (ret SQLRETURN) {
+	r0, _, _ := syscall.Syscall6(procSQLSetDescFieldW.Addr(), 5, uintptr(descriptorHandle), uintptr(recNumber), uintptr(fieldIdentifier), uintptr(valuePtr), uintptr(bufferLength), 0)
+	ret = SQLRETURN(r0)
+	return
+}
+
+func SQLSetStmtUIntPtrAttr(statementHandle SQLHSTMT, attribute SQLINTEGER, valuePtr uintptr, stringLength SQLINTEGER) (ret SQLRETURN) {
+	r0, _, _ := syscall.Syscall6(procSQLSetStmtAttrW.Addr(), 4, uintptr(statementHandle), uintptr(attribute), uintptr(valuePtr), uintptr(stringLength), 0, 0)
+	ret = SQLRETURN(r0)
+	return
+}
@@ -7,4 +7,6 @@ package api
 type (
 	SQLLEN  SQLINTEGER
 	SQLULEN SQLUINTEGER
+
+	SQLSETPOSIROW SQLUSMALLINT
 )
@@ -7,4 +7,6 @@ package api
 type (
 	SQLLEN  int64
 	SQLULEN uint64
+
+	SQLSETPOSIROW uint64
 )
@@ -35,6 +35,16 @@ func SQLBindParameter(statementHandle SQLHSTMT, parameterNumber SQLUSMALLINT, in
 	return SQLRETURN(r)
 }
 
+func SQLBrowseConnect(connectionHandle SQLHDBC, inConnectionString *SQLWCHAR, stringLength1 SQLSMALLINT, outConnectionString *SQLWCHAR, bufferLength SQLSMALLINT, stringLength2Ptr *SQLSMALLINT) (ret SQLRETURN) {
+	r := C.SQLBrowseConnectW(C.SQLHDBC(connectionHandle), (*C.SQLWCHAR)(unsafe.Pointer(inConnectionString)), C.SQLSMALLINT(stringLength1), (*C.SQLWCHAR)(unsafe.Pointer(outConnectionString)), C.SQLSMALLINT(bufferLength), (*C.SQLSMALLINT)(stringLength2Ptr))
+	return SQLRETURN(r)
+}
+
+func SQLBulkOperations(statementHandle SQLHSTMT, operation SQLSMALLINT) (ret SQLRETURN) {
+	r := C.SQLBulkOperations(C.SQLHSTMT(statementHandle), C.SQLSMALLINT(operation))
+	return SQLRETURN(r)
+}
+
 func SQLCancel(statementHandle SQLHSTMT) (ret SQLRETURN) {
 	r := C.SQLCancel(C.SQLHSTMT(statementHandle))
 	return SQLRETURN(r)
@@ -45,6 +55,21 @@ func SQLCloseCursor(statementHandle SQLHSTMT) (ret SQLRETURN) {
 	return SQLRETURN(r)
 }
 
+func SQLColAttribute(statementHandle SQLHSTMT, columnNumber SQLUSMALLINT, fieldIdentifier SQLUSMALLINT, characterAttributePtr SQLPOINTER, bufferLength SQLSMALLINT, stringLengthPtr *SQLSMALLINT, numericAttributePtr *SQLLEN) (ret SQLRETURN) {
+	r := C.SQLColAttributeW(C.SQLHSTMT(statementHandle), C.SQLUSMALLINT(columnNumber), C.SQLUSMALLINT(fieldIdentifier), C.SQLPOINTER(characterAttributePtr), C.SQLSMALLINT(bufferLength), (*C.SQLSMALLINT)(stringLengthPtr), (*C.SQLLEN)(numericAttributePtr))
+	return SQLRETURN(r)
+}
+
+func SQLConnect(connectionHandle SQLHDBC, serverName *SQLWCHAR, nameLength1 SQLSMALLINT, userName *SQLWCHAR, nameLength2 SQLSMALLINT, authentication *SQLWCHAR, nameLength3 SQLSMALLINT) (ret SQLRETURN) {
+	r := C.SQLConnectW(C.SQLHDBC(connectionHandle), (*C.SQLWCHAR)(unsafe.Pointer(serverName)), C.SQLSMALLINT(nameLength1), (*C.SQLWCHAR)(unsafe.Pointer(userName)), C.SQLSMALLINT(nameLength2), (*C.SQLWCHAR)(unsafe.Pointer(authentication)), C.SQLSMALLINT(nameLength3))
+	return SQLRETURN(r)
+}
+
+func SQLDataSources(environmentHandle SQLHENV, direction SQLUSMALLINT, serverName *SQLWCHAR, bufferLength1 SQLSMALLINT, nameLength1Ptr *SQLSMALLINT, description *SQLWCHAR, bufferLength2 SQLSMALLINT, nameLength2Ptr *SQLSMALLINT) (ret SQLRETURN) {
+	r := C.SQLDataSourcesW(C.SQLHENV(environmentHandle), C.SQLUSMALLINT(direction), (*C.SQLWCHAR)(unsafe.Pointer(serverName)), C.SQLSMALLINT(bufferLength1), (*C.SQLSMALLINT)(nameLength1Ptr), (*C.SQLWCHAR)(unsafe.Pointer(description)), C.SQLSMALLINT(bufferLength2), (*C.SQLSMALLINT)(nameLength2Ptr))
+	return SQLRETURN(r)
+}
+
 func SQLDescribeCol(statementHandle SQLHSTMT, columnNumber SQLUSMALLINT, columnName *SQLWCHAR, bufferLength SQLSMALLINT, nameLengthPtr *SQLSMALLINT, dataTypePtr *SQLSMALLINT, columnSizePtr *SQLULEN, decimalDigitsPtr *SQLSMALLINT, nullablePtr *SQLSMALLINT) (ret SQLRETURN) {
 	r := C.SQLDescribeColW(C.SQLHSTMT(statementHandle), C.SQLUSMALLINT(columnNumber), (*C.SQLWCHAR)(unsafe.Pointer(columnName)), C.SQLSMALLINT(bufferLength), (*C.SQLSMALLINT)(nameLengthPtr), (*C.SQLSMALLINT)(dataTypePtr), (*C.SQLULEN)(columnSizePtr), (*C.SQLSMALLINT)(decimalDigitsPtr), (*C.SQLSMALLINT)(nullablePtr))
 	return SQLRETURN(r)
@@ -65,6 +90,11 @@ func SQLDriverConnect(connectionHandle SQLHDBC, windowHandle SQLHWND, inConnecti
 	return SQLRETURN(r)
 }
 
+func SQLDrivers(environmentHandle SQLHENV, direction SQLUSMALLINT, driverDescription *SQLWCHAR, bufferLength1 SQLSMALLINT, descriptionLengthPtr *SQLSMALLINT, driverAttributes *SQLWCHAR, bufferLength2 SQLSMALLINT, attributesLengthPtr *SQLSMALLINT) (ret SQLRETURN) {
+	r := C.SQLDriversW(C.SQLHENV(environmentHandle), C.SQLUSMALLINT(direction), (*C.SQLWCHAR)(unsafe.Pointer(driverDescription)), C.SQLSMALLINT(bufferLength1), (*C.SQLSMALLINT)(descriptionLengthPtr), (*C.SQLWCHAR)(unsafe.Pointer(driverAttributes)), C.SQLSMALLINT(bufferLength2), (*C.SQLSMALLINT)(attributesLengthPtr))
+	return SQLRETURN(r)
+}
+
 func SQLEndTran(handleType SQLSMALLINT, handle SQLHANDLE, completionType SQLSMALLINT) (ret SQLRETURN) {
 	r := C.SQLEndTran(C.SQLSMALLINT(handleType), C.SQLHANDLE(handle), C.SQLSMALLINT(completionType))
 	return SQLRETURN(r)
@@ -85,6 +115,16 @@ func SQLFreeHandle(handleType SQLSMALLINT, handle SQLHANDLE) (ret SQLRETURN) {
 	return SQLRETURN(r)
 }
 
+func SQLFreeStmt(statementHandle SQLHSTMT, option SQLUSMALLINT) (ret SQLRETURN) {
+	r := C.SQLFreeStmt(C.SQLHSTMT(statementHandle), C.SQLUSMALLINT(option))
+	return SQLRETURN(r)
+}
+
+func SQLGetConnectAttr(connectionHandle SQLHDBC, attribute SQLINTEGER, valuePtr SQLPOINTER, bufferLength SQLINTEGER, stringLengthPtr *SQLINTEGER) (ret SQLRETURN) {
+	r := C.SQLGetConnectAttrW(C.SQLHDBC(connectionHandle), C.SQLINTEGER(attribute), C.SQLPOINTER(valuePtr), C.SQLINTEGER(bufferLength), (*C.SQLINTEGER)(stringLengthPtr))
+	return SQLRETURN(r)
+}
+
 func SQLGetData(statementHandle SQLHSTMT, colOrParamNum SQLUSMALLINT, targetType SQLSMALLINT, targetValuePtr SQLPOINTER, bufferLength SQLLEN, vallen *SQLLEN) (ret SQLRETURN) {
 	r := C.SQLGetData(C.SQLHSTMT(statementHandle), C.SQLUSMALLINT(colOrParamNum), C.SQLSMALLINT(targetType), C.SQLPOINTER(targetValuePtr), C.SQLLEN(bufferLength), (*C.SQLLEN)(vallen))
 	return SQLRETURN(r)
@@ -95,6 +135,21 @@ func SQLGetDiagRec(handleType SQLSMALLINT, handle SQLHANDLE, recNumber SQLSMALLI
 	return SQLRETURN(r)
 }
 
+func SQLGetFunctions(connectionHandle SQLHDBC, functionId SQLUSMALLINT, supportedPtr *SQLUSMALLINT) (ret SQLRETURN) {
+	r := C.SQLGetFunctions(C.SQLHDBC(connectionHandle), C.SQLUSMALLINT(functionId), (*C.SQLUSMALLINT)(supportedPtr))
+	return SQLRETURN(r)
+}
+
+func SQLGetInfo(connectionHandle SQLHDBC, infoType SQLUSMALLINT, infoValuePtr SQLPOINTER, bufferLength SQLSMALLINT, stringLengthPtr *SQLSMALLINT) (ret SQLRETURN) {
+	r := C.SQLGetInfoW(C.SQLHDBC(connectionHandle), C.SQLUSMALLINT(infoType), C.SQLPOINTER(infoValuePtr), C.SQLSMALLINT(bufferLength), (*C.SQLSMALLINT)(stringLengthPtr))
+	return SQLRETURN(r)
+}
+
+func SQLGetStmtAttr(statementHandle SQLHSTMT, attribute SQLINTEGER, valuePtr SQLPOINTER, bufferLength SQLINTEGER, stringLengthPtr *SQLINTEGER) (ret SQLRETURN) {
+	r := C.SQLGetStmtAttrW(C.SQLHSTMT(statementHandle), C.SQLINTEGER(attribute), C.SQLPOINTER(valuePtr), C.SQLINTEGER(bufferLength), (*C.SQLINTEGER)(stringLengthPtr))
+	return SQLRETURN(r)
+}
+
 func SQLNumParams(statementHandle SQLHSTMT, parameterCountPtr *SQLSMALLINT) (ret SQLRETURN) {
 	r := C.SQLNumParams(C.SQLHSTMT(statementHandle), (*C.SQLSMALLINT)(parameterCountPtr))
 	return SQLRETURN(r)
@@ -115,6 +170,11 @@ func SQLPrepare(statementHandle SQLHSTMT, statementText *SQLWCHAR, textLength SQ
 	return SQLRETURN(r)
 }
 
+func SQLProcedureColumns(statementHandle SQLHSTMT, catalogName *SQLWCHAR, nameLength1 SQLSMALLINT, schemaName *SQLWCHAR, nameLength2 SQLSMALLINT, procName *SQLWCHAR, nameLength3 SQLSMALLINT, columnName *SQLWCHAR, nameLength4 SQLSMALLINT) (ret SQLRETURN) {
+	r := C.SQLProcedureColumnsW(C.SQLHSTMT(statementHandle), (*C.SQLWCHAR)(unsafe.Pointer(catalogName)), C.SQLSMALLINT(nameLength1), (*C.SQLWCHAR)(unsafe.Pointer(schemaName)), C.SQLSMALLINT(nameLength2), (*C.SQLWCHAR)(unsafe.Pointer(procName)), C.SQLSMALLINT(nameLength3), (*C.SQLWCHAR)(unsafe.Pointer(columnName)), C.SQLSMALLINT(nameLength4))
+	return SQLRETURN(r)
+}
+
 func SQLRowCount(statementHandle SQLHSTMT, rowCountPtr *SQLLEN) (ret SQLRETURN) {
 	r := C.SQLRowCount(C.SQLHSTMT(statementHandle), (*C.SQLLEN)(rowCountPtr))
 	return SQLRETURN(r)
@@ -125,7 +185,22 @@ func SQLSetEnvAttr(environmentHandle SQLHENV, attribute SQLINTEGER, valuePtr SQL
 	return SQLRETURN(r)
 }
 
+func SQLSetDescField(descriptorHandle SQLHDESC, recNumber SQLSMALLINT, fieldIdentifier SQLSMALLINT, valuePtr SQLPOINTER, bufferLength SQLINTEGER) (ret SQLRETURN) {
+	r := C.SQLSetDescFieldW(C.SQLHDESC(descriptorHandle), C.SQLSMALLINT(recNumber), C.SQLSMALLINT(fieldIdentifier), C.SQLPOINTER(valuePtr), C.SQLINTEGER(bufferLength))
+	return SQLRETURN(r)
+}
+
 func SQLSetConnectAttr(connectionHandle SQLHDBC, attribute SQLINTEGER, valuePtr SQLPOINTER, stringLength SQLINTEGER) (ret SQLRETURN) {
 	r := C.SQLSetConnectAttrW(C.SQLHDBC(connectionHandle), C.SQLINTEGER(attribute), C.SQLPOINTER(valuePtr), C.SQLINTEGER(stringLength))
 	return SQLRETURN(r)
 }
+
+func SQLSetPos(statementHandle SQLHSTMT, rowNumber SQLSETPOSIROW, operation SQLUSMALLINT, lockType SQLUSMALLINT) (ret SQLRETURN) {
+	r := C.SQLSetPos(C.SQLHSTMT(statementHandle), C.SQLSETPOSIROW(rowNumber), C.SQLUSMALLINT(operation), C.SQLUSMALLINT(lockType))
+	return SQLRETURN(r)
+}
+
+func SQLSetStmtAttr(statementHandle SQLHSTMT, attribute SQLINTEGER, valuePtr SQLPOINTER, stringLength SQLINTEGER) (ret SQLRETURN) {
+	r := C.SQLSetStmtAttrW(C.SQLHSTMT(statementHandle), C.SQLINTEGER(attribute), C.SQLPOINTER(valuePtr), C.SQLINTEGER(stringLength))
+	return SQLRETURN(r)
+}
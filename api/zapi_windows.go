@@ -39,28 +39,43 @@ func errnoErr(e syscall.Errno) error {
 var (
 	mododbc32 = windows.NewLazySystemDLL("odbc32.dll")
 
-	procSQLAllocHandle     = mododbc32.NewProc("SQLAllocHandle")
-	procSQLBindCol         = mododbc32.NewProc("SQLBindCol")
-	procSQLBindParameter   = mododbc32.NewProc("SQLBindParameter")
-	procSQLCancel          = mododbc32.NewProc("SQLCancel")
-	procSQLCloseCursor     = mododbc32.NewProc("SQLCloseCursor")
-	procSQLDescribeColW    = mododbc32.NewProc("SQLDescribeColW")
-	procSQLDescribeParam   = mododbc32.NewProc("SQLDescribeParam")
-	procSQLDisconnect      = mododbc32.NewProc("SQLDisconnect")
-	procSQLDriverConnectW  = mododbc32.NewProc("SQLDriverConnectW")
-	procSQLEndTran         = mododbc32.NewProc("SQLEndTran")
-	procSQLExecute         = mododbc32.NewProc("SQLExecute")
-	procSQLFetch           = mododbc32.NewProc("SQLFetch")
-	procSQLFreeHandle      = mododbc32.NewProc("SQLFreeHandle")
-	procSQLGetData         = mododbc32.NewProc("SQLGetData")
-	procSQLGetDiagRecW     = mododbc32.NewProc("SQLGetDiagRecW")
-	procSQLNumParams       = mododbc32.NewProc("SQLNumParams")
-	procSQLMoreResults     = mododbc32.NewProc("SQLMoreResults")
-	procSQLNumResultCols   = mododbc32.NewProc("SQLNumResultCols")
-	procSQLPrepareW        = mododbc32.NewProc("SQLPrepareW")
-	procSQLRowCount        = mododbc32.NewProc("SQLRowCount")
-	procSQLSetEnvAttr      = mododbc32.NewProc("SQLSetEnvAttr")
-	procSQLSetConnectAttrW = mododbc32.NewProc("SQLSetConnectAttrW")
+	procSQLAllocHandle       = mododbc32.NewProc("SQLAllocHandle")
+	procSQLBindCol           = mododbc32.NewProc("SQLBindCol")
+	procSQLBindParameter     = mododbc32.NewProc("SQLBindParameter")
+	procSQLBrowseConnectW    = mododbc32.NewProc("SQLBrowseConnectW")
+	procSQLBulkOperations    = mododbc32.NewProc("SQLBulkOperations")
+	procSQLCancel            = mododbc32.NewProc("SQLCancel")
+	procSQLCloseCursor       = mododbc32.NewProc("SQLCloseCursor")
+	procSQLColAttributeW     = mododbc32.NewProc("SQLColAttributeW")
+	procSQLConnectW          = mododbc32.NewProc("SQLConnectW")
+	procSQLDataSourcesW      = mododbc32.NewProc("SQLDataSourcesW")
+	procSQLDescribeColW      = mododbc32.NewProc("SQLDescribeColW")
+	procSQLDescribeParam     = mododbc32.NewProc("SQLDescribeParam")
+	procSQLDisconnect        = mododbc32.NewProc("SQLDisconnect")
+	procSQLDriverConnectW    = mododbc32.NewProc("SQLDriverConnectW")
+	procSQLDriversW          = mododbc32.NewProc("SQLDriversW")
+	procSQLEndTran           = mododbc32.NewProc("SQLEndTran")
+	procSQLExecute           = mododbc32.NewProc("SQLExecute")
+	procSQLFetch             = mododbc32.NewProc("SQLFetch")
+	procSQLFreeHandle        = mododbc32.NewProc("SQLFreeHandle")
+	procSQLFreeStmt          = mododbc32.NewProc("SQLFreeStmt")
+	procSQLGetConnectAttrW   = mododbc32.NewProc("SQLGetConnectAttrW")
+	procSQLGetData           = mododbc32.NewProc("SQLGetData")
+	procSQLGetDiagRecW       = mododbc32.NewProc("SQLGetDiagRecW")
+	procSQLGetFunctions      = mododbc32.NewProc("SQLGetFunctions")
+	procSQLGetInfoW          = mododbc32.NewProc("SQLGetInfoW")
+	procSQLGetStmtAttrW      = mododbc32.NewProc("SQLGetStmtAttrW")
+	procSQLNumParams         = mododbc32.NewProc("SQLNumParams")
+	procSQLMoreResults       = mododbc32.NewProc("SQLMoreResults")
+	procSQLNumResultCols     = mododbc32.NewProc("SQLNumResultCols")
+	procSQLPrepareW          = mododbc32.NewProc("SQLPrepareW")
+	procSQLProcedureColumnsW = mododbc32.NewProc("SQLProcedureColumnsW")
+	procSQLRowCount          = mododbc32.NewProc("SQLRowCount")
+	procSQLSetEnvAttr        = mododbc32.NewProc("SQLSetEnvAttr")
+	procSQLSetDescFieldW     = mododbc32.NewProc("SQLSetDescFieldW")
+	procSQLSetConnectAttrW   = mododbc32.NewProc("SQLSetConnectAttrW")
+	procSQLSetPos            = mododbc32.NewProc("SQLSetPos")
+	procSQLSetStmtAttrW      = mododbc32.NewProc("SQLSetStmtAttrW")
 )
 
 func SQLAllocHandle(handleType SQLSMALLINT, inputHandle SQLHANDLE, outputHandle *SQLHANDLE) (ret SQLRETURN) {
@@ -81,6 +96,18 @@ func SQLBindParameter(statementHandle SQLHSTMT, parameterNumber SQLUSMALLINT, in
 	return
 }
 
+func SQLBrowseConnect(connectionHandle SQLHDBC, inConnectionString *SQLWCHAR, stringLength1 SQLSMALLINT, outConnectionString *SQLWCHAR, bufferLength SQLSMALLINT, stringLength2Ptr *SQLSMALLINT) (ret SQLRETURN) {
+	r0, _, _ := syscall.Syscall6(procSQLBrowseConnectW.Addr(), 6, uintptr(connectionHandle), uintptr(unsafe.Pointer(inConnectionString)), uintptr(stringLength1), uintptr(unsafe.Pointer(outConnectionString)), uintptr(bufferLength), uintptr(unsafe.Pointer(stringLength2Ptr)))
+	ret = SQLRETURN(r0)
+	return
+}
+
+func SQLBulkOperations(statementHandle SQLHSTMT, operation SQLSMALLINT) (ret SQLRETURN) {
+	r0, _, _ := syscall.Syscall(procSQLBulkOperations.Addr(), 2, uintptr(statementHandle), uintptr(operation), 0)
+	ret = SQLRETURN(r0)
+	return
+}
+
 func SQLCancel(statementHandle SQLHSTMT) (ret SQLRETURN) {
 	r0, _, _ := syscall.Syscall(procSQLCancel.Addr(), 1, uintptr(statementHandle), 0, 0)
 	ret = SQLRETURN(r0)
@@ -93,6 +120,24 @@ func SQLCloseCursor(statementHandle SQLHSTMT) (ret SQLRETURN) {
 	return
 }
 
+func SQLColAttribute(statementHandle SQLHSTMT, columnNumber SQLUSMALLINT, fieldIdentifier SQLUSMALLINT, characterAttributePtr SQLPOINTER, bufferLength SQLSMALLINT, stringLengthPtr *SQLSMALLINT, numericAttributePtr *SQLLEN) (ret SQLRETURN) {
+	r0, _, _ := syscall.Syscall9(procSQLColAttributeW.Addr(), 7, uintptr(statementHandle), uintptr(columnNumber), uintptr(fieldIdentifier), uintptr(characterAttributePtr), uintptr(bufferLength), uintptr(unsafe.Pointer(stringLengthPtr)), uintptr(unsafe.Pointer(numericAttributePtr)), 0, 0)
+	ret = SQLRETURN(r0)
+	return
+}
+
+func SQLConnect(connectionHandle SQLHDBC, serverName *SQLWCHAR, nameLength1 SQLSMALLINT, userName *SQLWCHAR, nameLength2 SQLSMALLINT, authentication *SQLWCHAR, nameLength3 SQLSMALLINT) (ret SQLRETURN) {
+	r0, _, _ := syscall.Syscall9(procSQLConnectW.Addr(), 7, uintptr(connectionHandle), uintptr(unsafe.Pointer(serverName)), uintptr(nameLength1), uintptr(unsafe.Pointer(userName)), uintptr(nameLength2), uintptr(unsafe.Pointer(authentication)), uintptr(nameLength3), 0, 0)
+	ret = SQLRETURN(r0)
+	return
+}
+
+func SQLDataSources(environmentHandle SQLHENV, direction SQLUSMALLINT, serverName *SQLWCHAR, bufferLength1 SQLSMALLINT, nameLength1Ptr *SQLSMALLINT, description *SQLWCHAR, bufferLength2 SQLSMALLINT, nameLength2Ptr *SQLSMALLINT) (ret SQLRETURN) {
+	r0, _, _ := syscall.Syscall9(procSQLDataSourcesW.Addr(), 8, uintptr(environmentHandle), uintptr(direction), uintptr(unsafe.Pointer(serverName)), uintptr(bufferLength1), uintptr(unsafe.Pointer(nameLength1Ptr)), uintptr(unsafe.Pointer(description)), uintptr(bufferLength2), uintptr(unsafe.Pointer(nameLength2Ptr)), 0)
+	ret = SQLRETURN(r0)
+	return
+}
+
 func SQLDescribeCol(statementHandle SQLHSTMT, columnNumber SQLUSMALLINT, columnName *SQLWCHAR, bufferLength SQLSMALLINT, nameLengthPtr *SQLSMALLINT, dataTypePtr *SQLSMALLINT, columnSizePtr *SQLULEN, decimalDigitsPtr *SQLSMALLINT, nullablePtr *SQLSMALLINT) (ret SQLRETURN) {
 	r0, _, _ := syscall.Syscall9(procSQLDescribeColW.Addr(), 9, uintptr(statementHandle), uintptr(columnNumber), uintptr(unsafe.Pointer(columnName)), uintptr(bufferLength), uintptr(unsafe.Pointer(nameLengthPtr)), uintptr(unsafe.Pointer(dataTypePtr)), uintptr(unsafe.Pointer(columnSizePtr)), uintptr(unsafe.Pointer(decimalDigitsPtr)), uintptr(unsafe.Pointer(nullablePtr)))
 	ret = SQLRETURN(r0)
@@ -117,6 +162,12 @@ func SQLDriverConnect(connectionHandle SQLHDBC, windowHandle SQLHWND, inConnecti
 	return
 }
 
+func SQLDrivers(environmentHandle SQLHENV, direction SQLUSMALLINT, driverDescription *SQLWCHAR, bufferLength1 SQLSMALLINT, descriptionLengthPtr *SQLSMALLINT, driverAttributes *SQLWCHAR, bufferLength2 SQLSMALLINT, attributesLengthPtr *SQLSMALLINT) (ret SQLRETURN) {
+	r0, _, _ := syscall.Syscall9(procSQLDriversW.Addr(), 8, uintptr(environmentHandle), uintptr(direction), uintptr(unsafe.Pointer(driverDescription)), uintptr(bufferLength1), uintptr(unsafe.Pointer(descriptionLengthPtr)), uintptr(unsafe.Pointer(driverAttributes)), uintptr(bufferLength2), uintptr(unsafe.Pointer(attributesLengthPtr)), 0)
+	ret = SQLRETURN(r0)
+	return
+}
+
 func SQLEndTran(handleType SQLSMALLINT, handle SQLHANDLE, completionType SQLSMALLINT) (ret SQLRETURN) {
 	r0, _, _ := syscall.Syscall(procSQLEndTran.Addr(), 3, uintptr(handleType), uintptr(handle), uintptr(completionType))
 	ret = SQLRETURN(r0)
@@ -141,6 +192,18 @@ func SQLFreeHandle(handleType SQLSMALLINT, handle SQLHANDLE) (ret SQLRETURN) {
 	return
 }
 
+func SQLFreeStmt(statementHandle SQLHSTMT, option SQLUSMALLINT) (ret SQLRETURN) {
+	r0, _, _ := syscall.Syscall(procSQLFreeStmt.Addr(), 2, uintptr(statementHandle), uintptr(option), 0)
+	ret = SQLRETURN(r0)
+	return
+}
+
+func SQLGetConnectAttr(connectionHandle SQLHDBC, attribute SQLINTEGER, valuePtr SQLPOINTER, bufferLength SQLINTEGER, stringLengthPtr *SQLINTEGER) (ret SQLRETURN) {
+	r0, _, _ := syscall.Syscall6(procSQLGetConnectAttrW.Addr(), 5, uintptr(connectionHandle), uintptr(attribute), uintptr(valuePtr), uintptr(bufferLength), uintptr(unsafe.Pointer(stringLengthPtr)), 0)
+	ret = SQLRETURN(r0)
+	return
+}
+
 func SQLGetData(statementHandle SQLHSTMT, colOrParamNum SQLUSMALLINT, targetType SQLSMALLINT, targetValuePtr SQLPOINTER, bufferLength SQLLEN, vallen *SQLLEN) (ret SQLRETURN) {
 	r0, _, _ := syscall.Syscall6(procSQLGetData.Addr(), 6, uintptr(statementHandle), uintptr(colOrParamNum), uintptr(targetType), uintptr(targetValuePtr), uintptr(bufferLength), uintptr(unsafe.Pointer(vallen)))
 	ret = SQLRETURN(r0)
@@ -153,6 +216,24 @@ func SQLGetDiagRec(handleType SQLSMALLINT, handle SQLHANDLE, recNumber SQLSMALLI
 	return
 }
 
+func SQLGetFunctions(connectionHandle SQLHDBC, functionId SQLUSMALLINT, supportedPtr *SQLUSMALLINT) (ret SQLRETURN) {
+	r0, _, _ := syscall.Syscall(procSQLGetFunctions.Addr(), 3, uintptr(connectionHandle), uintptr(functionId), uintptr(unsafe.Pointer(supportedPtr)))
+	ret = SQLRETURN(r0)
+	return
+}
+
+func SQLGetInfo(connectionHandle SQLHDBC, infoType SQLUSMALLINT, infoValuePtr SQLPOINTER, bufferLength SQLSMALLINT, stringLengthPtr *SQLSMALLINT) (ret SQLRETURN) {
+	r0, _, _ := syscall.Syscall6(procSQLGetInfoW.Addr(), 5, uintptr(connectionHandle), uintptr(infoType), uintptr(infoValuePtr), uintptr(bufferLength), uintptr(unsafe.Pointer(stringLengthPtr)), 0)
+	ret = SQLRETURN(r0)
+	return
+}
+
+func SQLGetStmtAttr(statementHandle SQLHSTMT, attribute SQLINTEGER, valuePtr SQLPOINTER, bufferLength SQLINTEGER, stringLengthPtr *SQLINTEGER) (ret SQLRETURN) {
+	r0, _, _ := syscall.Syscall6(procSQLGetStmtAttrW.Addr(), 5, uintptr(statementHandle), uintptr(attribute), uintptr(valuePtr), uintptr(bufferLength), uintptr(unsafe.Pointer(stringLengthPtr)), 0)
+	ret = SQLRETURN(r0)
+	return
+}
+
 func SQLNumParams(statementHandle SQLHSTMT, parameterCountPtr *SQLSMALLINT) (ret SQLRETURN) {
 	r0, _, _ := syscall.Syscall(procSQLNumParams.Addr(), 2, uintptr(statementHandle), uintptr(unsafe.Pointer(parameterCountPtr)), 0)
 	ret = SQLRETURN(r0)
@@ -177,6 +258,12 @@ func SQLPrepare(statementHandle SQLHSTMT, statementText *SQLWCHAR, textLength SQ
 	return
 }
 
+func SQLProcedureColumns(statementHandle SQLHSTMT, catalogName *SQLWCHAR, nameLength1 SQLSMALLINT, schemaName *SQLWCHAR, nameLength2 SQLSMALLINT, procName *SQLWCHAR, nameLength3 SQLSMALLINT, columnName *SQLWCHAR, nameLength4 SQLSMALLINT) (ret SQLRETURN) {
+	r0, _, _ := syscall.Syscall9(procSQLProcedureColumnsW.Addr(), 9, uintptr(statementHandle), uintptr(unsafe.Pointer(catalogName)), uintptr(nameLength1), uintptr(unsafe.Pointer(schemaName)), uintptr(nameLength2), uintptr(unsafe.Pointer(procName)), uintptr(nameLength3), uintptr(unsafe.Pointer(columnName)), uintptr(nameLength4))
+	ret = SQLRETURN(r0)
+	return
+}
+
 func SQLRowCount(statementHandle SQLHSTMT, rowCountPtr *SQLLEN) (ret SQLRETURN) {
 	r0, _, _ := syscall.Syscall(procSQLRowCount.Addr(), 2, uintptr(statementHandle), uintptr(unsafe.Pointer(rowCountPtr)), 0)
 	ret = SQLRETURN(r0)
@@ -189,8 +276,26 @@ func SQLSetEnvAttr(environmentHandle SQLHENV, attribute SQLINTEGER, valuePtr SQL
 	return
 }
 
+func SQLSetDescField(descriptorHandle SQLHDESC, recNumber SQLSMALLINT, fieldIdentifier SQLSMALLINT, valuePtr SQLPOINTER, bufferLength SQLINTEGER) (ret SQLRETURN) {
+	r0, _, _ := syscall.Syscall6(procSQLSetDescFieldW.Addr(), 5, uintptr(descriptorHandle), uintptr(recNumber), uintptr(fieldIdentifier), uintptr(valuePtr), uintptr(bufferLength), 0)
+	ret = SQLRETURN(r0)
+	return
+}
+
 func SQLSetConnectAttr(connectionHandle SQLHDBC, attribute SQLINTEGER, valuePtr SQLPOINTER, stringLength SQLINTEGER) (ret SQLRETURN) {
 	r0, _, _ := syscall.Syscall6(procSQLSetConnectAttrW.Addr(), 4, uintptr(connectionHandle), uintptr(attribute), uintptr(valuePtr), uintptr(stringLength), 0, 0)
 	ret = SQLRETURN(r0)
 	return
 }
+
+func SQLSetPos(statementHandle SQLHSTMT, rowNumber SQLSETPOSIROW, operation SQLUSMALLINT, lockType SQLUSMALLINT) (ret SQLRETURN) {
+	r0, _, _ := syscall.Syscall6(procSQLSetPos.Addr(), 4, uintptr(statementHandle), uintptr(rowNumber), uintptr(operation), uintptr(lockType), 0, 0)
+	ret = SQLRETURN(r0)
+	return
+}
+
+func SQLSetStmtAttr(statementHandle SQLHSTMT, attribute SQLINTEGER, valuePtr SQLPOINTER, stringLength SQLINTEGER) (ret SQLRETURN) {
+	r0, _, _ := syscall.Syscall6(procSQLSetStmtAttrW.Addr(), 4, uintptr(statementHandle), uintptr(attribute), uintptr(valuePtr), uintptr(stringLength), 0, 0)
+	ret = SQLRETURN(r0)
+	return
+}
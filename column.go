@@ -5,9 +5,13 @@
 package odbc
 
 import (
+	"bytes"
 	"database/sql/driver"
 	"errors"
 	"fmt"
+	"math"
+	"reflect"
+	"strings"
 	"time"
 	"unsafe"
 
@@ -39,24 +43,152 @@ type Column interface {
 	Value(h api.SQLHSTMT, idx int) (driver.Value, error)
 }
 
-func describeColumn(h api.SQLHSTMT, idx int, namebuf []uint16) (namelen int, sqltype api.SQLSMALLINT, size api.SQLULEN, ret api.SQLRETURN) {
-	var l, decimal, nullable api.SQLSMALLINT
+func describeColumn(h api.SQLHSTMT, idx int, namebuf []uint16) (namelen int, sqltype api.SQLSMALLINT, size api.SQLULEN, decimal api.SQLSMALLINT, ret api.SQLRETURN) {
+	var l, nullable api.SQLSMALLINT
 	ret = api.SQLDescribeCol(h, api.SQLUSMALLINT(idx+1),
 		(*api.SQLWCHAR)(unsafe.Pointer(&namebuf[0])),
 		api.SQLSMALLINT(len(namebuf)), &l,
 		&sqltype, &size, &decimal, &nullable)
-	return int(l), sqltype, size, ret
+	return int(l), sqltype, size, decimal, ret
 }
 
+// ColumnInfo describes query output column, see DescribeQuery.
+type ColumnInfo struct {
+	Name          string
+	SQLType       api.SQLSMALLINT
+	Size          api.SQLULEN
+	DecimalDigits api.SQLSMALLINT
+	Nullable      bool // true, if driver does not know
+
+	// Fields below are reported by SQLColAttribute. Source names
+	// are empty for computed columns, and for drivers that do
+	// not know them.
+	BaseTableName  string
+	BaseColumnName string
+	SchemaName     string
+	CatalogName    string
+	AutoIncrement  bool // like SQL Server IDENTITY column
+	CaseSensitive  bool
+	// Searchable tells how column can be used in WHERE clause:
+	// api.SQL_PRED_NONE, api.SQL_PRED_CHAR (only with LIKE),
+	// api.SQL_PRED_BASIC (with all operators except LIKE)
+	// or api.SQL_PRED_SEARCHABLE.
+	Searchable int
+}
+
+// colAttrString returns string attribute field of column idx,
+// or "", if driver does not report it.
+func colAttrString(h api.SQLHSTMT, idx int, field api.SQLUSMALLINT) string {
+	buf := make([]uint16, 129)
+	for {
+		var l api.SQLSMALLINT
+		ret := api.SQLColAttribute(h, api.SQLUSMALLINT(idx+1), field,
+			api.SQLPOINTER(unsafe.Pointer(&buf[0])), api.SQLSMALLINT(len(buf)*2), &l, nil)
+		if IsError(ret) {
+			return ""
+		}
+		if int(l)/2 < len(buf) {
+			return api.UTF16ToString(buf)
+		}
+		// try again with bigger buffer
+		buf = make([]uint16, int(l)/2+1)
+	}
+}
+
+// colAttrInt returns numeric attribute field of column idx,
+// or 0, if driver does not report it.
+func colAttrInt(h api.SQLHSTMT, idx int, field api.SQLUSMALLINT) int {
+	var v api.SQLLEN
+	ret := api.SQLColAttribute(h, api.SQLUSMALLINT(idx+1), field, nil, 0, nil, &v)
+	if IsError(ret) {
+		return 0
+	}
+	return int(v)
+}
+
+// describeSource sets ci fields reported by SQLColAttribute
+// for column idx of statement h.
+func (ci *ColumnInfo) describeSource(h api.SQLHSTMT, idx int) {
+	ci.BaseTableName = colAttrString(h, idx, api.SQL_DESC_BASE_TABLE_NAME)
+	ci.BaseColumnName = colAttrString(h, idx, api.SQL_DESC_BASE_COLUMN_NAME)
+	ci.SchemaName = colAttrString(h, idx, api.SQL_DESC_SCHEMA_NAME)
+	ci.CatalogName = colAttrString(h, idx, api.SQL_DESC_CATALOG_NAME)
+	ci.AutoIncrement = colAttrInt(h, idx, api.SQL_DESC_AUTO_UNIQUE_VALUE) != 0
+	ci.CaseSensitive = colAttrInt(h, idx, api.SQL_DESC_CASE_SENSITIVE) != 0
+	ci.Searchable = colAttrInt(h, idx, api.SQL_DESC_SEARCHABLE)
+}
+
+// DescribeQuery returns descriptions of columns, that query would
+// return. query is prepared, but not executed. Column source (like
+// base table name) and auto-increment flag let ORMs map result
+// columns to table columns.
+func (c *Conn) DescribeQuery(query string) ([]ColumnInfo, error) {
+	if c.bad.Load() {
+		return nil, driver.ErrBadConn
+	}
+	os, err := c.PrepareODBCStmt(query)
+	if err != nil {
+		return nil, err
+	}
+	defer os.closeByStmt()
+	var n api.SQLSMALLINT
+	ret := api.SQLNumResultCols(os.h, &n)
+	if IsError(ret) {
+		return nil, c.newError("SQLNumResultCols", os.h)
+	}
+	cols := make([]ColumnInfo, n)
+	for i := range cols {
+		namebuf := make([]uint16, c.opts.nameBufferSize())
+		for {
+			var l, nullable api.SQLSMALLINT
+			ci := &cols[i]
+			ret = api.SQLDescribeCol(os.h, api.SQLUSMALLINT(i+1),
+				(*api.SQLWCHAR)(unsafe.Pointer(&namebuf[0])),
+				api.SQLSMALLINT(len(namebuf)), &l,
+				&ci.SQLType, &ci.Size, &ci.DecimalDigits, &nullable)
+			if IsError(ret) {
+				return nil, c.newError("SQLDescribeCol", os.h)
+			}
+			if int(l) >= len(namebuf) {
+				// try again with bigger buffer
+				namebuf = make([]uint16, int(l)+1)
+				continue
+			}
+			ci.Name = api.UTF16ToString(namebuf[:l])
+			ci.Nullable = nullable != api.SQL_NO_NULLS
+			ci.describeSource(os.h, i)
+			break
+		}
+	}
+	return cols, nil
+}
+
+// Defaults for connection options that control column buffers.
+const (
+	defaultNameBufSize  = 150  // in characters
+	defaultMaxBindWidth = 1024 // wider columns are read with SQLGetData
+)
+
+// maxDecimalPrecision is used as NUMERIC and DECIMAL column
+// precision, when driver does not report one.
+const maxDecimalPrecision = 38
+
 // TODO(brainman): did not check for MS SQL timestamp
 
 func NewColumn(h api.SQLHSTMT, idx int) (Column, error) {
-	namebuf := make([]uint16, 150)
-	namelen, sqltype, size, ret := describeColumn(h, idx, namebuf)
+	return newColumn(h, idx, &connOptions{}, false)
+}
+
+// newColumn describes column idx of statement h. Type names of
+// character columns are only checked for JSON, if jsonTypes is set,
+// because that costs extra SQLColAttribute call for every column.
+func newColumn(h api.SQLHSTMT, idx int, opts *connOptions, jsonTypes bool) (Column, error) {
+	namebuf := make([]uint16, opts.nameBufferSize())
+	namelen, sqltype, size, decimal, ret := describeColumn(h, idx, namebuf)
 	if ret == api.SQL_SUCCESS_WITH_INFO && namelen > len(namebuf) {
 		// try again with bigger buffer
 		namebuf = make([]uint16, namelen)
-		namelen, sqltype, size, ret = describeColumn(h, idx, namebuf)
+		namelen, sqltype, size, decimal, ret = describeColumn(h, idx, namebuf)
 	}
 	if IsError(ret) {
 		return nil, NewError("SQLDescribeCol", h)
@@ -66,17 +198,65 @@ func NewColumn(h api.SQLHSTMT, idx int) (Column, error) {
 		return nil, errors.New("Failed to allocate column name buffer")
 	}
 	b := &BaseColumn{
-		name:    api.UTF16ToString(namebuf[:namelen]),
-		SQLType: sqltype,
+		name:         api.UTF16ToString(namebuf[:namelen]),
+		SQLType:      sqltype,
+		size:         size,
+		decimal:      decimal,
+		charAsString: opts.charAsString,
+		civilDate:    opts.civilDate,
+		maxDataSize:  opts.maxDataSize,
+		trimChar:     opts.trimChar,
+	}
+	if opts.textMode && !isCharSQLType(sqltype) {
+		// Width of value formatted as text is not
+		// known, so it is read with SQLGetData.
+		b.CType = api.SQL_C_WCHAR
+		return &NonBindableColumn{BaseColumn: b}, nil
+	}
+	if jsonTypes && sqltype != api.SQL_SS_XML && isCharSQLType(sqltype) && isJSONColumn(h, idx) {
+		// Read JSON as wide characters, so non-ASCII text is not
+		// lost in conversion to client code page.
+		b.typeName = "JSON"
+		switch sqltype {
+		case api.SQL_LONGVARCHAR, api.SQL_WLONGVARCHAR:
+			size = 0
+		}
+		return newVariableWidthColumn(b, api.SQL_C_WCHAR, size, opts.maxBindWidth())
 	}
 	switch sqltype {
 	case api.SQL_BIT:
 		return NewBindableColumn(b, api.SQL_C_BIT, 1), nil
 	case api.SQL_TINYINT, api.SQL_SMALLINT, api.SQL_INTEGER:
+		// SQL_TINYINT is unsigned (0 to 255) on SQL Server, but signed
+		// (-128 to 127) on others, like MySQL. SQL_C_LONG fits both,
+		// so driver never has to reinterpret sign of the value.
+		// Sign is only needed to report scan type of the column,
+		// see lookupUnsigned.
 		return NewBindableColumn(b, api.SQL_C_LONG, 4), nil
 	case api.SQL_BIGINT:
 		return NewBindableColumn(b, api.SQL_C_SBIGINT, 8), nil
-	case api.SQL_NUMERIC, api.SQL_DECIMAL, api.SQL_FLOAT, api.SQL_REAL, api.SQL_DOUBLE:
+	case api.SQL_NUMERIC, api.SQL_DECIMAL:
+		if opts.decimalAsNumeric {
+			if size == 0 {
+				size = maxDecimalPrecision
+			}
+			b.size = size
+			var v api.SQL_NUMERIC_STRUCT
+			return NewBindableColumn(b, api.SQL_C_NUMERIC, int(unsafe.Sizeof(v))), nil
+		}
+		if opts.decimalAsString {
+			if size == 0 {
+				// Some drivers, like Microsoft dBASE Driver,
+				// do not report precision of numeric fields.
+				size = maxDecimalPrecision
+			}
+			// room for sign, decimal point, leading zero and null-termination character
+			c := NewBindableColumn(b, api.SQL_C_CHAR, int(size)+4)
+			c.IsVariableWidth = true
+			return c, nil
+		}
+		return NewBindableColumn(b, api.SQL_C_DOUBLE, 8), nil
+	case api.SQL_FLOAT, api.SQL_REAL, api.SQL_DOUBLE:
 		return NewBindableColumn(b, api.SQL_C_DOUBLE, 8), nil
 	case api.SQL_TYPE_TIMESTAMP:
 		var v api.SQL_TIMESTAMP_STRUCT
@@ -94,33 +274,267 @@ func NewColumn(h api.SQLHSTMT, idx int) (Column, error) {
 		var v api.SQLGUID
 		return NewBindableColumn(b, api.SQL_C_GUID, int(unsafe.Sizeof(v))), nil
 	case api.SQL_CHAR, api.SQL_VARCHAR:
-		return NewVariableWidthColumn(b, api.SQL_C_CHAR, size)
+		return newVariableWidthColumn(b, api.SQL_C_CHAR, size, opts.maxBindWidth())
 	case api.SQL_WCHAR, api.SQL_WVARCHAR:
-		return NewVariableWidthColumn(b, api.SQL_C_WCHAR, size)
+		return newVariableWidthColumn(b, api.SQL_C_WCHAR, size, opts.maxBindWidth())
 	case api.SQL_BINARY, api.SQL_VARBINARY:
-		return NewVariableWidthColumn(b, api.SQL_C_BINARY, size)
+		return newVariableWidthColumn(b, api.SQL_C_BINARY, size, opts.maxBindWidth())
 	case api.SQL_LONGVARCHAR:
-		return NewVariableWidthColumn(b, api.SQL_C_CHAR, 0)
+		return newVariableWidthColumn(b, api.SQL_C_CHAR, 0, opts.maxBindWidth())
 	case api.SQL_WLONGVARCHAR, api.SQL_SS_XML:
-		return NewVariableWidthColumn(b, api.SQL_C_WCHAR, 0)
+		return newVariableWidthColumn(b, api.SQL_C_WCHAR, 0, opts.maxBindWidth())
 	case api.SQL_LONGVARBINARY:
-		return NewVariableWidthColumn(b, api.SQL_C_BINARY, 0)
+		return newVariableWidthColumn(b, api.SQL_C_BINARY, 0, opts.maxBindWidth())
 	default:
 		return nil, fmt.Errorf("unsupported column type %d", sqltype)
 	}
 }
 
+// isCharSQLType reports whether t is one of character SQL types.
+func isCharSQLType(t api.SQLSMALLINT) bool {
+	switch t {
+	case api.SQL_CHAR, api.SQL_VARCHAR, api.SQL_LONGVARCHAR,
+		api.SQL_WCHAR, api.SQL_WVARCHAR, api.SQL_WLONGVARCHAR, api.SQL_SS_XML:
+		return true
+	}
+	return false
+}
+
+// isJSONColumn reports whether driver calls type of column idx
+// "json" or "jsonb", like PostgreSQL and MySQL drivers do. These
+// drivers report JSON columns as one of character SQL types.
+func isJSONColumn(h api.SQLHSTMT, idx int) bool {
+	var buf [32]uint16
+	var l api.SQLSMALLINT
+	ret := api.SQLColAttribute(h, api.SQLUSMALLINT(idx+1), api.SQL_DESC_TYPE_NAME,
+		api.SQLPOINTER(unsafe.Pointer(&buf[0])), api.SQLSMALLINT(len(buf)*2), &l, nil)
+	if IsError(ret) {
+		return false
+	}
+	switch strings.ToLower(api.UTF16ToString(buf[:])) {
+	case "json", "jsonb":
+		return true
+	}
+	return false
+}
+
+// isUnsignedColumn reports whether driver describes
+// numeric column idx as unsigned.
+func isUnsignedColumn(h api.SQLHSTMT, idx int) bool {
+	return colAttrInt(h, idx, api.SQL_DESC_UNSIGNED) != 0
+}
+
 // BaseColumn implements common column functionality.
 type BaseColumn struct {
 	name    string
 	SQLType api.SQLSMALLINT
 	CType   api.SQLSMALLINT
+	size    api.SQLULEN     // column size reported by SQLDescribeCol
+	decimal api.SQLSMALLINT // decimal digits reported by SQLDescribeCol
+	// typeName replaces name from databaseTypeNames, if not empty.
+	typeName string
+	// charAsString makes Value return character data
+	// as string instead of []byte, see char=string.
+	charAsString bool
+	// civilDate makes Value return DATE values
+	// as Date instead of time.Time, see date=civil.
+	civilDate bool
+	// trimChar makes Value remove trailing spaces
+	// of CHAR and NCHAR values, see trim_char=true.
+	trimChar bool
+	// maxDataSize limits data size of unbound column,
+	// see max_data_size.
+	maxDataSize int
+	// unsigned is set for unsigned integer columns, once
+	// signKnown is set, see lookupUnsigned.
+	unsigned  bool
+	signKnown bool
+	// cached is value of unbound column in current row. Column
+	// data can be read with SQLGetData only once, so second Value
+	// call for the same row returns cached value.
+	cached      driver.Value
+	cachedValid bool
+}
+
+// cacheValue remembers v as value of c in current row.
+func (c *BaseColumn) cacheValue(v driver.Value) {
+	c.cached = v
+	c.cachedValid = true
+}
+
+// resetCachedValues forgets values of cols cached for previous row.
+func resetCachedValues(cols []Column) {
+	for _, c := range cols {
+		switch c := c.(type) {
+		case *BindableColumn:
+			c.cached, c.cachedValid = nil, false
+		case *NonBindableColumn:
+			c.cached, c.cachedValid = nil, false
+		}
+	}
 }
 
 func (c *BaseColumn) Name() string {
 	return c.name
 }
 
+var databaseTypeNames = map[api.SQLSMALLINT]string{
+	api.SQL_BIT:            "BIT",
+	api.SQL_TINYINT:        "TINYINT",
+	api.SQL_SMALLINT:       "SMALLINT",
+	api.SQL_INTEGER:        "INT",
+	api.SQL_BIGINT:         "BIGINT",
+	api.SQL_NUMERIC:        "NUMERIC",
+	api.SQL_DECIMAL:        "DECIMAL",
+	api.SQL_FLOAT:          "FLOAT",
+	api.SQL_REAL:           "REAL",
+	api.SQL_DOUBLE:         "DOUBLE",
+	api.SQL_TYPE_TIMESTAMP: "DATETIME",
+	api.SQL_TYPE_DATE:      "DATE",
+	api.SQL_TYPE_TIME:      "TIME",
+	api.SQL_SS_TIME2:       "TIME",
+	api.SQL_GUID:           "UNIQUEIDENTIFIER",
+	api.SQL_CHAR:           "CHAR",
+	api.SQL_VARCHAR:        "VARCHAR",
+	api.SQL_LONGVARCHAR:    "TEXT",
+	api.SQL_WCHAR:          "NCHAR",
+	api.SQL_WVARCHAR:       "NVARCHAR",
+	api.SQL_WLONGVARCHAR:   "NTEXT",
+	api.SQL_SS_XML:         "XML",
+	api.SQL_BINARY:         "BINARY",
+	api.SQL_VARBINARY:      "VARBINARY",
+	api.SQL_LONGVARBINARY:  "IMAGE",
+}
+
+// DatabaseTypeName returns uppercase database type name of the
+// column, like "NVARCHAR" or "DECIMAL". Names follow SQL Server
+// conventions, except for "JSON" columns of drivers, that report
+// them as character types. Empty string is returned for unknown types.
+// JSON values can be scanned into json.RawMessage.
+func (c *BaseColumn) DatabaseTypeName() string {
+	if c.typeName != "" {
+		return c.typeName
+	}
+	return databaseTypeNames[c.SQLType]
+}
+
+var (
+	scanTypeBool      = reflect.TypeOf(false)
+	scanTypeInt8      = reflect.TypeOf(int8(0))
+	scanTypeUint8     = reflect.TypeOf(uint8(0))
+	scanTypeInt16     = reflect.TypeOf(int16(0))
+	scanTypeUint16    = reflect.TypeOf(uint16(0))
+	scanTypeInt32     = reflect.TypeOf(int32(0))
+	scanTypeUint32    = reflect.TypeOf(uint32(0))
+	scanTypeInt64     = reflect.TypeOf(int64(0))
+	scanTypeFloat64   = reflect.TypeOf(float64(0))
+	scanTypeString    = reflect.TypeOf("")
+	scanTypeBytes     = reflect.TypeOf([]byte(nil))
+	scanTypeTime      = reflect.TypeOf(time.Time{})
+	scanTypeDate      = reflect.TypeOf(Date{})
+	scanTypeInterface = reflect.TypeOf((*interface{})(nil)).Elem()
+)
+
+// lookupUnsigned sets c.unsigned for integer column idx of statement
+// h. Driver is asked only once, and only when scan type of the
+// column is needed, so queries do not pay for SQLColAttribute call
+// for every integer column.
+func (c *BaseColumn) lookupUnsigned(h api.SQLHSTMT, idx int) {
+	if c.signKnown || c.CType != api.SQL_C_LONG {
+		return
+	}
+	c.unsigned = isUnsignedColumn(h, idx)
+	c.signKnown = true
+}
+
+// ScanType returns Go type, that is suitable to scan values of the
+// column into. Integer columns are read as int32, but their scan
+// type is as narrow as SQL type of the column, like int16 for
+// SMALLINT or uint8 for SQL Server (unsigned) TINYINT.
+func (c *BaseColumn) ScanType() reflect.Type {
+	switch c.CType {
+	case api.SQL_C_BIT:
+		return scanTypeBool
+	case api.SQL_C_LONG:
+		switch c.SQLType {
+		case api.SQL_TINYINT:
+			if c.unsigned {
+				return scanTypeUint8
+			}
+			return scanTypeInt8
+		case api.SQL_SMALLINT:
+			if c.unsigned {
+				return scanTypeUint16
+			}
+			return scanTypeInt16
+		}
+		if c.unsigned {
+			return scanTypeUint32
+		}
+		return scanTypeInt32
+	case api.SQL_C_SBIGINT:
+		return scanTypeInt64
+	case api.SQL_C_DOUBLE:
+		return scanTypeFloat64
+	case api.SQL_C_CHAR, api.SQL_C_WCHAR:
+		switch c.SQLType {
+		case api.SQL_NUMERIC, api.SQL_DECIMAL:
+			return scanTypeString
+		}
+		if c.charAsString {
+			return scanTypeString
+		}
+		return scanTypeBytes
+	case api.SQL_C_NUMERIC, api.SQL_C_GUID:
+		return scanTypeString
+	case api.SQL_C_TYPE_TIMESTAMP, api.SQL_C_TIME:
+		return scanTypeTime
+	case api.SQL_C_DATE:
+		if c.civilDate {
+			return scanTypeDate
+		}
+		return scanTypeTime
+	case api.SQL_C_BINARY:
+		if c.SQLType == api.SQL_SS_TIME2 {
+			return scanTypeTime
+		}
+		return scanTypeBytes
+	}
+	return scanTypeInterface
+}
+
+// Length returns length of character and binary column, as
+// driver.RowsColumnTypeLength describes. Character column length is
+// in characters, binary column length is in bytes. Columns, that have
+// no size limit, like SQL Server VARCHAR(MAX) (reported as SQL_VARCHAR
+// with size 0) and TEXT, return math.MaxInt64. ok is false for other
+// column types.
+func (c *BaseColumn) Length() (length int64, ok bool) {
+	switch c.SQLType {
+	case api.SQL_CHAR, api.SQL_VARCHAR, api.SQL_WCHAR, api.SQL_WVARCHAR,
+		api.SQL_BINARY, api.SQL_VARBINARY:
+		if c.size == 0 {
+			return math.MaxInt64, true
+		}
+		return int64(c.size), true
+	case api.SQL_LONGVARCHAR, api.SQL_WLONGVARCHAR, api.SQL_LONGVARBINARY, api.SQL_SS_XML:
+		return math.MaxInt64, true
+	}
+	return 0, false
+}
+
+// baseColumn returns BaseColumn of column c, or nil,
+// if c is not implemented by this package.
+func baseColumn(c Column) *BaseColumn {
+	switch c := c.(type) {
+	case *BindableColumn:
+		return c.BaseColumn
+	case *NonBindableColumn:
+		return c.BaseColumn
+	}
+	return nil
+}
+
 func (c *BaseColumn) Value(buf []byte) (driver.Value, error) {
 	var p unsafe.Pointer
 	if len(buf) > 0 {
@@ -136,19 +550,38 @@ func (c *BaseColumn) Value(buf []byte) (driver.Value, error) {
 	case api.SQL_C_DOUBLE:
 		return *((*float64)(p)), nil
 	case api.SQL_C_CHAR:
+		switch c.SQLType {
+		case api.SQL_NUMERIC, api.SQL_DECIMAL:
+			return normalizeDecimal(string(buf)), nil
+		}
+		if c.trimChar && c.SQLType == api.SQL_CHAR {
+			buf = bytes.TrimRight(buf, " ")
+		}
+		if c.charAsString {
+			return string(buf), nil
+		}
 		return buf, nil
 	case api.SQL_C_WCHAR:
 		if p == nil {
+			if c.charAsString {
+				return "", nil
+			}
 			return buf, nil
 		}
 		s := (*[1 << 28]uint16)(p)[: len(buf)/2 : len(buf)/2]
-		return utf16toutf8(s), nil
+		if c.trimChar && (c.SQLType == api.SQL_CHAR || c.SQLType == api.SQL_WCHAR) {
+			for len(s) > 0 && s[len(s)-1] == ' ' {
+				s = s[:len(s)-1]
+			}
+		}
+		if c.charAsString {
+			return api.UTF16ToString(s), nil
+		}
+		return api.UTF16ToUTF8(s), nil
+	case api.SQL_C_NUMERIC:
+		return numericString((*api.SQL_NUMERIC_STRUCT)(p)), nil
 	case api.SQL_C_TYPE_TIMESTAMP:
-		t := (*api.SQL_TIMESTAMP_STRUCT)(p)
-		r := time.Date(int(t.Year), time.Month(t.Month), int(t.Day),
-			int(t.Hour), int(t.Minute), int(t.Second), int(t.Fraction),
-			time.Local)
-		return r, nil
+		return timestampValue((*api.SQL_TIMESTAMP_STRUCT)(p)), nil
 	case api.SQL_C_GUID:
 		t := (*api.SQLGUID)(p)
 		var p1, p2 string
@@ -163,27 +596,37 @@ func (c *BaseColumn) Value(buf []byte) (driver.Value, error) {
 		return r, nil
 	case api.SQL_C_DATE:
 		t := (*api.SQL_DATE_STRUCT)(p)
-		r := time.Date(int(t.Year), time.Month(t.Month), int(t.Day),
-			0, 0, 0, 0, time.Local)
-		return r, nil
+		if c.civilDate {
+			return Date{Year: int(t.Year), Month: time.Month(t.Month), Day: int(t.Day)}, nil
+		}
+		return dateTime(int(t.Year), time.Month(t.Month), int(t.Day), 0, 0, 0, 0), nil
 	case api.SQL_C_TIME:
 		t := (*api.SQL_TIME_STRUCT)(p)
-		r := time.Date(1, time.January, 1,
-			int(t.Hour), int(t.Minute), int(t.Second), 0, time.Local)
-		return r, nil
+		return timeOfDay(int(t.Hour), int(t.Minute), int(t.Second), 0), nil
 	case api.SQL_C_BINARY:
 		if c.SQLType == api.SQL_SS_TIME2 {
 			t := (*api.SQL_SS_TIME2_STRUCT)(p)
-			r := time.Date(1, time.January, 1,
-				int(t.Hour), int(t.Minute), int(t.Second), int(t.Fraction),
-				time.Local)
-			return r, nil
+			return timeOfDay(int(t.Hour), int(t.Minute), int(t.Second), int(t.Fraction)), nil
 		}
 		return buf, nil
 	}
 	return nil, fmt.Errorf("unsupported column ctype %d", c.CType)
 }
 
+// normalizeDecimal removes surrounding spaces and adds leading zero
+// to decimal string s, if it is missing. Some drivers (like SQL Server)
+// convert 0.5 into ".5".
+func normalizeDecimal(s string) string {
+	s = strings.TrimSpace(s) // dBASE pads numbers with spaces
+	switch {
+	case strings.HasPrefix(s, "."):
+		return "0" + s
+	case strings.HasPrefix(s, "-."):
+		return "-0" + s[1:]
+	}
+	return s
+}
+
 // BindableColumn allows access to columns that can have their buffers
 // bound. Once bound at start, they are written to by odbc driver every
 // time it fetches new row. This saves on syscall and, perhaps, some
@@ -212,9 +655,15 @@ func NewBindableColumn(b *BaseColumn, ctype api.SQLSMALLINT, bufSize int) *Binda
 }
 
 func NewVariableWidthColumn(b *BaseColumn, ctype api.SQLSMALLINT, colWidth api.SQLULEN) (Column, error) {
-	if colWidth == 0 || colWidth > 1024 {
+	return newVariableWidthColumn(b, ctype, colWidth, defaultMaxBindWidth)
+}
+
+// newVariableWidthColumn returns NonBindableColumn for columns
+// wider than maxBindWidth, and BindableColumn otherwise.
+func newVariableWidthColumn(b *BaseColumn, ctype api.SQLSMALLINT, colWidth api.SQLULEN, maxBindWidth int) (Column, error) {
+	if colWidth == 0 || colWidth > api.SQLULEN(maxBindWidth) {
 		b.CType = ctype
-		return &NonBindableColumn{b}, nil
+		return &NonBindableColumn{BaseColumn: b}, nil
 	}
 	l := int(colWidth)
 	switch ctype {
@@ -238,17 +687,52 @@ func (c *BindableColumn) Bind(h api.SQLHSTMT, idx int) (bool, error) {
 	if IsError(ret) {
 		return false, NewError("SQLBindCol", h)
 	}
+	if c.CType == api.SQL_C_NUMERIC {
+		err := setNumericDesc(h, api.SQL_ATTR_APP_ROW_DESC, idx, int(c.size), int(c.decimal), unsafe.Pointer(&c.Buffer[0]))
+		if err != nil {
+			return false, err
+		}
+	}
 	c.IsBound = true
 	return true, nil
 }
 
 func (c *BindableColumn) Value(h api.SQLHSTMT, idx int) (driver.Value, error) {
 	if !c.IsBound {
-		ret := c.Len.GetData(h, idx, c.CType, c.Buffer)
-		if IsError(ret) {
-			return nil, NewError("SQLGetData", h)
+		if c.cachedValid {
+			return c.cached, nil
+		}
+		v, err := c.getData(h, idx)
+		if err != nil {
+			return nil, err
 		}
+		c.cacheValue(v)
+		return v, nil
 	}
+	return c.value(idx)
+}
+
+// getData reads value of unbound column idx with SQLGetData.
+func (c *BindableColumn) getData(h api.SQLHSTMT, idx int) (driver.Value, error) {
+	ctype := c.CType
+	if ctype == api.SQL_C_NUMERIC {
+		// SQLGetData uses precision and scale
+		// from row descriptor for SQL_ARD_TYPE.
+		err := setNumericDesc(h, api.SQL_ATTR_APP_ROW_DESC, idx, int(c.size), int(c.decimal), nil)
+		if err != nil {
+			return nil, err
+		}
+		ctype = api.SQL_ARD_TYPE
+	}
+	ret := c.Len.GetData(h, idx, ctype, c.Buffer)
+	if IsError(ret) {
+		return nil, NewError("SQLGetData", h)
+	}
+	return c.value(idx)
+}
+
+// value converts data stored in c.Buffer into column value.
+func (c *BindableColumn) value(idx int) (driver.Value, error) {
 	if c.Len.IsNull() {
 		// is NULL
 		return nil, nil
@@ -262,8 +746,14 @@ func (c *BindableColumn) Value(h api.SQLHSTMT, idx int) (driver.Value, error) {
 // NonBindableColumn provide access to columns, that can't be bound.
 // These are of character or binary type, and, usually, there is no
 // limit for their width.
+//
+// Memory used to read column data is reused for every row. So []byte
+// values are only valid until next call to Rows.Next, like values
+// of bound columns are. database/sql copies them, unless they are
+// scanned into sql.RawBytes, that must not be retained.
 type NonBindableColumn struct {
 	*BaseColumn
+	buf dataBuffer
 }
 
 func (c *NonBindableColumn) Bind(h api.SQLHSTMT, idx int) (bool, error) {
@@ -271,49 +761,138 @@ func (c *NonBindableColumn) Bind(h api.SQLHSTMT, idx int) (bool, error) {
 }
 
 func (c *NonBindableColumn) Value(h api.SQLHSTMT, idx int) (driver.Value, error) {
-	var l BufferLen
-	var total []byte
-	b := make([]byte, 1024)
-loop:
-	for {
+	if c.cachedValid {
+		return c.cached, nil
+	}
+	v, err := c.getData(h, idx)
+	if err != nil {
+		return nil, err
+	}
+	c.cacheValue(v)
+	return v, nil
+}
+
+// getData reads all data of column idx with SQLGetData.
+func (c *NonBindableColumn) getData(h api.SQLHSTMT, idx int) (driver.Value, error) {
+	total, isNull, err := readData(c.CType, &c.buf, c.maxDataSize, func(b []byte, l *BufferLen) (api.SQLRETURN, error) {
 		ret := l.GetData(h, idx, c.CType, b)
 		switch ret {
 		case api.SQL_SUCCESS:
-			if l.IsNull() {
-				// is NULL
-				return nil, nil
-			}
-			if int(l) > len(b) {
-				return nil, fmt.Errorf("too much data returned: %d bytes returned, but buffer size is %d", l, cap(b))
-			}
-			total = append(total, b[:l]...)
-			break loop
 		case api.SQL_SUCCESS_WITH_INFO:
 			err := NewError("SQLGetData", h).(*Error)
 			if len(err.Diag) > 0 && err.Diag[0].State != "01004" {
-				return nil, err
+				return ret, err
 			}
-			i := len(b)
-			switch c.CType {
-			case api.SQL_C_WCHAR:
-				i -= 2 // remove wchar (2 bytes) null-termination character
-			case api.SQL_C_CHAR:
-				i-- // remove null-termination character
+		default:
+			return ret, NewError("SQLGetData", h)
+		}
+		return ret, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if isNull {
+		return nil, nil
+	}
+	return c.BaseColumn.Value(total)
+}
+
+// maxChunkSize limits buffer growth in readData,
+// when driver does not know how much data is left.
+const maxChunkSize = 1 << 20
+
+// dataBuffer keeps memory used by readData,
+// so it can be reused to read next value.
+type dataBuffer struct {
+	chunk []byte // buffer passed to getData
+	total []byte // data of all chunks
+}
+
+// readData reads all column data of ctype type by calling getData
+// repeatedly. getData must behave as SQLGetData does: fill b with
+// the next chunk of data (null-terminated for character data), set
+// l, and return SQL_SUCCESS_WITH_INFO while there is more data to
+// read. l is either number of bytes still available, or SQL_NO_TOTAL.
+// Returned data is stored in buf memory, if buf is not nil, so it is
+// only valid until next readData call with the same buf. If limit is
+// not 0, reading data of more than limit bytes fails, before memory
+// for the data is allocated.
+func readData(ctype api.SQLSMALLINT, buf *dataBuffer, limit int, getData func(b []byte, l *BufferLen) (api.SQLRETURN, error)) (total []byte, isNull bool, err error) {
+	if buf == nil {
+		buf = new(dataBuffer)
+	}
+	if len(buf.chunk) == 0 {
+		buf.chunk = make([]byte, 1024)
+	}
+	var l BufferLen
+	b := buf.chunk
+	total = buf.total[:0]
+	for {
+		ret, err := getData(b, &l)
+		if err != nil {
+			return nil, false, err
+		}
+		if ret == api.SQL_SUCCESS {
+			if l.IsNull() {
+				return nil, true, nil
+			}
+			if int(l) > len(b) {
+				return nil, false, fmt.Errorf("too much data returned: %d bytes returned, but buffer size is %d", l, len(b))
+			}
+			if limit > 0 && len(total)+int(l) > limit {
+				return nil, false, dataTooLargeError(limit)
 			}
-			total = append(total, b[:i]...)
-			if l != api.SQL_NO_TOTAL {
-				// odbc gives us a hint about remaining data,
-				// lets get it in one go.
-				n := int(l) // total bytes for our data
-				n -= i      // subtract already received
-				n += 2      // room for biggest (wchar) null-terminator
-				if len(b) < n {
-					b = make([]byte, n)
+			buf.chunk = b
+			if len(total) == 0 {
+				if l == 0 {
+					return nil, false, nil
 				}
+				// all data fits into single chunk, no need to copy it
+				return b[:l:l], false, nil
 			}
-		default:
-			return nil, NewError("SQLGetData", h)
+			total = append(total, b[:l]...)
+			buf.total = total
+			return total[:len(total):len(total)], false, nil
+		}
+		// SQL_SUCCESS_WITH_INFO: b is full
+		i := chunkDataLen(ctype, len(b))
+		if limit > 0 && (len(total)+i > limit || l != api.SQL_NO_TOTAL && len(total)+int(l) > limit) {
+			return nil, false, dataTooLargeError(limit)
+		}
+		total = append(total, b[:i]...)
+		n := 2 * len(b)
+		if n > maxChunkSize {
+			n = maxChunkSize
+		}
+		if l != api.SQL_NO_TOTAL {
+			// odbc gives us a hint about remaining data,
+			// lets get it in one go.
+			n = int(l) // total bytes for our data
+			n -= i     // subtract already received
+			n += 2     // room for biggest (wchar) null-terminator
+		}
+		if len(b) < n {
+			b = make([]byte, n)
 		}
 	}
-	return c.BaseColumn.Value(total)
+}
+
+// dataTooLargeError returns error reported by readData,
+// when column data is larger than limit bytes.
+func dataTooLargeError(limit int) error {
+	return fmt.Errorf("column data is larger than %d bytes (see max_data_size connection string attribute)", limit)
+}
+
+// chunkDataLen returns number of data bytes in completely filled
+// buffer of bufLen bytes, returned by SQLGetData for ctype type.
+// Character data is null-terminated, and SQL_C_WCHAR data is
+// made of whole 2 bytes characters.
+func chunkDataLen(ctype api.SQLSMALLINT, bufLen int) int {
+	switch ctype {
+	case api.SQL_C_WCHAR:
+		return bufLen/2*2 - 2 // remove wchar (2 bytes) null-termination character
+	case api.SQL_C_CHAR:
+		return bufLen - 1 // remove null-termination character
+	}
+	return bufLen
 }
@@ -6,9 +6,16 @@ package odbc
 
 import (
 	"context"
+	"database/sql"
 	"database/sql/driver"
 	"errors"
+	"fmt"
+	"math/big"
+	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 	"unsafe"
 
 	"github.com/alexbrainman/odbc/api"
@@ -16,46 +23,335 @@ import (
 
 type Conn struct {
 	h                api.SQLHDBC
+	drv              *Driver // owns h, and counts its handles
 	tx               *Tx
-	bad              bool
+	bad              atomicBool
 	isMSAccessDriver bool
+	dbms             string // cached SQL_DBMS_NAME, see dbmsName
+	quote            string // cached SQL_IDENTIFIER_QUOTE_CHAR, see QuoteIdentifier
+	describeParam    bool   // use SQLDescribeParam, see supportsDescribeParam
+	opts             connOptions
+	hooks            testHooks
+	// noDeadAttr is set, when driver does not support
+	// SQL_ATTR_CONNECTION_DEAD. Ping executes pingStmt then.
+	noDeadAttr bool
+	pingStmt   *ODBCStmt
+	// savedIsolation is isolation level of connection before it
+	// was first changed, see setIsolationLevel and ResetSession.
+	savedIsolation   uintptr
+	isolationChanged bool
+	// savedCatalog is current catalog of connection before
+	// SetCatalog changed it, see ResetSession.
+	savedCatalog   string
+	catalogChanged bool
+	// procDirs caches procedure parameter directions,
+	// see setProcParamDirections.
+	procDirs map[string][]api.SQLSMALLINT
+	// abandoned counts statements, that were not cancelled
+	// promptly, and left to complete in the background. If Close
+	// is called meanwhile, the last of them disconnects c, see abandon.
+	mu        sync.Mutex
+	abandoned int
+	closed    bool
+}
+
+// atomicBool is boolean, that is safe to use from multiple
+// goroutines. Conn.bad is set by whatever goroutine finds
+// connection broken, while others may check it.
+type atomicBool int32
+
+func (b *atomicBool) Load() bool {
+	return atomic.LoadInt32((*int32)(b)) != 0
+}
+
+func (b *atomicBool) Store(v bool) {
+	var n int32
+	if v {
+		n = 1
+	}
+	atomic.StoreInt32((*int32)(b), n)
 }
 
 var accessDriverSubstr = strings.ToUpper(strings.Replace("DRIVER={Microsoft Access Driver", " ", "", -1))
 
+// Open implements driver.Driver interface. Connection string, that
+// has only DSN, UID and PWD attributes, is opened with SQLConnect.
+// Everything else is passed to SQLDriverConnect.
 func (d *Driver) Open(dsn string) (driver.Conn, error) {
 	if d.initErr != nil {
 		return nil, d.initErr
 	}
+	c, err := d.open(context.Background(), dsn, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+var (
+	connStrRewriterMu sync.Mutex
+	connStrRewriter   func(string) (string, error)
+)
+
+// SetConnectionStringRewriter sets function f, that is called with
+// connection string of every new connection. Connection is opened
+// with connection string returned by f, or fails, if f returns error.
+// f can add required attributes (like Encrypt=yes), or reject
+// insecure connection strings. f must be safe for concurrent use.
+// nil f removes the rewriter.
+func SetConnectionStringRewriter(f func(string) (string, error)) {
+	connStrRewriterMu.Lock()
+	defer connStrRewriterMu.Unlock()
+	connStrRewriter = f
+}
+
+// rewriteConnString returns dsn rewritten by function
+// set with SetConnectionStringRewriter, if any.
+func rewriteConnString(dsn string) (string, error) {
+	connStrRewriterMu.Lock()
+	f := connStrRewriter
+	connStrRewriterMu.Unlock()
+	if f == nil {
+		return dsn, nil
+	}
+	return f(dsn)
+}
+
+// open opens new connection to dsn. If loginTimeout is not 0,
+// it is used to set SQL_ATTR_LOGIN_TIMEOUT of the connection.
+// Non empty accessToken is passed to SQL Server driver with
+// SQL_COPT_SS_ACCESS_TOKEN, see AccessTokenConnector.
+// Connection retries stop, once ctx is done.
+func (d *Driver) open(ctx context.Context, dsn string, loginTimeout time.Duration, accessToken string) (*Conn, error) {
+	dsn, err := rewriteConnString(dsn)
+	if err != nil {
+		return nil, err
+	}
+	dsn, opts, err := extractConnOptions(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := d.connect(dsn, opts, loginTimeout, accessToken)
+	delay := opts.connectRetryDelay
+	if delay == 0 {
+		delay = defaultConnectRetryDelay
+	}
+	for i := 0; i < opts.connectRetries && isTransientConnectError(err); i++ {
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+		delay *= 2
+		c, err = d.connect(dsn, opts, loginTimeout, accessToken)
+	}
+	if err != nil {
+		// connect keeps all diagnostic records for
+		// isTransientConnectError, report them as usual now
+		return nil, badConnError(err)
+	}
+	return c, nil
+}
 
+// defaultConnectRetryDelay is delay before first connection
+// retry, if connect_retry_delay attribute is not set.
+const defaultConnectRetryDelay = time.Second
+
+// connect allocates new connection handle and connects it to dsn.
+// See open for loginTimeout and accessToken.
+func (d *Driver) connect(dsn string, opts connOptions, loginTimeout time.Duration, accessToken string) (*Conn, error) {
 	var out api.SQLHANDLE
 	ret := api.SQLAllocHandle(api.SQL_HANDLE_DBC, api.SQLHANDLE(d.h), &out)
 	if IsError(ret) {
 		return nil, NewError("SQLAllocHandle", d.h)
 	}
 	h := api.SQLHDBC(out)
-	drv.Stats.updateHandleCount(api.SQL_HANDLE_DBC, 1)
+	d.Stats.updateHandleCount(api.SQL_HANDLE_DBC, api.SQLHANDLE(h), 1)
 
-	b := api.StringToUTF16(dsn)
-	ret = api.SQLDriverConnect(h, 0,
-		(*api.SQLWCHAR)(unsafe.Pointer(&b[0])), api.SQL_NTS,
-		nil, 0, nil, api.SQL_DRIVER_NOPROMPT)
-	if IsError(ret) {
-		defer releaseHandle(h)
-		return nil, NewError("SQLDriverConnect", h)
+	if loginTimeout > 0 {
+		// round up to whole seconds
+		secs := (loginTimeout + time.Second - 1) / time.Second
+		ret = api.SQLSetConnectUIntPtrAttr(h, api.SQL_ATTR_LOGIN_TIMEOUT, uintptr(secs), api.SQL_IS_UINTEGER)
+		if IsError(ret) {
+			defer d.releaseHandle(h)
+			return nil, NewError("SQLSetConnectUIntPtrAttr", h)
+		}
+	}
+
+	if opts.packetSize > 0 {
+		// SQL_ATTR_PACKET_SIZE must be set before connecting.
+		ret = api.SQLSetConnectUIntPtrAttr(h, api.SQL_ATTR_PACKET_SIZE, uintptr(opts.packetSize), api.SQL_IS_UINTEGER)
+		if IsError(ret) {
+			defer d.releaseHandle(h)
+			return nil, NewError("SQLSetConnectUIntPtrAttr", h)
+		}
+	}
+
+	if accessToken != "" {
+		b := encodeAccessToken(accessToken)
+		// b must stay alive until connection is established.
+		defer runtime.KeepAlive(b)
+		ret = api.SQLSetConnectAttr(h, api.SQL_COPT_SS_ACCESS_TOKEN,
+			api.SQLPOINTER(unsafe.Pointer(&b[0])), api.SQL_IS_POINTER)
+		if IsError(ret) {
+			defer d.releaseHandle(h)
+			return nil, NewError("SQLSetConnectAttr", h)
+		}
+	}
+
+	if name, uid, pwd, ok := dsnConnectArgs(dsn); ok {
+		n := api.StringToUTF16(name)
+		u := api.StringToUTF16(uid)
+		p := api.StringToUTF16(pwd)
+		ret = api.SQLConnect(h,
+			(*api.SQLWCHAR)(unsafe.Pointer(&n[0])), api.SQL_NTS,
+			(*api.SQLWCHAR)(unsafe.Pointer(&u[0])), api.SQL_NTS,
+			(*api.SQLWCHAR)(unsafe.Pointer(&p[0])), api.SQL_NTS)
+		if IsError(ret) {
+			defer d.releaseHandle(h)
+			return nil, newDiagError("SQLConnect", h)
+		}
+	} else {
+		b := api.StringToUTF16(dsn)
+		ret = api.SQLDriverConnect(h, 0,
+			(*api.SQLWCHAR)(unsafe.Pointer(&b[0])), api.SQL_NTS,
+			nil, 0, nil, api.SQL_DRIVER_NOPROMPT)
+		if IsError(ret) {
+			defer d.releaseHandle(h)
+			return nil, newDiagError("SQLDriverConnect", h)
+		}
 	}
 	isAccess := strings.Contains(strings.ToUpper(strings.Replace(dsn, " ", "", -1)), accessDriverSubstr)
-	return &Conn{h: h, isMSAccessDriver: isAccess}, nil
+	c := &Conn{h: h, drv: d, isMSAccessDriver: isAccess, opts: opts, hooks: d.hooks}
+	c.describeParam = !opts.noDescribeParam && c.supportsDescribeParam()
+	return c, nil
+}
+
+// noDescribeParamDrivers lists ODBC drivers (as returned by
+// SQL_DRIVER_NAME) that claim SQLDescribeParam support,
+// but fail or even crash when it is called.
+var noDescribeParamDrivers = []string{
+	"duckdb",
+}
+
+// supportsDescribeParam reports whether SQLDescribeParam
+// can be used with connection c.
+func (c *Conn) supportsDescribeParam() bool {
+	if ok, err := c.SupportsFunction(api.SQL_API_SQLDESCRIBEPARAM); err == nil && !ok {
+		return false
+	}
+	name, err := c.getInfoString(api.SQL_DRIVER_NAME)
+	if err != nil {
+		return true
+	}
+	name = strings.ToLower(name)
+	for _, d := range noDescribeParamDrivers {
+		if strings.Contains(name, d) {
+			return false
+		}
+	}
+	return true
 }
 
-func (c *Conn) Close() (err error) {
+// BrowseConnect discovers attributes required to connect to a data
+// source. It calls SQLBrowseConnect with connection string in, and
+// returns out connection string, that lists attributes still required
+// (or optional) by the driver. If in has everything necessary to
+// connect, complete is set to true and out contains complete
+// connection string. BrowseConnect does not keep connection open, so
+// every call should include all attributes discovered so far.
+func (d *Driver) BrowseConnect(in string) (out string, complete bool, err error) {
+	if d.initErr != nil {
+		return "", false, d.initErr
+	}
+	bufLen := 1024
+	for {
+		var n int
+		out, complete, n, err = d.browseConnect(in, bufLen)
+		if err != nil || n < bufLen {
+			return out, complete, err
+		}
+		// out was truncated, try again with bigger buffer
+		bufLen = n + 1
+	}
+}
+
+func (d *Driver) browseConnect(in string, bufLen int) (out string, complete bool, outLen int, err error) {
+	var hout api.SQLHANDLE
+	ret := api.SQLAllocHandle(api.SQL_HANDLE_DBC, api.SQLHANDLE(d.h), &hout)
+	if IsError(ret) {
+		return "", false, 0, NewError("SQLAllocHandle", d.h)
+	}
+	h := api.SQLHDBC(hout)
+	d.Stats.updateHandleCount(api.SQL_HANDLE_DBC, api.SQLHANDLE(h), 1)
+	defer d.releaseHandle(h)
+
+	b := api.StringToUTF16(in)
+	ob := make([]uint16, bufLen)
+	var l api.SQLSMALLINT
+	ret = api.SQLBrowseConnect(h,
+		(*api.SQLWCHAR)(unsafe.Pointer(&b[0])), api.SQL_NTS,
+		(*api.SQLWCHAR)(unsafe.Pointer(&ob[0])), api.SQLSMALLINT(len(ob)), &l)
+	switch ret {
+	case api.SQL_NEED_DATA:
+		// SQLDisconnect cancels browsing
+	case api.SQL_SUCCESS, api.SQL_SUCCESS_WITH_INFO:
+		complete = true
+	default:
+		return "", false, 0, NewError("SQLBrowseConnect", h)
+	}
+	api.SQLDisconnect(h)
+	return api.UTF16ToString(ob), complete, int(l), nil
+}
+
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	pending := c.abandoned > 0
+	c.mu.Unlock()
+	if pending {
+		// c is disconnected, once abandoned statements complete
+		return nil
+	}
+	return c.close()
+}
+
+// abandon marks c bad, so database/sql discards it, and runs
+// release in the background. release must wait for abandoned
+// statement to complete and free its handles. If c is closed
+// meanwhile, c is disconnected after the last release returns.
+func (c *Conn) abandon(release func()) {
+	c.bad.Store(true)
+	c.mu.Lock()
+	c.abandoned++
+	c.mu.Unlock()
+	go func() {
+		release()
+		c.mu.Lock()
+		c.abandoned--
+		last := c.abandoned == 0 && c.closed
+		c.mu.Unlock()
+		if last {
+			c.close()
+		}
+	}()
+}
+
+func (c *Conn) close() (err error) {
 	if c.tx != nil {
 		c.tx.Rollback()
 	}
+	if c.pingStmt != nil {
+		c.pingStmt.closeByStmt()
+		c.pingStmt = nil
+	}
 	h := c.h
 	defer func() {
 		c.h = api.SQLHDBC(api.SQL_NULL_HDBC)
-		e := releaseHandle(h)
+		e := c.drv.releaseHandle(h)
 		if err == nil {
 			err = e
 		}
@@ -67,41 +363,469 @@ func (c *Conn) Close() (err error) {
 	return err
 }
 
+// HandleConn is implemented by connections of this package.
+// It gives access to native ODBC connection handle, so users
+// can call ODBC functions, that are not wrapped by the package.
+// Use it with sql.Conn.Raw:
+//
+//	err := conn.Raw(func(dc interface{}) error {
+//		h := dc.(odbc.HandleConn).Handle()
+//		// call api functions with h here
+//		return nil
+//	})
+//
+// The handle is owned by the connection. It must not be freed or
+// disconnected, and must not be used after function passed to Raw
+// returns. Changing connection state (like autocommit mode) behind
+// the package back is not supported.
+type HandleConn interface {
+	Handle() api.SQLHDBC
+}
+
+// Handle implements HandleConn interface.
+func (c *Conn) Handle() api.SQLHDBC {
+	return c.h
+}
+
 func (c *Conn) newError(apiName string, handle interface{}) error {
 	err := NewError(apiName, handle)
 	if err == driver.ErrBadConn {
-		c.bad = true
+		c.bad.Store(true)
 	}
 	return err
 }
 
+// GetAttr returns current value of integer connection attribute
+// attr, like api.SQL_ATTR_AUTOCOMMIT or api.SQL_ATTR_PACKET_SIZE.
+func (c *Conn) GetAttr(attr int32) (uintptr, error) {
+	var v uintptr
+	ret := api.SQLGetConnectAttr(c.h, api.SQLINTEGER(attr), api.SQLPOINTER(unsafe.Pointer(&v)), 0, nil)
+	if IsError(ret) {
+		return 0, c.newError("SQLGetConnectAttr", c.h)
+	}
+	return v, nil
+}
+
+// Catalog returns current catalog (default database)
+// of connection c, SQL_ATTR_CURRENT_CATALOG attribute.
+func (c *Conn) Catalog() (string, error) {
+	b := make([]uint16, 256)
+	for {
+		var l api.SQLINTEGER
+		ret := api.SQLGetConnectAttr(c.h, api.SQL_ATTR_CURRENT_CATALOG,
+			api.SQLPOINTER(unsafe.Pointer(&b[0])), api.SQLINTEGER(len(b)*2), &l)
+		if IsError(ret) {
+			return "", c.newError("SQLGetConnectAttr", c.h)
+		}
+		if int(l) < len(b)*2 {
+			return api.UTF16ToString(b), nil
+		}
+		// name was truncated, try again with bigger buffer
+		b = make([]uint16, int(l)/2+1)
+	}
+}
+
+// SetCatalog changes current catalog (default database) of
+// connection c with SQL_ATTR_CURRENT_CATALOG attribute, so no
+// USE statement is needed. database/sql restores original
+// catalog, before connection is reused, see ResetSession.
+// Use sql.Conn.Raw to call it, like
+//
+//	err := conn.Raw(func(dc interface{}) error {
+//		return dc.(*odbc.Conn).SetCatalog(tenantDB)
+//	})
+func (c *Conn) SetCatalog(name string) error {
+	if !c.catalogChanged {
+		saved, err := c.Catalog()
+		if err != nil {
+			return err
+		}
+		c.savedCatalog = saved
+	}
+	if err := c.setCatalog(name); err != nil {
+		return err
+	}
+	c.catalogChanged = true
+	return nil
+}
+
+func (c *Conn) setCatalog(name string) error {
+	b := api.StringToUTF16(name)
+	ret := api.SQLSetConnectAttr(c.h, api.SQL_ATTR_CURRENT_CATALOG,
+		api.SQLPOINTER(unsafe.Pointer(&b[0])), api.SQL_NTS)
+	if IsError(ret) {
+		return c.newError("SQLSetConnectAttr", c.h)
+	}
+	return nil
+}
+
+// resetCatalog restores current catalog of connection c,
+// if it was changed by SetCatalog.
+func (c *Conn) resetCatalog() error {
+	if !c.catalogChanged {
+		return nil
+	}
+	if err := c.setCatalog(c.savedCatalog); err != nil {
+		return err
+	}
+	c.catalogChanged = false
+	return nil
+}
+
+// SupportsFunction reports whether ODBC driver of connection c
+// supports function functionID, like api.SQL_API_SQLDESCRIBEPARAM
+// or api.SQL_API_SQLMORERESULTS. It calls SQLGetFunctions.
+func (c *Conn) SupportsFunction(functionID uint16) (bool, error) {
+	var supported api.SQLUSMALLINT
+	ret := api.SQLGetFunctions(c.h, api.SQLUSMALLINT(functionID), &supported)
+	if IsError(ret) {
+		return false, c.newError("SQLGetFunctions", c.h)
+	}
+	return supported != 0, nil
+}
+
+// Ping implements driver.Pinger interface. It uses
+// SQL_ATTR_CONNECTION_DEAD attribute, so Ping does not need
+// to talk to the server. If driver does not support the attribute,
+// Ping executes statement set by ping_query connection option, or
+// "select 1" (adjusted for Oracle and DB2, see pingQuery) instead.
+// The statement is prepared once and reused by every Ping of the
+// connection.
+// Connections, that driver found broken, are reported as
+// driver.ErrBadConn.
+func (c *Conn) Ping(ctx context.Context) error {
+	if c.bad.Load() {
+		return driver.ErrBadConn
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if c.noDeadAttr {
+		return c.pingWithStmt()
+	}
+	dead, err := c.GetAttr(api.SQL_ATTR_CONNECTION_DEAD)
+	if err != nil {
+		if !isNotSupported(err) {
+			return err
+		}
+		c.noDeadAttr = true
+		return c.pingWithStmt()
+	}
+	if dead == api.SQL_CD_TRUE {
+		c.bad.Store(true)
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+// pingWithStmt checks connection c by executing c.pingStmt.
+func (c *Conn) pingWithStmt() error {
+	if c.pingStmt == nil {
+		q, err := c.pingQuery()
+		if err != nil {
+			return err
+		}
+		os, err := c.PrepareODBCStmt(q)
+		if err != nil {
+			return err
+		}
+		c.pingStmt = os
+	}
+	h := c.pingStmt.h
+	ret := api.SQLExecute(h)
+	if IsError(ret) {
+		return c.newError("SQLExecute", h)
+	}
+	ret = api.SQLFreeStmt(h, api.SQL_CLOSE)
+	if IsError(ret) {
+		return c.newError("SQLFreeStmt", h)
+	}
+	return nil
+}
+
+// pingQuery returns statement executed by pingWithStmt. Oracle
+// and DB2 do not allow SELECT without FROM, so their dummy
+// tables are used.
+func (c *Conn) pingQuery() (string, error) {
+	if c.opts.pingQuery != "" {
+		return c.opts.pingQuery, nil
+	}
+	dbms, err := c.dbmsName()
+	if err != nil {
+		return "", err
+	}
+	return defaultPingQuery(dbms), nil
+}
+
+// defaultPingQuery returns ping statement for DBMS named dbms,
+// as returned by SQLGetInfo(SQL_DBMS_NAME).
+func defaultPingQuery(dbms string) string {
+	d := strings.ToUpper(dbms)
+	switch {
+	case strings.Contains(d, "ORACLE"):
+		return "select 1 from dual"
+	case strings.HasPrefix(d, "DB2"):
+		return "select 1 from sysibm.sysdummy1"
+	}
+	return "select 1"
+}
+
+// IsValid implements driver.Validator interface. database/sql calls
+// it before reusing pooled connection. Connection is not valid, if
+// it was found broken before, or driver reports it dead with
+// SQL_ATTR_CONNECTION_DEAD attribute. Unlike Ping, IsValid never
+// talks to the server.
+func (c *Conn) IsValid() bool {
+	if c.bad.Load() {
+		return false
+	}
+	if c.noDeadAttr {
+		return true
+	}
+	dead, err := c.GetAttr(api.SQL_ATTR_CONNECTION_DEAD)
+	if err != nil {
+		if isNotSupported(err) {
+			c.noDeadAttr = true
+		}
+		return true
+	}
+	if dead == api.SQL_CD_TRUE {
+		c.bad.Store(true)
+		return false
+	}
+	return true
+}
+
+// ResetSession implements driver.SessionResetter interface.
+// database/sql calls it before connection is reused. It rolls back
+// transaction left open by previous user of the connection, turns
+// autocommit mode on, and restores read-write access mode, isolation
+// level changed by SetIsolationLevel or BeginTx, and catalog changed
+// by SetCatalog. Other session state, like SET options and temporary
+// tables, is kept.
+func (c *Conn) ResetSession(ctx context.Context) error {
+	if c.bad.Load() {
+		return driver.ErrBadConn
+	}
+	if err := c.resetState(); err != nil {
+		c.bad.Store(true)
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+// getInfoString returns string information of type infoType
+// about the driver and data source associated with connection c.
+func (c *Conn) getInfoString(infoType api.SQLUSMALLINT) (string, error) {
+	b := make([]uint16, 256)
+	var l api.SQLSMALLINT
+	ret := api.SQLGetInfo(c.h, infoType, api.SQLPOINTER(unsafe.Pointer(&b[0])), api.SQLSMALLINT(len(b)*2), &l)
+	if IsError(ret) {
+		return "", c.newError("SQLGetInfo", c.h)
+	}
+	return api.UTF16ToString(b), nil
+}
+
+// dbmsName returns name of the DBMS product accessed by
+// connection c, like "Microsoft SQL Server" or "PostgreSQL".
+func (c *Conn) dbmsName() (string, error) {
+	if c.dbms == "" {
+		n, err := c.getInfoString(api.SQL_DBMS_NAME)
+		if err != nil {
+			return "", err
+		}
+		c.dbms = n
+	}
+	return c.dbms, nil
+}
+
+// QuoteIdentifier quotes name, like table or column name, so it can
+// be used in SQL text sent over connection c. Quote character is
+// returned by SQLGetInfo(SQL_IDENTIFIER_QUOTE_CHAR), and quote
+// characters inside name are doubled. Quote every part of qualified
+// name, like schema.table, separately. Error is returned, if driver
+// does not support quoted identifiers.
+func (c *Conn) QuoteIdentifier(name string) (string, error) {
+	if c.quote == "" {
+		q, err := c.getInfoString(api.SQL_IDENTIFIER_QUOTE_CHAR)
+		if err != nil {
+			return "", err
+		}
+		c.quote = q
+	}
+	return quoteIdentifier(c.quote, name)
+}
+
+// quoteIdentifier encloses name in quote q, see QuoteIdentifier.
+func quoteIdentifier(q, name string) (string, error) {
+	if q == "" || q == " " {
+		return "", errors.New("quoted identifiers are not supported by the driver")
+	}
+	if name == "" {
+		return "", errors.New("empty identifier")
+	}
+	if strings.IndexByte(name, 0) >= 0 {
+		return "", fmt.Errorf("identifier %q contains null character", name)
+	}
+	return q + strings.Replace(name, q, q+q, -1) + q, nil
+}
+
+// isMSSQL reports whether connection c talks to SQL Server.
+func (c *Conn) isMSSQL() (bool, error) {
+	n, err := c.dbmsName()
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(n, "SQL Server"), nil
+}
+
+// hasJSONType reports whether connection c talks to DBMS, that has
+// JSON type, but driver reports JSON columns as character columns,
+// like PostgreSQL and MySQL do. See isJSONColumn.
+func (c *Conn) hasJSONType() bool {
+	n, err := c.dbmsName()
+	if err != nil {
+		return false
+	}
+	return strings.Contains(n, "PostgreSQL") || strings.Contains(n, "MySQL")
+}
+
+// execQuery executes query that has no parameters and
+// returns no rows on connection c.
+func (c *Conn) execQuery(query string) error {
+	os, err := c.PrepareODBCStmt(query)
+	if err != nil {
+		return err
+	}
+	defer os.closeByStmt()
+	return os.Exec(nil, c)
+}
+
+// CheckNamedValue implements driver.NamedValueChecker interface.
+// It lets arbitrary-precision numbers, TVP, Typed and sql.Out reach
+// (*Parameter).BindValue unchanged, and slices reach expandSliceArgs.
+// XML values are passed as Typed with SQL_SS_XML type, JSON values
+// are passed as JSON text. Everything
+// else is converted by sliceElemValue, so unsupported types are
+// rejected before anything is bound.
+func (c *Conn) CheckNamedValue(nv *driver.NamedValue) error {
+	switch v := nv.Value.(type) {
+	case *big.Int, *big.Rat, Decimal, Numeric, TVP, Date:
+		return nil
+	case XML:
+		nv.Value = Typed{Value: string(v), SQLType: api.SQL_SS_XML}
+		return nil
+	case Typed:
+		iv, err := sliceElemValue(v.Value)
+		if err != nil {
+			return err
+		}
+		v.Value = iv
+		nv.Value = v
+		return nil
+	case sql.Out:
+		if !isOutDest(v.Dest) {
+			return fmt.Errorf("unsupported sql.Out destination type %T", v.Dest)
+		}
+		return nil
+	}
+	if isSliceArg(nv.Value) {
+		return nil
+	}
+	v, err := sliceElemValue(nv.Value)
+	if err != nil {
+		return err
+	}
+	nv.Value = v
+	return nil
+}
+
+type maxRowsKey struct{}
+
+// WithMaxRows returns copy of ctx, that makes QueryContext return
+// no more than n rows. The limit is enforced by the driver with
+// SQL_ATTR_MAX_ROWS statement attribute, so the rest of rows
+// are not sent to the client. n of 0 means no limit. Negative
+// n is ignored, and ctx is returned unchanged.
+func WithMaxRows(ctx context.Context, n int) context.Context {
+	if n < 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, maxRowsKey{}, n)
+}
+
+// ExecContext implements driver.ExecerContext interface, so
+// statements with named arguments can be executed, see
+// expandNamedArgs. Other statements are prepared and executed
+// by database/sql as usual. ctx cancels statement preparation,
+// see PrepareContext, and is checked again before execution.
+func (c *Conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if !hasNamedArgs(args) {
+		return nil, driver.ErrSkip
+	}
+	query, dargs, err := expandNamedArgs(query, args, c.bracketQuotes())
+	if err != nil {
+		return nil, err
+	}
+	st, err := c.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer st.Close()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return st.Exec(dargs)
+}
+
 // QueryContext implements the driver.QueryerContext interface.
 // As per the specifications, it honours the context timeout and returns when the context is cancelled.
 // When the context is cancelled, it first cancels the statement, closes it, and then returns an error.
+// The connection stays usable, if the statement is cancelled promptly; it is discarded otherwise,
+// see cancelQuery.
 func (c *Conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
-	// Prepare a query
-	os, err := c.PrepareODBCStmt(query)
+	query, dargs, err := expandNamedArgs(query, args, c.bracketQuotes())
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkReadOnly(query); err != nil {
+		return nil, err
+	}
+	query, dargs, err = expandSliceArgs(query, dargs, c.bracketQuotes())
 	if err != nil {
 		return nil, err
 	}
 
-	dargs, err := namedValueToValue(args)
+	// Prepare a query
+	updatable, _ := ctx.Value(updatableCursorKey{}).(bool)
+	os, err := c.prepareODBCStmt(ctx, query, updatable)
 	if err != nil {
 		return nil, err
 	}
+	if n, ok := ctx.Value(maxRowsKey{}).(int); ok {
+		if err := os.setAttr(api.SQL_ATTR_MAX_ROWS, uintptr(n)); err != nil {
+			os.closeByStmt()
+			return nil, err
+		}
+	}
+	os.streamLongData, _ = ctx.Value(streamLongDataKey{}).(bool)
 
-	// Execute the statement
-	rowsChan := make(chan driver.Rows)
-	defer close(rowsChan)
-	errorChan := make(chan error)
-	defer close(errorChan)
+	// Execute the statement. Channel is buffered, so wrapQuery
+	// never blocks, even if nobody waits for it anymore.
+	done := make(chan queryResult, 1)
 
 	if ctx.Err() != nil {
 		os.closeByStmt()
 		return nil, ctx.Err()
 	}
 
-	go c.wrapQuery(ctx, os, dargs, rowsChan, errorChan)
+	if c.opts.async && os.enableAsync() {
+		rows, err := c.queryAsync(ctx, os, dargs)
+		os.closeByStmt()
+		return rows, err
+	}
+
+	go c.wrapQuery(os, dargs, done)
 
 	var finalErr error
 	var finalRes driver.Rows
@@ -109,19 +833,13 @@ func (c *Conn) QueryContext(ctx context.Context, query string, args []driver.Nam
 	select {
 	case <-ctx.Done():
 		// Context has been cancelled or has expired, cancel the statement
-		if err := os.Cancel(); err != nil {
-			finalErr = err
-			break
+		if !c.cancelQuery(os, done) {
+			// statement is closed in the background
+			return nil, ctx.Err()
 		}
-
-		// The statement has been cancelled, the query execution should eventually fail now.
-		// We wait for it in order to avoid having a dangling goroutine running in the background
-		<-errorChan
 		finalErr = ctx.Err()
-	case err := <-errorChan:
-		finalErr = err
-	case rows := <-rowsChan:
-		finalRes = rows
+	case r := <-done:
+		finalRes, finalErr = r.rows, r.err
 	}
 
 	// Close the statement
@@ -131,27 +849,90 @@ func (c *Conn) QueryContext(ctx context.Context, query string, args []driver.Nam
 	return finalRes, finalErr
 }
 
+// queryCancelWait is how long cancelQuery (and prepare) waits
+// for cancelled statement to complete.
+const queryCancelWait = 5 * time.Second
+
+// queryResult is sent by wrapQuery, once statement completes.
+// Either rows or err is set.
+type queryResult struct {
+	rows driver.Rows
+	err  error
+}
+
+// close closes r.rows, if any.
+func (r queryResult) close() {
+	if r.rows != nil {
+		r.rows.Close()
+	}
+}
+
+// cancelQuery cancels statement os executed by wrapQuery, and waits
+// for wrapQuery to send its result. The connection is kept, if os is
+// cancelled cleanly and promptly: autocommit mode is restored then,
+// unless os is part of transaction, which is left to its owner.
+// Otherwise cancelQuery abandons the connection and returns false;
+// os is closed in the background, once it completes.
+func (c *Conn) cancelQuery(os *ODBCStmt, done <-chan queryResult) bool {
+	abandon := func() bool {
+		c.abandon(func() {
+			r := <-done
+			r.close()
+			os.closeByStmt()
+		})
+		return false
+	}
+	if err := os.Cancel(); err != nil {
+		return abandon()
+	}
+	t := time.NewTimer(queryCancelWait)
+	defer t.Stop()
+	select {
+	case r := <-done:
+		// statement might complete before it is cancelled
+		r.close()
+	case <-t.C:
+		return abandon()
+	}
+	if c.tx == nil {
+		if err := c.resetAutoCommit(); err != nil {
+			c.bad.Store(true)
+		}
+	}
+	return true
+}
+
 // wrapQuery is following the same logic as `stmt.Query()` except that we don't use a lock
 // because the ODBC statement doesn't get exposed externally.
-func (c *Conn) wrapQuery(ctx context.Context, os *ODBCStmt, dargs []driver.Value, rowsChan chan<- driver.Rows, errorChan chan<- error) {
+// It sends exactly one result to done.
+func (c *Conn) wrapQuery(os *ODBCStmt, dargs []driver.Value, done chan<- queryResult) {
 	if err := os.Exec(dargs, c); err != nil {
-		errorChan <- err
+		done <- queryResult{err: err}
 		return
 	}
 
-	if err := os.BindColumns(); err != nil {
-		errorChan <- err
+	// Statement without result set (like UPDATE) is executed
+	// anyway, so return empty rows instead of an error.
+	if err := os.BindColumns(); err != nil && err != ErrNoResultSet {
+		done <- queryResult{err: err}
 		return
 	}
 
 	os.usedByRows = true
-	rowsChan <- &Rows{os: os}
+	done <- queryResult{rows: &Rows{os: os}}
+}
 
-	// At the end of the execution, we check if the context has been cancelled
-	// to ensure the caller doesn't end up waiting for a message indefinitely (L119)
-	if ctx.Err() != nil {
-		errorChan <- ctx.Err()
+// queryAsync is like wrapQuery, but executes os in asynchronous
+// mode, so it is cancelled with ctx without extra goroutine.
+func (c *Conn) queryAsync(ctx context.Context, os *ODBCStmt, dargs []driver.Value) (driver.Rows, error) {
+	if err := os.execAsync(ctx, dargs, c); err != nil {
+		return nil, err
 	}
+	if err := os.BindColumns(); err != nil && err != ErrNoResultSet {
+		return nil, err
+	}
+	os.usedByRows = true
+	return &Rows{os: os}, nil
 }
 
 // namedValueToValue is a utility function that converts a driver.NamedValue into a driver.Value.
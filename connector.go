@@ -0,0 +1,229 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package odbc
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+type connector struct {
+	d    *Driver
+	name string
+	// accessToken returns access token for new connection, if not nil.
+	accessToken func(ctx context.Context) (string, error)
+	// keywords are merged into name, see KeywordsConnector.
+	keywords map[string]string
+}
+
+// OpenConnector implements driver.DriverContext interface.
+func (d *Driver) OpenConnector(name string) (driver.Connector, error) {
+	if d.initErr != nil {
+		return nil, d.initErr
+	}
+	return &connector{d: d, name: name}, nil
+}
+
+// NewConnector returns connector for ODBC connection string dsn,
+// so database can be opened with sql.OpenDB, like
+//
+//	db := sql.OpenDB(odbc.NewConnector("driver={ODBC Driver 18 for SQL Server};server=srv;..."))
+//
+// instead of sql.Open with "odbc" driver name. dsn may have the same
+// package attributes (like decimal=string) as sql.Open dsn has.
+func NewConnector(dsn string) driver.Connector {
+	return &connector{d: &drv, name: dsn}
+}
+
+// AccessTokenConnector returns connector, that connects to SQL
+// Server (including Azure SQL Database) with Azure Active Directory
+// access token. token is called for every new connection, so it can
+// return fresh token, when previous one expires. name must not have
+// UID, PWD or Authentication attributes. Use connector with sql.OpenDB.
+// The token is passed with SQL_COPT_SS_ACCESS_TOKEN connection
+// attribute, that is only supported by Microsoft ODBC Driver for
+// SQL Server.
+func (d *Driver) AccessTokenConnector(name string, token func(ctx context.Context) (string, error)) (driver.Connector, error) {
+	if d.initErr != nil {
+		return nil, d.initErr
+	}
+	if token == nil {
+		return nil, errors.New("access token function is nil")
+	}
+	return &connector{d: d, name: name, accessToken: token}, nil
+}
+
+// KeywordsConnector returns connector, that connects with connection
+// string name and keywords added to it, like
+//
+//	c, err := drv.KeywordsConnector(baseDSN, map[string]string{
+//		"Database":          tenantDB,
+//		"ApplicationIntent": "ReadOnly",
+//	})
+//	db := sql.OpenDB(c)
+//
+// Keywords replace values of the same (case insensitive) name in
+// name. Keyword values are escaped, so they can contain semicolons
+// and braces. keywords is copied, so it can be changed afterwards.
+func (d *Driver) KeywordsConnector(name string, keywords map[string]string) (driver.Connector, error) {
+	if d.initErr != nil {
+		return nil, d.initErr
+	}
+	c := &connector{d: d, name: name, keywords: make(map[string]string, len(keywords))}
+	for k, v := range keywords {
+		c.keywords[k] = v
+	}
+	// report bad keywords and connection string early
+	if _, err := c.connString(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ReadOnlyIntentConnector returns connector, that connects with
+// connection string name and ApplicationIntent=ReadOnly keyword, so
+// SQL Server Availability Group listener routes connections to
+// readable secondary replicas. Use it with sql.OpenDB for read-only
+// workloads. Application intent is only sent to the server, when
+// connection is established, so it cannot be changed for single
+// transaction. BeginTx with sql.TxOptions.ReadOnly sets read-only
+// access mode of existing connection instead.
+func (d *Driver) ReadOnlyIntentConnector(name string) (driver.Connector, error) {
+	return d.KeywordsConnector(name, map[string]string{"ApplicationIntent": "ReadOnly"})
+}
+
+// ErrIntegratedAuthUnavailable is returned by IntegratedAuthConnector
+// on systems other than Windows.
+var ErrIntegratedAuthUnavailable = errors.New("integrated Windows authentication is only available on Windows")
+
+// IntegratedAuthConnector returns connector, that connects with
+// connection string name and Trusted_Connection=yes keyword, so
+// SQL Server authenticates the user running the program (integrated
+// Windows authentication). name must not have UID or PWD attributes.
+// IntegratedAuthConnector returns ErrIntegratedAuthUnavailable on
+// other systems. Microsoft ODBC Driver for SQL Server on Linux and
+// macOS uses Kerberos for Trusted_Connection=yes instead; that needs
+// the keyword to be set explicitly.
+func (d *Driver) IntegratedAuthConnector(name string) (driver.Connector, error) {
+	if err := checkIntegratedAuth(runtime.GOOS, name); err != nil {
+		return nil, err
+	}
+	return d.KeywordsConnector(name, map[string]string{"Trusted_Connection": "yes"})
+}
+
+// checkIntegratedAuth verifies, that connection string name
+// can be used for integrated authentication on goos system.
+func checkIntegratedAuth(goos, name string) error {
+	if goos != "windows" {
+		return ErrIntegratedAuthUnavailable
+	}
+	attrs, err := parseConnString(name)
+	if err != nil {
+		return err
+	}
+	for _, a := range attrs {
+		switch strings.ToLower(a.key) {
+		case "uid", "pwd":
+			return fmt.Errorf("connection string with %s attribute cannot use integrated authentication", a.key)
+		}
+	}
+	return nil
+}
+
+// connString returns connection string of c.
+func (c *connector) connString() (string, error) {
+	if len(c.keywords) == 0 {
+		return c.name, nil
+	}
+	return mergeConnString(c.name, c.keywords)
+}
+
+// encodeAccessToken returns ACCESSTOKEN structure, as expected by
+// SQL_COPT_SS_ACCESS_TOKEN: 4 bytes of data length followed by
+// token, where every byte is expanded to 2 bytes.
+func encodeAccessToken(token string) []byte {
+	b := make([]byte, 4+2*len(token))
+	binary.LittleEndian.PutUint32(b, uint32(2*len(token)))
+	for i := 0; i < len(token); i++ {
+		b[4+2*i] = token[i]
+	}
+	return b
+}
+
+// Connect implements driver.Connector interface. If ctx has
+// deadline, it is used as connection login timeout. Connect
+// returns as soon as ctx is done, even if driver is still
+// connecting. Such connection is closed once it is established.
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	if c.d.initErr != nil {
+		return nil, c.d.initErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	name, err := c.connString()
+	if err != nil {
+		return nil, err
+	}
+	var loginTimeout time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		loginTimeout = time.Until(deadline)
+	}
+	var token string
+	if c.accessToken != nil {
+		token, err = c.accessToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if token == "" {
+			return nil, errors.New("empty access token")
+		}
+	}
+	if ctx.Done() == nil {
+		// ctx cannot be cancelled, no need for goroutine
+		conn, err := c.d.open(ctx, name, loginTimeout, token)
+		if err != nil {
+			return nil, err
+		}
+		return conn, nil
+	}
+
+	type result struct {
+		conn *Conn
+		err  error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		conn, err := c.d.open(ctx, name, loginTimeout, token)
+		ch <- result{conn, err}
+	}()
+	select {
+	case r := <-ch:
+		if r.err != nil {
+			return nil, r.err
+		}
+		return r.conn, nil
+	case <-ctx.Done():
+		// SQLDriverConnect cannot be cancelled, so leave it to
+		// finish in background, and discard new connection.
+		go func() {
+			if r := <-ch; r.err == nil {
+				r.conn.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+// Driver implements driver.Connector interface.
+func (c *connector) Driver() driver.Driver {
+	return c.d
+}
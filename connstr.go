@@ -0,0 +1,330 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package odbc
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// connAttr is single keyword=value pair of ODBC connection string.
+type connAttr struct {
+	key   string
+	value string
+}
+
+// parseConnString splits ODBC connection string s into attributes.
+// Attribute values can be enclosed in braces, and "}}" inside braces
+// stands for "}".
+func parseConnString(s string) ([]connAttr, error) {
+	var attrs []connAttr
+	for {
+		s = strings.TrimLeft(s, " ;")
+		if s == "" {
+			return attrs, nil
+		}
+		i := strings.IndexByte(s, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("missing '=' after %q in connection string", s)
+		}
+		a := connAttr{key: strings.TrimSpace(s[:i])}
+		s = strings.TrimLeft(s[i+1:], " ")
+		if strings.HasPrefix(s, "{") {
+			var b strings.Builder
+			for s = s[1:]; ; {
+				i = strings.IndexByte(s, '}')
+				if i < 0 {
+					return nil, fmt.Errorf("missing '}' in %q value of connection string", a.key)
+				}
+				b.WriteString(s[:i])
+				s = s[i+1:]
+				if !strings.HasPrefix(s, "}") {
+					break
+				}
+				b.WriteByte('}')
+				s = s[1:]
+			}
+			a.value = b.String()
+			s = strings.TrimLeft(s, " ")
+			if s != "" && s[0] != ';' {
+				return nil, fmt.Errorf("unexpected %q after %q value of connection string", s, a.key)
+			}
+		} else {
+			i = strings.IndexByte(s, ';')
+			if i < 0 {
+				i = len(s)
+			}
+			a.value = strings.TrimRight(s[:i], " ")
+			s = s[i:]
+		}
+		attrs = append(attrs, a)
+	}
+}
+
+// dsnConnectArgs returns DSN, UID and PWD values of connection string
+// s, if s does not have any other attributes. Such connection strings
+// are opened with SQLConnect to use the data source exactly as it is
+// configured by administrator.
+func dsnConnectArgs(s string) (dsn, uid, pwd string, ok bool) {
+	attrs, err := parseConnString(s)
+	if err != nil {
+		return "", "", "", false
+	}
+	for _, a := range attrs {
+		switch strings.ToUpper(a.key) {
+		case "DSN":
+			dsn = a.value
+			ok = true
+		case "UID":
+			uid = a.value
+		case "PWD":
+			pwd = a.value
+		default:
+			return "", "", "", false
+		}
+	}
+	return dsn, uid, pwd, ok
+}
+
+// formatConnString is the opposite of parseConnString. Values,
+// that need it, are enclosed in braces.
+func formatConnString(attrs []connAttr) string {
+	var b strings.Builder
+	for i, a := range attrs {
+		if i > 0 {
+			b.WriteByte(';')
+		}
+		b.WriteString(a.key)
+		b.WriteByte('=')
+		if strings.ContainsAny(a.value, "[]{}(),;?*=!@") || strings.TrimSpace(a.value) != a.value {
+			b.WriteByte('{')
+			b.WriteString(strings.Replace(a.value, "}", "}}", -1))
+			b.WriteByte('}')
+		} else {
+			b.WriteString(a.value)
+		}
+	}
+	return b.String()
+}
+
+// mergeConnString returns connection string s with keywords added.
+// Values of keywords, that s already has, are replaced (keywords are
+// case insensitive). New keywords are appended in sorted order.
+// Values are escaped with braces as necessary.
+func mergeConnString(s string, keywords map[string]string) (string, error) {
+	attrs, err := parseConnString(s)
+	if err != nil {
+		return "", err
+	}
+	keys := make([]string, 0, len(keywords))
+	for k := range keywords {
+		if k == "" || strings.ContainsAny(k, "=;{}") || strings.TrimSpace(k) != k {
+			return "", fmt.Errorf("invalid connection string keyword %q", k)
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		found := false
+		for i := range attrs {
+			if strings.EqualFold(attrs[i].key, k) {
+				attrs[i].value = keywords[k]
+				found = true
+			}
+		}
+		if !found {
+			attrs = append(attrs, connAttr{key: k, value: keywords[k]})
+		}
+	}
+	return formatConnString(attrs), nil
+}
+
+// Limits of packet_size connection option, as
+// allowed by TDS protocol used by SQL Server.
+const (
+	minPacketSize = 512
+	maxPacketSize = 32767
+)
+
+// connOptions are connection options consumed by this package.
+// See package documentation for the list.
+type connOptions struct {
+	decimalAsString  bool // decimal=string
+	decimalAsNumeric bool // decimal=numeric
+	charAsString     bool // char=string
+	trimChar         bool // trim_char=true
+	civilDate        bool // date=civil
+	nameBufSize      int  // name_buffer_size=N
+	bindWidth        int  // max_bind_width=N
+	noDescribeParam  bool // describe_params=false
+	textMode         bool // text_mode=true
+	readOnlyCheck    bool // read_only_check=true
+	packetSize       int  // packet_size=N
+	maxDataSize      int  // max_data_size=N
+	async            bool // async=true
+	// connect_retries=N and connect_retry_delay=D
+	connectRetries    int
+	connectRetryDelay time.Duration
+	pingQuery         string // ping_query=S
+}
+
+// nameBufferSize returns initial size of column name buffer.
+func (o *connOptions) nameBufferSize() int {
+	if o.nameBufSize > 0 {
+		return o.nameBufSize
+	}
+	return defaultNameBufSize
+}
+
+// maxBindWidth returns width of the widest character or
+// binary column, that is bound with SQLBindCol.
+func (o *connOptions) maxBindWidth() int {
+	if o.bindWidth > 0 {
+		return o.bindWidth
+	}
+	return defaultMaxBindWidth
+}
+
+// extractConnOptions removes attributes, that are consumed by this
+// package, from connection string s. It returns remaining connection
+// string, that is passed to ODBC driver manager, and options found.
+// s is returned unchanged, if it has no package attributes.
+func extractConnOptions(s string) (string, connOptions, error) {
+	var opts connOptions
+	attrs, err := parseConnString(s)
+	if err != nil {
+		// let driver manager deal with it
+		return s, opts, nil
+	}
+	rest := attrs[:0]
+	for _, a := range attrs {
+		switch strings.ToLower(a.key) {
+		case "decimal":
+			switch strings.ToLower(a.value) {
+			case "float":
+				opts.decimalAsString, opts.decimalAsNumeric = false, false
+			case "string":
+				opts.decimalAsString, opts.decimalAsNumeric = true, false
+			case "numeric":
+				opts.decimalAsString, opts.decimalAsNumeric = false, true
+			default:
+				return "", opts, fmt.Errorf("invalid decimal connection string attribute value %q", a.value)
+			}
+		case "char":
+			switch strings.ToLower(a.value) {
+			case "bytes":
+				opts.charAsString = false
+			case "string":
+				opts.charAsString = true
+			default:
+				return "", opts, fmt.Errorf("invalid char connection string attribute value %q", a.value)
+			}
+		case "trim_char":
+			switch strings.ToLower(a.value) {
+			case "true":
+				opts.trimChar = true
+			case "false":
+				opts.trimChar = false
+			default:
+				return "", opts, fmt.Errorf("invalid trim_char connection string attribute value %q", a.value)
+			}
+		case "async":
+			switch strings.ToLower(a.value) {
+			case "true":
+				opts.async = true
+			case "false":
+				opts.async = false
+			default:
+				return "", opts, fmt.Errorf("invalid async connection string attribute value %q", a.value)
+			}
+		case "date":
+			switch strings.ToLower(a.value) {
+			case "time":
+				opts.civilDate = false
+			case "civil":
+				opts.civilDate = true
+			default:
+				return "", opts, fmt.Errorf("invalid date connection string attribute value %q", a.value)
+			}
+		case "name_buffer_size":
+			if opts.nameBufSize, err = parsePositiveInt(a); err != nil {
+				return "", opts, err
+			}
+		case "max_bind_width":
+			if opts.bindWidth, err = parsePositiveInt(a); err != nil {
+				return "", opts, err
+			}
+		case "max_data_size":
+			if opts.maxDataSize, err = parsePositiveInt(a); err != nil {
+				return "", opts, err
+			}
+		case "packet_size":
+			if opts.packetSize, err = parsePositiveInt(a); err != nil {
+				return "", opts, err
+			}
+			if opts.packetSize < minPacketSize || opts.packetSize > maxPacketSize {
+				return "", opts, fmt.Errorf("packet_size connection string attribute value %d is out of range [%d, %d]", opts.packetSize, minPacketSize, maxPacketSize)
+			}
+		case "connect_retries":
+			if opts.connectRetries, err = parsePositiveInt(a); err != nil {
+				return "", opts, err
+			}
+		case "ping_query":
+			if strings.TrimSpace(a.value) == "" {
+				return "", opts, fmt.Errorf("invalid ping_query connection string attribute value %q", a.value)
+			}
+			opts.pingQuery = a.value
+		case "connect_retry_delay":
+			opts.connectRetryDelay, err = time.ParseDuration(a.value)
+			if err != nil || opts.connectRetryDelay <= 0 {
+				return "", opts, fmt.Errorf("invalid connect_retry_delay connection string attribute value %q", a.value)
+			}
+		case "text_mode":
+			switch strings.ToLower(a.value) {
+			case "true":
+				opts.textMode = true
+			case "false":
+				opts.textMode = false
+			default:
+				return "", opts, fmt.Errorf("invalid text_mode connection string attribute value %q", a.value)
+			}
+		case "describe_params":
+			switch strings.ToLower(a.value) {
+			case "true":
+				opts.noDescribeParam = false
+			case "false":
+				opts.noDescribeParam = true
+			default:
+				return "", opts, fmt.Errorf("invalid describe_params connection string attribute value %q", a.value)
+			}
+		case "read_only_check":
+			switch strings.ToLower(a.value) {
+			case "true":
+				opts.readOnlyCheck = true
+			case "false":
+				opts.readOnlyCheck = false
+			default:
+				return "", opts, fmt.Errorf("invalid read_only_check connection string attribute value %q", a.value)
+			}
+		default:
+			rest = append(rest, a)
+		}
+	}
+	if len(rest) == len(attrs) {
+		return s, opts, nil
+	}
+	return formatConnString(rest), opts, nil
+}
+
+func parsePositiveInt(a connAttr) (int, error) {
+	n, err := strconv.Atoi(a.value)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid %s connection string attribute value %q", a.key, a.value)
+	}
+	return n, nil
+}
@@ -0,0 +1,287 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package odbc
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+	"unsafe"
+
+	"github.com/alexbrainman/odbc/api"
+)
+
+type updatableCursorKey struct{}
+
+// WithUpdatableCursor returns copy of ctx, that makes QueryContext
+// open keyset-driven cursor, that allows positioned updates. Current
+// row of such cursor can be changed with Rows.UpdateRow and
+// Rows.DeleteRow, and new rows can be added with Rows.InsertRow.
+// database/sql does not give access to driver rows, so use
+// sql.Conn.Raw, like
+//
+//	err := conn.Raw(func(dc interface{}) error {
+//		c := dc.(*odbc.Conn)
+//		rows, err := c.QueryContext(odbc.WithUpdatableCursor(ctx), "select id, name from t", nil)
+//		if err != nil {
+//			return err
+//		}
+//		defer rows.Close()
+//		r := rows.(*odbc.Rows)
+//		dest := make([]driver.Value, len(r.Columns()))
+//		for r.Next(dest) == nil {
+//			if err := r.UpdateRow(map[int]driver.Value{1: "new name"}); err != nil {
+//				return err
+//			}
+//		}
+//		return nil
+//	})
+//
+// Only bound columns (see max_bind_width connection string option)
+// can be modified.
+func WithUpdatableCursor(ctx context.Context) context.Context {
+	return context.WithValue(ctx, updatableCursorKey{}, true)
+}
+
+// setUpdatableCursor makes cursor of statement h updatable.
+func setUpdatableCursor(h api.SQLHSTMT) error {
+	ret := api.SQLSetStmtUIntPtrAttr(h, api.SQL_ATTR_CURSOR_TYPE, api.SQL_CURSOR_KEYSET_DRIVEN, api.SQL_IS_UINTEGER)
+	if IsError(ret) {
+		return NewError("SQLSetStmtUIntPtrAttr", h)
+	}
+	ret = api.SQLSetStmtUIntPtrAttr(h, api.SQL_ATTR_CONCURRENCY, api.SQL_CONCUR_VALUES, api.SQL_IS_UINTEGER)
+	if IsError(ret) {
+		return NewError("SQLSetStmtUIntPtrAttr", h)
+	}
+	return nil
+}
+
+var errNotUpdatable = errors.New("Rows cursor is not updatable, use WithUpdatableCursor")
+
+func (r *Rows) checkUpdatable() error {
+	if r.isClosed() {
+		return errRowsClosed
+	}
+	if !r.os.updatable {
+		return errNotUpdatable
+	}
+	return nil
+}
+
+// RowNumber returns number of current row in the result set, as
+// reported by SQL_ATTR_ROW_NUMBER statement attribute. Rows are
+// numbered from 1, and 0 means that current row number cannot be
+// determined. The number is only meaningful for scrollable cursors,
+// like one opened with WithUpdatableCursor. Error is returned, if
+// driver does not support the attribute.
+func (r *Rows) RowNumber() (int64, error) {
+	if r.isClosed() {
+		return 0, errRowsClosed
+	}
+	n, err := r.os.getAttr(api.SQL_ATTR_ROW_NUMBER)
+	if err != nil {
+		return 0, err
+	}
+	return int64(n), nil
+}
+
+// UpdateRow sets columns of current row to values, indexed by column
+// number, and writes the row to the data source with SQLSetPos.
+// Columns, that are not in values, are left unchanged.
+func (r *Rows) UpdateRow(values map[int]driver.Value) error {
+	if err := r.checkUpdatable(); err != nil {
+		return err
+	}
+	if err := r.os.setRowValues(values); err != nil {
+		return err
+	}
+	ret := api.SQLSetPos(r.os.h, 1, api.SQL_UPDATE, api.SQL_LOCK_NO_CHANGE)
+	if IsError(ret) {
+		return NewError("SQLSetPos", r.os.h)
+	}
+	return nil
+}
+
+// DeleteRow deletes current row from the data source with SQLSetPos.
+func (r *Rows) DeleteRow() error {
+	if err := r.checkUpdatable(); err != nil {
+		return err
+	}
+	ret := api.SQLSetPos(r.os.h, 1, api.SQL_DELETE, api.SQL_LOCK_NO_CHANGE)
+	if IsError(ret) {
+		return NewError("SQLSetPos", r.os.h)
+	}
+	return nil
+}
+
+// InsertRow adds new row with values, indexed by column number,
+// to the data source with SQLBulkOperations. Columns, that are
+// not in values, get their default values. Current row data is
+// lost, so call Next before calling UpdateRow or DeleteRow again.
+func (r *Rows) InsertRow(values map[int]driver.Value) error {
+	if err := r.checkUpdatable(); err != nil {
+		return err
+	}
+	if err := r.os.setRowValues(values); err != nil {
+		return err
+	}
+	ret := api.SQLBulkOperations(r.os.h, api.SQL_ADD)
+	if IsError(ret) {
+		return NewError("SQLBulkOperations", r.os.h)
+	}
+	return nil
+}
+
+// setRowValues stores values into buffers of bound columns of s.
+// Columns, that are not in values, are marked as ignored.
+func (s *ODBCStmt) setRowValues(values map[int]driver.Value) error {
+	for i := range values {
+		if i < 0 || i >= len(s.Cols) {
+			return fmt.Errorf("invalid column number %d", i)
+		}
+	}
+	for i, c := range s.Cols {
+		bc, ok := c.(*BindableColumn)
+		v, found := values[i]
+		if !found {
+			if ok && bc.IsBound {
+				bc.Len = api.SQL_COLUMN_IGNORE
+			}
+			continue
+		}
+		if !ok || !bc.IsBound {
+			return fmt.Errorf("column %d is not bound, so it cannot be modified", i)
+		}
+		if err := bc.setValue(v); err != nil {
+			return fmt.Errorf("column %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// setValue stores v into bound buffer of c.
+func (c *BindableColumn) setValue(v driver.Value) error {
+	v, err := driver.DefaultParameterConverter.ConvertValue(v)
+	if err != nil {
+		return err
+	}
+	if v == nil {
+		c.Len = api.SQL_NULL_DATA
+		return nil
+	}
+	p := unsafe.Pointer(&c.Buffer[0])
+	switch c.CType {
+	case api.SQL_C_BIT:
+		d, ok := v.(bool)
+		if !ok {
+			break
+		}
+		c.Buffer[0] = 0
+		if d {
+			c.Buffer[0] = 1
+		}
+		c.Len = 1
+		return nil
+	case api.SQL_C_LONG:
+		d, ok := v.(int64)
+		if !ok {
+			break
+		}
+		if d < math.MinInt32 || d > math.MaxInt32 {
+			return fmt.Errorf("value %d is out of range", d)
+		}
+		*(*int32)(p) = int32(d)
+		c.Len = 4
+		return nil
+	case api.SQL_C_SBIGINT:
+		d, ok := v.(int64)
+		if !ok {
+			break
+		}
+		*(*int64)(p) = d
+		c.Len = 8
+		return nil
+	case api.SQL_C_DOUBLE:
+		switch d := v.(type) {
+		case float64:
+			*(*float64)(p) = d
+		case int64:
+			*(*float64)(p) = float64(d)
+		default:
+			return fmt.Errorf("unsupported value type %T", v)
+		}
+		c.Len = 8
+		return nil
+	case api.SQL_C_WCHAR:
+		s, ok := textValue(v)
+		if !ok {
+			break
+		}
+		b := api.StringToUTF16(s)
+		if len(b)*2 > len(c.Buffer) {
+			return fmt.Errorf("value %q is too long", s)
+		}
+		copy((*[1 << 28]uint16)(p)[:len(b):len(b)], b)
+		c.Len = BufferLen((len(b) - 1) * 2)
+		return nil
+	case api.SQL_C_CHAR:
+		s, ok := textValue(v)
+		if !ok {
+			break
+		}
+		if len(s)+1 > len(c.Buffer) {
+			return fmt.Errorf("value %q is too long", s)
+		}
+		copy(c.Buffer, s)
+		c.Buffer[len(s)] = 0
+		c.Len = BufferLen(len(s))
+		return nil
+	case api.SQL_C_BINARY:
+		d, ok := v.([]byte)
+		if !ok {
+			break
+		}
+		if len(d) > len(c.Buffer) {
+			return fmt.Errorf("value of %d bytes is too long", len(d))
+		}
+		copy(c.Buffer, d)
+		c.Len = BufferLen(len(d))
+		return nil
+	case api.SQL_C_TYPE_TIMESTAMP:
+		d, ok := v.(time.Time)
+		if !ok {
+			break
+		}
+		y, m, day := d.Date()
+		*(*api.SQL_TIMESTAMP_STRUCT)(p) = api.SQL_TIMESTAMP_STRUCT{
+			Year:     api.SQLSMALLINT(y),
+			Month:    api.SQLUSMALLINT(m),
+			Day:      api.SQLUSMALLINT(day),
+			Hour:     api.SQLUSMALLINT(d.Hour()),
+			Minute:   api.SQLUSMALLINT(d.Minute()),
+			Second:   api.SQLUSMALLINT(d.Second()),
+			Fraction: api.SQLUINTEGER(d.Nanosecond()),
+		}
+		c.Len = BufferLen(unsafe.Sizeof(api.SQL_TIMESTAMP_STRUCT{}))
+		return nil
+	default:
+		return fmt.Errorf("columns of C type %d cannot be modified", c.CType)
+	}
+	return fmt.Errorf("unsupported value type %T", v)
+}
+
+// textValue returns string or []byte v as string.
+func textValue(v driver.Value) (string, bool) {
+	switch d := v.(type) {
+	case string:
+		return d, true
+	case []byte:
+		return string(d), true
+	}
+	return "", false
+}
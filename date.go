@@ -0,0 +1,90 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package odbc
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alexbrainman/odbc/api"
+)
+
+// Date is calendar date without time of day and time zone, like
+// SQL DATE value is. DATE columns are returned as time.Time at
+// midnight in time.Local, so their values depend on local time zone
+// rules, unless date=civil connection string option is used. Then
+// DATE columns are returned as Date. Date can be scanned from DATE
+// and TIMESTAMP columns with either option, and it is bound as
+// SQL_TYPE_DATE when passed as parameter.
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// DateOf returns date of t in t location.
+func DateOf(t time.Time) Date {
+	var d Date
+	d.Year, d.Month, d.Day = t.Date()
+	return d
+}
+
+// String returns d in yyyy-mm-dd format.
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// In returns time.Time of midnight of d in loc location.
+func (d Date) In(loc *time.Location) time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+}
+
+// Scan implements sql.Scanner interface. It accepts Date, time.Time
+// and text in yyyy-mm-dd format.
+func (d *Date) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case Date:
+		*d = v
+		return nil
+	case time.Time:
+		*d = DateOf(v)
+		return nil
+	case string:
+		return d.parse(v)
+	case []byte:
+		return d.parse(string(v))
+	case nil:
+		return fmt.Errorf("cannot scan NULL into Date")
+	}
+	return fmt.Errorf("cannot scan %T into Date", src)
+}
+
+func (d *Date) parse(s string) error {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return err
+	}
+	*d = DateOf(t)
+	return nil
+}
+
+// dateTime returns value of date or time column. Values of all date
+// and time columns are in time.Local, and components, that SQL type
+// of the column does not store, are set as package documentation
+// describes, so applications do not need to check column type.
+func dateTime(year int, month time.Month, day, hour, min, sec, nsec int) time.Time {
+	return time.Date(year, month, day, hour, min, sec, nsec, time.Local)
+}
+
+// timeOfDay returns value of TIME column, see dateTime.
+func timeOfDay(hour, min, sec, nsec int) time.Time {
+	return dateTime(1, time.January, 1, hour, min, sec, nsec)
+}
+
+// timestampValue returns value of TIMESTAMP column, see dateTime.
+func timestampValue(t *api.SQL_TIMESTAMP_STRUCT) time.Time {
+	return dateTime(int(t.Year), time.Month(t.Month), int(t.Day),
+		int(t.Hour), int(t.Minute), int(t.Second), int(t.Fraction))
+}
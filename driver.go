@@ -3,6 +3,105 @@
 // license that can be found in the LICENSE file.
 
 // Package odbc implements database/sql driver to access data via odbc interface.
+//
+// Connection string is passed to ODBC driver manager unchanged, except
+// for these attributes, that are consumed by the package itself:
+//
+//	decimal=float   return NUMERIC and DECIMAL columns as float64 (default)
+//	decimal=string  return NUMERIC and DECIMAL columns (including SQL Server
+//	                MONEY and SMALLMONEY) as strings with exact decimal value
+//	decimal=numeric same as decimal=string, but values are fetched as
+//	                SQL_NUMERIC_STRUCT instead of text, so they do not
+//	                depend on how driver formats numbers
+//	char=bytes      return character columns as []byte (default)
+//	char=string     return character columns as strings, like most
+//	                other database/sql drivers do
+//	trim_char=true  remove trailing spaces of fixed width CHAR and NCHAR
+//	                values; VARCHAR values are returned unchanged
+//	date=time       return DATE columns as time.Time at midnight
+//	                in time.Local (default)
+//	date=civil      return DATE columns as Date, that has no time of
+//	                day and time zone
+//	name_buffer_size=N
+//	                initial size (in characters) of column name buffer,
+//	                150 by default; longer names need extra SQLDescribeCol call
+//	max_bind_width=N
+//	                character and binary columns up to N characters wide are
+//	                bound with SQLBindCol, wider columns are read with
+//	                SQLGetData; 1024 by default
+//	max_data_size=N largest value (in bytes) of column, that is read with
+//	                SQLGetData; reading bigger values fails instead of
+//	                allocating memory for them; no limit by default
+//	packet_size=N   network packet size in bytes (512 to 32767), set
+//	                with SQL_ATTR_PACKET_SIZE before connecting; bigger
+//	                packets help to read large results over slow links
+//	connect_retries=N
+//	                connect again up to N times, if connection fails
+//	                with network error (SQLSTATE 08001, 08S01 or HYT00);
+//	                authentication failures are never retried
+//	connect_retry_delay=D
+//	                delay before first retry, like 500ms, doubled
+//	                before every next one; 1s by default
+//	async=true      execute QueryContext statements in asynchronous
+//	                mode (SQL_ATTR_ASYNC_ENABLE) and poll them, so
+//	                cancelled statements are stopped with SQLCancel
+//	                without extra goroutine; statements of drivers,
+//	                that do not support asynchronous mode, are executed
+//	                as usual
+//	text_mode=true  return values of all columns as text formatted by
+//	                the driver, like character columns; useful for
+//	                dumping data into CSV files
+//	describe_params=false
+//	                do not call SQLDescribeParam; it is also skipped for
+//	                drivers that do not support it (like DuckDB)
+//	read_only_check=true
+//	                refuse statements, that contain keywords like INSERT,
+//	                DROP or EXEC, inside read-only transactions; this
+//	                protects against drivers that ignore read-only mode
+//	ping_query=S    statement, that Ping executes, if driver does not
+//	                support SQL_ATTR_CONNECTION_DEAD; by default it is
+//	                "select 1 from dual" for Oracle, "select 1 from
+//	                sysibm.sysdummy1" for DB2 and "select 1" otherwise
+//
+// Output parameters are passed as sql.Out to Exec. Pointers passed to
+// OUT and INPUT_OUTPUT parameters of procedure call, like
+//
+//	db.Exec("{? = call dbo.proc(?, ?)}", &ret, 1, &out)
+//
+// are handled as sql.Out too, because parameter directions are
+// discovered with SQLProcedureColumns when statement is prepared.
+//
+// Query accepts output parameters too, but drivers only send their
+// values after all result sets. So output parameter destinations are
+// set, when rows.NextResultSet returns false after the last result
+// set is read, like
+//
+//	rows, err := db.Query("{call dbo.proc(?)}", sql.Out{Dest: &out})
+//	...
+//	for rows.Next() {
+//		// read procedure result set
+//	}
+//	for rows.NextResultSet() {
+//		// read more result sets, if any
+//	}
+//	// out is set here, unless rows.Err() reports an error
+//
+// Date and time columns are returned as time.Time in time.Local.
+// Components, that SQL type does not store, are always the same:
+// DATE values are at midnight, TIME values (including SQL Server
+// TIME(n), which keeps fractional seconds) are on January 1 of year 1.
+// TIMESTAMP values (like SQL Server DATETIME and DATETIME2) have
+// fractional seconds in nanoseconds.
+//
+// Exec and Query accept named arguments (see sql.Named) instead of
+// positional ones. Every @name reference to named argument in query
+// is replaced with parameter marker, so the same value can be used
+// many times, like
+//
+//	db.Exec("update t set a = @v where b = @v", sql.Named("v", 1))
+//
+// Other names, like T-SQL variables, are left alone. Named arguments
+// cannot be used with statements prepared by Prepare.
 package odbc
 
 import (
@@ -17,9 +116,24 @@ type Driver struct {
 	Stats
 	h       api.SQLHENV // environment handle
 	initErr error
+	hooks   testHooks // copied into every new connection
 }
 
-func initDriver() error {
+// testHooks let tests change behavior of connection. All
+// hooks are nil, unless they are set by tests.
+type testHooks struct {
+	// beforeExecute is called just before SQLExecute.
+	beforeExecute func()
+	// beforePrepare is called just before SQLPrepare of query.
+	beforePrepare func(query string)
+	// setAutoCommit replaces setting SQL_ATTR_AUTOCOMMIT
+	// connection attribute to a.
+	setAutoCommit func(a uintptr) error
+}
+
+// init allocates environment handle of d and sets its connection
+// pooling mode.
+func (d *Driver) init(pooling bool) error {
 
 	//Allocate environment handle
 	var out api.SQLHANDLE
@@ -28,32 +142,31 @@ func initDriver() error {
 	if IsError(ret) {
 		return NewError("SQLAllocHandle", api.SQLHENV(in))
 	}
-	drv.h = api.SQLHENV(out)
-	err := drv.Stats.updateHandleCount(api.SQL_HANDLE_ENV, 1)
+	d.h = api.SQLHENV(out)
+	err := d.Stats.updateHandleCount(api.SQL_HANDLE_ENV, out, 1)
 	if err != nil {
 		return err
 	}
 
 	// will use ODBC v3
-	ret = api.SQLSetEnvUIntPtrAttr(drv.h, api.SQL_ATTR_ODBC_VERSION, api.SQL_OV_ODBC3, 0)
+	ret = api.SQLSetEnvUIntPtrAttr(d.h, api.SQL_ATTR_ODBC_VERSION, api.SQL_OV_ODBC3, 0)
 	if IsError(ret) {
-		defer releaseHandle(drv.h)
-		return NewError("SQLSetEnvUIntPtrAttr", drv.h)
+		defer d.releaseHandle(d.h)
+		return NewError("SQLSetEnvUIntPtrAttr", d.h)
 	}
 
-	//TODO: find a way to make this attribute changeable at runtime
-	//Enable connection pooling
-	ret = api.SQLSetEnvUIntPtrAttr(drv.h, api.SQL_ATTR_CONNECTION_POOLING, api.SQL_CP_ONE_PER_HENV, api.SQL_IS_UINTEGER)
-	if IsError(ret) {
-		defer releaseHandle(drv.h)
-		return NewError("SQLSetEnvUIntPtrAttr", drv.h)
+	//Set connection pooling mode, see SetConnectionPooling
+	err = d.SetConnectionPooling(pooling)
+	if err != nil {
+		defer d.releaseHandle(d.h)
+		return err
 	}
 
 	//Set relaxed connection pool matching
-	ret = api.SQLSetEnvUIntPtrAttr(drv.h, api.SQL_ATTR_CP_MATCH, api.SQL_CP_RELAXED_MATCH, api.SQL_IS_UINTEGER)
+	ret = api.SQLSetEnvUIntPtrAttr(d.h, api.SQL_ATTR_CP_MATCH, api.SQL_CP_RELAXED_MATCH, api.SQL_IS_UINTEGER)
 	if IsError(ret) {
-		defer releaseHandle(drv.h)
-		return NewError("SQLSetEnvUIntPtrAttr", drv.h)
+		defer d.releaseHandle(d.h)
+		return NewError("SQLSetEnvUIntPtrAttr", d.h)
 	}
 
 	//TODO: it would be nice if we could call "drv.SetMaxIdleConns(0)" here but from the docs it looks like
@@ -62,15 +175,57 @@ func initDriver() error {
 	return nil
 }
 
+// NewDriver returns new Driver with its own environment handle and
+// connection pooling mode. Register it with sql.Register under name
+// other than "odbc", or pass it to sql.OpenDB with its OpenConnector
+// method, to use different pooling settings for different databases
+// in one process. Close the driver, when it is no longer used.
+func NewDriver(pooling bool) (*Driver, error) {
+	d := new(Driver)
+	err := d.init(pooling)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// SetConnectionPooling enables or disables ODBC driver manager
+// connection pooling of the default "odbc" driver. Pooling is
+// enabled by default. database/sql keeps its own pool of idle
+// connections (see sql.DB.SetMaxIdleConns), so it is recommended
+// to disable ODBC pooling, and let database/sql own the pool. Then
+// every connection closed by database/sql is disconnected from the
+// server. Only connections opened after the call are affected.
+func SetConnectionPooling(enabled bool) error {
+	return drv.SetConnectionPooling(enabled)
+}
+
+// SetConnectionPooling enables or disables ODBC driver manager
+// connection pooling of connections opened by d.
+func (d *Driver) SetConnectionPooling(enabled bool) error {
+	if d.initErr != nil {
+		return d.initErr
+	}
+	var v uintptr = api.SQL_CP_OFF
+	if enabled {
+		v = api.SQL_CP_ONE_PER_HENV
+	}
+	ret := api.SQLSetEnvUIntPtrAttr(d.h, api.SQL_ATTR_CONNECTION_POOLING, v, api.SQL_IS_UINTEGER)
+	if IsError(ret) {
+		return NewError("SQLSetEnvUIntPtrAttr", d.h)
+	}
+	return nil
+}
+
 func (d *Driver) Close() error {
 	// TODO(brainman): who will call (*Driver).Close (to dispose all opened handles)?
 	h := d.h
 	d.h = api.SQLHENV(api.SQL_NULL_HENV)
-	return releaseHandle(h)
+	return d.releaseHandle(h)
 }
 
 func init() {
-	err := initDriver()
+	err := drv.init(true)
 	if err != nil {
 		drv.initErr = err
 	}
@@ -0,0 +1,125 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package odbc
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"unicode/utf16"
+	"unsafe"
+
+	"github.com/alexbrainman/odbc/api"
+)
+
+// DataSource describes ODBC data source, as returned by DataSources.
+type DataSource struct {
+	Name        string
+	Description string // usually name of the data source driver
+}
+
+// DriverInfo describes installed ODBC driver, as returned by Drivers.
+type DriverInfo struct {
+	Description string
+	Attributes  []string // like "FileUsage=1"
+}
+
+// envMu protects enumeration state of driver environment handle.
+var envMu sync.Mutex
+
+// DataSources returns list of data sources, that are known to
+// ODBC driver manager. It uses driver environment handle, so no
+// new handles are allocated. ctx is checked before every call to
+// SQLDataSources, so slow enumeration can be abandoned.
+func DataSources(ctx context.Context) ([]DataSource, error) {
+	items, err := enumerate(ctx, "SQLDataSources", api.SQL_MAX_DSN_LENGTH+1, 256,
+		func(direction api.SQLUSMALLINT, b1, b2 []uint16, l1, l2 *api.SQLSMALLINT) api.SQLRETURN {
+			return api.SQLDataSources(drv.h, direction,
+				(*api.SQLWCHAR)(unsafe.Pointer(&b1[0])), api.SQLSMALLINT(len(b1)), l1,
+				(*api.SQLWCHAR)(unsafe.Pointer(&b2[0])), api.SQLSMALLINT(len(b2)), l2)
+		})
+	if err != nil {
+		return nil, err
+	}
+	dss := make([]DataSource, len(items))
+	for i, it := range items {
+		dss[i] = DataSource{Name: it[0], Description: it[1]}
+	}
+	return dss, nil
+}
+
+// Drivers returns list of installed ODBC drivers. Like DataSources,
+// it uses driver environment handle and can be abandoned with ctx.
+func Drivers(ctx context.Context) ([]DriverInfo, error) {
+	items, err := enumerate(ctx, "SQLDrivers", 256, 1024,
+		func(direction api.SQLUSMALLINT, b1, b2 []uint16, l1, l2 *api.SQLSMALLINT) api.SQLRETURN {
+			return api.SQLDrivers(drv.h, direction,
+				(*api.SQLWCHAR)(unsafe.Pointer(&b1[0])), api.SQLSMALLINT(len(b1)), l1,
+				(*api.SQLWCHAR)(unsafe.Pointer(&b2[0])), api.SQLSMALLINT(len(b2)), l2)
+		})
+	if err != nil {
+		return nil, err
+	}
+	ds := make([]DriverInfo, len(items))
+	for i, it := range items {
+		ds[i].Description = it[0]
+		// attributes are separated by null characters
+		for _, a := range strings.Split(it[1], "\x00") {
+			if a != "" {
+				ds[i].Attributes = append(ds[i].Attributes, a)
+			}
+		}
+	}
+	return ds, nil
+}
+
+type enumFunc func(direction api.SQLUSMALLINT, b1, b2 []uint16, l1, l2 *api.SQLSMALLINT) api.SQLRETURN
+
+// enumerate calls fn until it returns SQL_NO_DATA, and returns
+// pair of strings fn stored in its buffers for every item. Item
+// values are not lost, if initial buffer sizes n1 and n2 are too
+// small, because enumeration is repeated with bigger buffers.
+func enumerate(ctx context.Context, apiName string, n1, n2 int, fn enumFunc) ([][2]string, error) {
+	if drv.initErr != nil {
+		return nil, drv.initErr
+	}
+	envMu.Lock()
+	defer envMu.Unlock()
+	for {
+		items, truncated, err := enumerateOnce(ctx, apiName, n1, n2, fn)
+		if err != nil || !truncated || n1 > 1<<14 || n2 > 1<<14 {
+			return items, err
+		}
+		n1 *= 2
+		n2 *= 2
+	}
+}
+
+func enumerateOnce(ctx context.Context, apiName string, n1, n2 int, fn enumFunc) (items [][2]string, truncated bool, err error) {
+	b1 := make([]uint16, n1)
+	b2 := make([]uint16, n2)
+	direction := api.SQLUSMALLINT(api.SQL_FETCH_FIRST)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, false, err
+		}
+		var l1, l2 api.SQLSMALLINT
+		ret := fn(direction, b1, b2, &l1, &l2)
+		if ret == api.SQL_NO_DATA {
+			return items, false, nil
+		}
+		if IsError(ret) {
+			return nil, false, NewError(apiName, drv.h)
+		}
+		if int(l1) >= n1 || int(l2) >= n2 {
+			return items, true, nil
+		}
+		items = append(items, [2]string{
+			string(utf16.Decode(b1[:l1])),
+			string(utf16.Decode(b2[:l2])),
+		})
+		direction = api.SQL_FETCH_NEXT
+	}
+}
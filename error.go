@@ -6,6 +6,7 @@ package odbc
 
 import (
 	"database/sql/driver"
+	"errors"
 	"fmt"
 	"strings"
 	"unsafe"
@@ -17,9 +18,10 @@ func IsError(ret api.SQLRETURN) bool {
 	return !(ret == api.SQL_SUCCESS || ret == api.SQL_SUCCESS_WITH_INFO)
 }
 
+// DiagRecord is single ODBC diagnostic record, as returned by SQLGetDiagRec.
 type DiagRecord struct {
-	State       string
-	NativeError int
+	State       string // SQLSTATE, like "23000"
+	NativeError int    // driver specific error code
 	Message     string
 }
 
@@ -27,20 +29,115 @@ func (r *DiagRecord) String() string {
 	return fmt.Sprintf("{%s} %s", r.State, r.Message)
 }
 
+// Error is ODBC error returned by API function APIName.
+// Diag has all diagnostic records of the failed call in the
+// order SQLGetDiagRec returns them, so the first record is
+// usually the most important one.
 type Error struct {
 	APIName string
 	Diag    []DiagRecord
 }
 
+// Records returns copy of e.Diag. Unlike Error, it keeps SQLSTATE
+// and native error code of every record separate, so they can be
+// checked by the program.
+func (e *Error) Records() []DiagRecord {
+	rs := make([]DiagRecord, len(e.Diag))
+	copy(rs, e.Diag)
+	return rs
+}
+
 func (e *Error) Error() string {
 	ss := make([]string, len(e.Diag))
 	for i, r := range e.Diag {
 		ss[i] = r.String()
 	}
-	return e.APIName + ": " + strings.Join(ss, "\n")
+	msg := e.APIName + ": " + strings.Join(ss, "\n")
+	if e.isConnBusy() {
+		msg += "\n" + ErrConnBusy.Error()
+	}
+	return msg
+}
+
+// ErrConnBusy is reported, when statement is executed while results
+// of another statement on the same connection are not fully read.
+// Use errors.Is(err, ErrConnBusy) to check for it.
+var ErrConnBusy = errors.New("connection is busy with results of another statement: close or read all previous Rows first, or add MARS_Connection=yes to SQL Server connection string")
+
+func (e *Error) isConnBusy() bool {
+	for _, r := range e.Diag {
+		if strings.Contains(strings.ToLower(r.Message), "connection is busy") {
+			return true
+		}
+	}
+	return false
+}
+
+// Is reports whether e matches target. It allows
+// errors.Is(err, ErrConnBusy) to work.
+func (e *Error) Is(target error) bool {
+	return target == ErrConnBusy && e.isConnBusy()
+}
+
+// isNotSupported reports whether err is ODBC error, that says
+// that driver does not support requested attribute or feature.
+func isNotSupported(err error) bool {
+	e, ok := err.(*Error)
+	if !ok {
+		return false
+	}
+	for _, r := range e.Diag {
+		switch r.State {
+		case "HY092", "HYC00", "IM001":
+			return true
+		}
+	}
+	return false
+}
+
+// isTransientConnectError reports whether connection attempt
+// failed with err because of network problem, that might go away,
+// so connecting again could succeed. Authentication failures
+// (like SQLSTATE 28000) are never transient.
+func isTransientConnectError(err error) bool {
+	e, ok := err.(*Error)
+	if !ok {
+		return false
+	}
+	transient := false
+	for _, r := range e.Diag {
+		switch r.State {
+		case "28000":
+			return false
+		case "08001", "08S01", "HYT00":
+			transient = true
+		}
+	}
+	return transient
 }
 
 func NewError(apiName string, handle interface{}) error {
+	return badConnError(newDiagError(apiName, handle))
+}
+
+// badConnError returns driver.ErrBadConn, if err is *Error,
+// that reports broken connection (SQLSTATE 08S01). Otherwise
+// err is returned unchanged.
+func badConnError(err error) error {
+	if e, ok := err.(*Error); ok {
+		for _, r := range e.Diag {
+			if r.State == "08S01" {
+				return driver.ErrBadConn
+			}
+		}
+	}
+	return err
+}
+
+// newDiagError is like NewError, but it always returns all
+// diagnostic records of handle, even if connection is broken,
+// so they can be examined (see isTransientConnectError).
+func newDiagError(apiName string, handle interface{}) error {
 	h, ht, herr := ToHandleAndType(handle)
 	if herr != nil {
 		return herr
@@ -66,9 +163,6 @@ func NewError(apiName string, handle interface{}) error {
 			NativeError: int(ne),
 			Message:     api.UTF16ToString(msg),
 		}
-		if r.State == "08S01" {
-			return driver.ErrBadConn
-		}
 		err.Diag = append(err.Diag, r)
 	}
 	return err
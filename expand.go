@@ -0,0 +1,263 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package odbc
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// isSliceArg reports whether v is a slice, that should be expanded
+// into list of parameters. []byte and values that implement
+// driver.Valuer are passed as is.
+func isSliceArg(v interface{}) bool {
+	if _, ok := v.(driver.Valuer); ok {
+		return false
+	}
+	t := reflect.TypeOf(v)
+	return t != nil && t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8
+}
+
+// expandSliceArgs rewrites query, so every slice argument gets
+// its own parameter marker for every slice element. This allows
+// to use
+//
+//	db.Query("select * from t where id in (?)", []int{1, 2, 3})
+//
+// The query is executed as "select * from t where id in (?, ?, ?)"
+// with arguments 1, 2 and 3. Markers inside string literals,
+// quoted identifiers and comments are ignored, brackets quote
+// identifiers only if brackets is true (see bracketQuotes).
+// expandSliceArgs returns query and args unchanged, if there are
+// no slices in args.
+func expandSliceArgs(query string, args []driver.Value, brackets bool) (string, []driver.Value, error) {
+	hasSlice := false
+	for _, a := range args {
+		if isSliceArg(a) {
+			hasSlice = true
+			break
+		}
+	}
+	if !hasSlice {
+		return query, args, nil
+	}
+
+	var b strings.Builder
+	newArgs := make([]driver.Value, 0, len(args))
+	n := 0 // number of markers seen so far
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		end := quoteEnd(query, i, brackets)
+		switch {
+		case end != "":
+		case c == '?':
+			if n >= len(args) {
+				return "", nil, fmt.Errorf("query has more parameter markers than %d arguments given", len(args))
+			}
+			a := args[n]
+			n++
+			if !isSliceArg(a) {
+				b.WriteByte('?')
+				newArgs = append(newArgs, a)
+				continue
+			}
+			v := reflect.ValueOf(a)
+			if v.Len() == 0 {
+				return "", nil, fmt.Errorf("argument %d is an empty slice", n)
+			}
+			for j := 0; j < v.Len(); j++ {
+				if j > 0 {
+					b.WriteString(", ")
+				}
+				b.WriteByte('?')
+				e, err := sliceElemValue(v.Index(j).Interface())
+				if err != nil {
+					return "", nil, fmt.Errorf("argument %d element %d: %v", n, j, err)
+				}
+				newArgs = append(newArgs, e)
+			}
+			continue
+		default:
+			b.WriteByte(c)
+			continue
+		}
+		// copy quoted text or comment as is
+		j := skipQuoted(query, i, end)
+		b.WriteString(query[i:j])
+		i = j - 1
+	}
+	if n != len(args) {
+		return "", nil, fmt.Errorf("query has %d parameter markers, but %d arguments given", n, len(args))
+	}
+	return b.String(), newArgs, nil
+}
+
+// quoteEnd returns text, that ends string literal, quoted identifier
+// or comment, that starts at query[i], or "", if none starts there.
+// [name] is quoted identifier only if brackets is true.
+func quoteEnd(query string, i int, brackets bool) string {
+	switch c := query[i]; {
+	case c == '\'':
+		return "'"
+	case c == '"':
+		return `"`
+	case c == '[' && brackets:
+		return "]"
+	case c == '-' && strings.HasPrefix(query[i:], "--"):
+		return "\n"
+	case c == '/' && strings.HasPrefix(query[i:], "/*"):
+		return "*/"
+	}
+	return ""
+}
+
+// bracketQuotes reports whether brackets quote identifiers, like
+// [name], in SQL text sent over connection c. This is true for SQL
+// Server and Access only. Elsewhere brackets are array subscripts
+// and constructors, like PostgreSQL ARRAY[?], and markers inside
+// them must not be ignored.
+func (c *Conn) bracketQuotes() bool {
+	if c.isMSAccessDriver {
+		return true
+	}
+	n, err := c.dbmsName()
+	if err != nil {
+		return false
+	}
+	return strings.Contains(n, "SQL Server") || strings.EqualFold(n, "ACCESS")
+}
+
+// skipQuoted returns index of query just after quoted text
+// or comment, that starts at query[i] and ends with end.
+func skipQuoted(query string, i int, end string) int {
+	j := strings.Index(query[i+1:], end)
+	if j < 0 {
+		return len(query)
+	}
+	return j + i + 1 + len(end)
+}
+
+// hasNamedArgs reports whether any of args is named, see sql.Named.
+func hasNamedArgs(args []driver.NamedValue) bool {
+	for _, a := range args {
+		if a.Name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// isNameChar reports whether c can be part of parameter name.
+func isNameChar(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// expandNamedArgs rewrites query, so every @name reference to named
+// argument (see sql.Named) becomes parameter marker, and returns
+// arguments for all markers. Named argument can be used many times,
+// like
+//
+//	db.Exec("update t set a = @v where b = @v", sql.Named("v", 1))
+//
+// The query is executed as "update t set a = ? where b = ?" with
+// arguments 1 and 1. Other names, like T-SQL variables and
+// @@ROWCOUNT, are left unchanged, and so are names inside string
+// literals, quoted identifiers and comments. Named and positional
+// arguments cannot be mixed. brackets is passed to quoteEnd.
+// expandNamedArgs returns query unchanged, if there are no named
+// arguments.
+func expandNamedArgs(query string, args []driver.NamedValue, brackets bool) (string, []driver.Value, error) {
+	if !hasNamedArgs(args) {
+		dargs, err := namedValueToValue(args)
+		return query, dargs, err
+	}
+	named := make(map[string]driver.Value, len(args))
+	for _, a := range args {
+		if a.Name == "" {
+			return "", nil, fmt.Errorf("argument %d: named and positional arguments cannot be mixed", a.Ordinal)
+		}
+		named[a.Name] = a.Value
+	}
+	used := make(map[string]bool, len(named))
+	var b strings.Builder
+	var newArgs []driver.Value
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		if end := quoteEnd(query, i, brackets); end != "" {
+			j := skipQuoted(query, i, end)
+			b.WriteString(query[i:j])
+			i = j - 1
+			continue
+		}
+		switch c {
+		case '?':
+			return "", nil, errors.New("query has parameter markers, but arguments are named")
+		case '@':
+			j := i + 1
+			if j < len(query) && query[j] == '@' {
+				// system function, like @@ROWCOUNT
+				j++
+			}
+			for j < len(query) && isNameChar(query[j]) {
+				j++
+			}
+			name := query[i+1 : j]
+			if v, ok := named[name]; ok {
+				b.WriteByte('?')
+				newArgs = append(newArgs, v)
+				used[name] = true
+			} else {
+				b.WriteString(query[i:j])
+			}
+			i = j - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	for _, a := range args {
+		if !used[a.Name] {
+			return "", nil, fmt.Errorf("named argument %q is not used in query", a.Name)
+		}
+	}
+	return b.String(), newArgs, nil
+}
+
+// sliceElemValue converts parameter (or slice element, or Typed
+// value) v into value that (*Parameter).BindValue accepts. JSON
+// values and values, that implement json.Marshaler (but not
+// driver.Valuer), are passed as JSON text. float32 and integers,
+// that are smaller than 64 bits, are passed as is, so they are bound
+// as SQL_REAL and smallest SQL integer type that fits. Everything
+// else is converted as database/sql does: int and uint64 become
+// int64 and so on.
+func sliceElemValue(v interface{}) (driver.Value, error) {
+	switch x := v.(type) {
+	case *big.Int, *big.Rat, Decimal, Numeric, Date, float32:
+		return v, nil
+	case int8, int16, int32, uint8, uint16, uint32:
+		return v, nil
+	case JSON:
+		b, err := json.Marshal(x.Value)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case driver.Valuer, time.Time:
+		// converted below, even if they implement json.Marshaler
+	case json.Marshaler:
+		b, err := x.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	}
+	return driver.DefaultParameterConverter.ConvertValue(v)
+}
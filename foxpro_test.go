@@ -8,6 +8,7 @@ import (
 	"database/sql"
 	"flag"
 	"fmt"
+	"math/big"
 	"testing"
 	"time"
 
@@ -18,18 +19,24 @@ var (
 	fox = flag.String("fox", "testdata", "directory where foxpro tables reside")
 )
 
-func TestFoxPro(t *testing.T) {
-	conn := fmt.Sprintf("driver={Microsoft dBASE Driver (*.dbf)};driverid=277;dbq=%s;",
-		*fox)
+func foxproConnect(t *testing.T, extra string) *sql.DB {
+	conn := fmt.Sprintf("driver={Microsoft dBASE Driver (*.dbf)};driverid=277;dbq=%s;%s",
+		*fox, extra)
 
 	db, err := sql.Open("odbc", conn)
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer db.Close()
 	if err := db.Ping(); err != nil {
+		db.Close()
 		t.Skipf("skipping test: %v", err)
 	}
+	return db
+}
+
+func TestFoxPro(t *testing.T) {
+	db := foxproConnect(t, "")
+	defer db.Close()
 
 	type row struct {
 		char       string
@@ -155,3 +162,46 @@ func TestFoxPro(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestFoxProDecimalAsString(t *testing.T) {
+	db := foxproConnect(t, "decimal=string;")
+	defer db.Close()
+
+	// 0.123 and 99.99 cannot be represented by float exactly
+	want := map[int]string{
+		0: "12.73",
+		1: "2",
+		2: "99.99",
+		3: "0.123",
+	}
+	rows, err := db.Query("select id, num_6_3 from fldtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var s string
+		if err := rows.Scan(&id, &s); err != nil {
+			t.Fatal(err)
+		}
+		w, ok := want[id]
+		if !ok {
+			t.Errorf("unexpected row with id %d", id)
+			continue
+		}
+		// compare values, so trailing zeros do not matter
+		v, ok := new(big.Rat).SetString(s)
+		if !ok {
+			t.Errorf("row %d: num_6_3 returned invalid decimal %q", id, s)
+			continue
+		}
+		wv, _ := new(big.Rat).SetString(w)
+		if v.Cmp(wv) != 0 {
+			t.Errorf("row %d: num_6_3 expected %v, but received %q", id, w, s)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
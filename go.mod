@@ -1,5 +1,7 @@
 module github.com/alexbrainman/odbc
 
+go 1.27.1
+
 require (
 	github.com/go-ole/go-ole v1.2.5
 	golang.org/x/sys v0.0.0-20190916202348-b4ddaad3f8a3
@@ -25,13 +25,17 @@ func ToHandleAndType(handle interface{}) (h api.SQLHANDLE, ht api.SQLSMALLINT, e
 	case api.SQLHSTMT:
 		ht = api.SQL_HANDLE_STMT
 		h = api.SQLHANDLE(v)
+	case api.SQLHDESC:
+		ht = api.SQL_HANDLE_DESC
+		h = api.SQLHANDLE(v)
 	default:
 		err = fmt.Errorf("unexpected handle type %T", v)
 	}
 	return h, ht, err
 }
 
-func releaseHandle(handle interface{}) error {
+// releaseHandle frees handle, that was counted in s.
+func (s *Stats) releaseHandle(handle interface{}) error {
 	h, ht, err := ToHandleAndType(handle)
 	if err != nil {
 		return err
@@ -43,5 +47,5 @@ func releaseHandle(handle interface{}) error {
 	if IsError(ret) {
 		return NewError("SQLFreeHandle", handle)
 	}
-	return drv.Stats.updateHandleCount(ht, -1)
+	return s.updateHandleCount(ht, h, -1)
 }
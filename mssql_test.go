@@ -9,11 +9,15 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"math"
+	"math/big"
 	"net"
+	"reflect"
 	"runtime"
 	"strconv"
 	"strings"
@@ -21,6 +25,7 @@ import (
 	"sync/atomic"
 	"testing"
 	"time"
+	"unsafe"
 
 	"github.com/alexbrainman/odbc/api"
 )
@@ -124,7 +129,7 @@ func mssqlConnect() (db *sql.DB, stmtCount int, err error) {
 	return mssqlConnectWithParams(newConnParams())
 }
 
-func closeDB(t *testing.T, db *sql.DB, shouldStmtCount, ignoreIfStmtCount int) {
+func closeDB(t testing.TB, db *sql.DB, shouldStmtCount, ignoreIfStmtCount int) {
 	s := db.Driver().(*Driver).Stats
 	err := db.Close()
 	if err != nil {
@@ -267,6 +272,32 @@ func driverExec(t *testing.T, dc driver.Conn, query string) {
 	}
 }
 
+func TestMSSQLBrowseConnect(t *testing.T) {
+	if isFreeTDS() {
+		t.Skip("skipping test; freetds does not implement SQLBrowseConnect")
+	}
+	params := newConnParams()
+
+	out, complete, err := drv.BrowseConnect("driver=" + params["driver"] + ";")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if complete {
+		t.Fatalf("browsing with driver name only should not complete: %q", out)
+	}
+	if !strings.Contains(strings.ToUpper(out), "SERVER") {
+		t.Errorf("SERVER attribute should be requested, but %q returned", out)
+	}
+
+	out, complete, err = drv.BrowseConnect(params.makeODBCConnectionString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !complete {
+		t.Fatalf("browsing with full connection string should complete: %q", out)
+	}
+}
+
 func TestMSSQLCreateInsertDelete(t *testing.T) {
 	db, sc, err := mssqlConnect()
 	if err != nil {
@@ -480,409 +511,1090 @@ func TestMSSQLTransactions(t *testing.T) {
 	exec(t, db, "drop table dbo.temp")
 }
 
-type matchFunc func(v interface{}) error
+func TestMSSQLNamedTx(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
 
-func match(a interface{}) matchFunc {
-	return func(b interface{}) error {
-		switch got := b.(type) {
-		case nil:
-			switch expect := a.(type) {
-			case nil:
-				// matching
-			default:
-				return fmt.Errorf("expect %v, but got %v", expect, got)
-			}
-		case bool:
-			expect, ok := a.(bool)
-			if !ok {
-				return fmt.Errorf("couldn't convert expected value %v(%T) to %T", a, a, got)
-			}
-			if got != expect {
-				return fmt.Errorf("expect %v, but got %v", expect, got)
-			}
-		case int32:
-			expect, ok := a.(int32)
-			if !ok {
-				return fmt.Errorf("couldn't convert expected value %v(%T) to %T", a, a, got)
-			}
-			if got != expect {
-				return fmt.Errorf("expect %v, but got %v", expect, got)
-			}
-		case int64:
-			expect, ok := a.(int64)
-			if !ok {
-				return fmt.Errorf("couldn't convert expected value %v(%T) to %T", a, a, got)
-			}
-			if got != expect {
-				return fmt.Errorf("expect %v, but got %v", expect, got)
-			}
-		case float64:
-			switch expect := a.(type) {
-			case float64:
-				if got != expect {
-					return fmt.Errorf("expect %v, but got %v", expect, got)
-				}
-			case int64:
-				if got != float64(expect) {
-					return fmt.Errorf("expect %v, but got %v", expect, got)
-				}
-			default:
-				return fmt.Errorf("unsupported type %T", expect)
-			}
-		case string:
-			expect, ok := a.(string)
-			if !ok {
-				return fmt.Errorf("couldn't convert expected value %v(%T) to %T", a, a, got)
-			}
-			if got != expect {
-				return fmt.Errorf("expect %q, but got %q", expect, got)
-			}
-		case []byte:
-			expect, ok := a.([]byte)
-			if !ok {
-				return fmt.Errorf("couldn't convert expected value %v(%T) to %T", a, a, got)
-			}
-			if !bytes.Equal(got, expect) {
-				return fmt.Errorf("expect %v, but got %v", expect, got)
-			}
-		case time.Time:
-			expect, ok := a.(time.Time)
-			if !ok {
-				return fmt.Errorf("couldn't convert expected value %v(%T) to %T", a, a, got)
-			}
-			if !got.Equal(expect) {
-				return fmt.Errorf("expect %q, but got %q", expect, got)
-			}
-		default:
-			return fmt.Errorf("unsupported type %T", got)
+	db.Exec("drop table dbo.temp")
+	exec(t, db, "create table dbo.temp (a int)")
+	defer exec(t, db, "drop table dbo.temp")
+
+	ctx := WithTxName(context.Background(), "go_named_tx")
+	for _, commit := range []bool{false, true} {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var n int
+		err = tx.QueryRow("select count(*) from sys.dm_tran_active_transactions where name = 'go_named_tx'").Scan(&n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 1 {
+			t.Errorf("expected 1 active transaction named go_named_tx, but found %d", n)
+		}
+		if _, err := tx.Exec("insert into dbo.temp (a) values (1)"); err != nil {
+			t.Fatal(err)
+		}
+		if commit {
+			err = tx.Commit()
+		} else {
+			err = tx.Rollback()
+		}
+		if err != nil {
+			t.Fatal(err)
 		}
-		return nil
 	}
-}
+	var n int
+	if err := db.QueryRow("select count(*) from dbo.temp").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 committed row, but found %d", n)
+	}
 
-type typeTest struct {
-	query string
-	match matchFunc
+	if _, err := db.BeginTx(WithTxName(context.Background(), "bad name"), nil); err == nil {
+		t.Error("BeginTx with invalid transaction name should fail")
+	}
 }
 
-var veryLongString = strings.Repeat("abcd ", 206)
+func TestMSSQLSavepoints(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
 
-var typeTests = []typeTest{
-	// bool
-	{"select cast(1 as bit)", match(true)},
-	{"select cast(2 as bit)", match(true)},
-	{"select cast(0 as bit)", match(false)},
-	{"select cast(NULL as bit)", match(nil)},
+	db.Exec("drop table dbo.temp")
+	exec(t, db, "create table dbo.temp (name varchar(20))")
 
-	// int
-	{"select cast(0 as int)", match(int32(0))},
-	{"select cast(123 as int)", match(int32(123))},
-	{"select cast(-4 as int)", match(int32(-4))},
-	{"select cast(NULL as int)", match(nil)},
-	{"select cast(0 as tinyint)", match(int32(0))},
-	{"select cast(255 as tinyint)", match(int32(255))},
-	{"select cast(-32768 as smallint)", match(int32(-32768))},
-	{"select cast(32767 as smallint)", match(int32(32767))},
-	{"select cast(-9223372036854775808 as bigint)", match(int64(-9223372036854775808))},
-	{"select cast(9223372036854775807 as bigint)", match(int64(9223372036854775807))},
+	dc, err := drv.Open(newConnParams().makeODBCConnectionString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dc.Close()
 
-	// decimal, float, real
-	{"select cast(123 as decimal(5, 0))", match(float64(123))},
-	{"select cast(-123 as decimal(5, 0))", match(float64(-123))},
-	{"select cast(123.5 as decimal(5, 0))", match(float64(124))},
-	{"select cast(NULL as decimal(5, 0))", match(nil)},
-	{"select cast(123.45 as decimal(5, 2))", match(123.45)},
-	{"select cast(-123.45 as decimal(5, 2))", match(-123.45)},
-	{"select cast(123.456 as decimal(5, 2))", match(123.46)},
-	{"select cast(0.123456789 as float)", match(0.123456789)},
-	{"select cast(NULL as float)", match(nil)},
-	{"select cast(3.6666667461395264 as real)", match(3.6666667461395264)},
-	{"select cast(NULL as real)", match(nil)},
-	{"select cast(1.2333333504e+10 as real)", match(1.2333333504e+10)},
+	dtx, err := dc.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := dtx.(*Tx)
+	driverExec(t, dc, "insert into dbo.temp (name) values ('before')")
+	if err := tx.Savepoint("sp1"); err != nil {
+		t.Fatal(err)
+	}
+	driverExec(t, dc, "insert into dbo.temp (name) values ('after')")
+	if err := tx.RollbackToSavepoint("sp1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.ReleaseSavepoint("sp1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Savepoint("sp1; drop table dbo.temp"); err == nil {
+		t.Fatal("invalid savepoint name must fail")
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Savepoint("sp2"); err == nil {
+		t.Fatal("savepoint outside of transaction must fail")
+	}
 
-	// money
-	{"select cast(12 as money)", match(float64(12))},
-	{"select cast(-12 as money)", match(float64(-12))},
-	{"select cast(0.01 as money)", match(0.01)},
-	{"select cast(0.0123 as money)", match(0.0123)},
-	{"select cast(NULL as money)", match(nil)},
-	{"select cast(1 as smallmoney)", match(float64(1))},
-	{"select cast(0.0123 as smallmoney)", match(0.0123)},
-	{"select cast(NULL as smallmoney)", match(nil)},
+	var names []string
+	rows, err := db.Query("select name from dbo.temp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != "before" {
+		t.Fatalf("unexpected table content: %v", names)
+	}
 
-	// strings
-	{"select cast(123 as varchar(21))", match([]byte("123"))},
-	{"select cast(123 as char(5))", match([]byte("123  "))},
-	{"select cast('abcde' as varchar(3))", match([]byte("abc"))},
-	{"select cast('' as varchar(5))", match([]byte(""))},
-	{"select cast(NULL as varchar(5))", match(nil)},
-	{"select cast(123 as nvarchar(21))", match([]byte("123"))},
-	{"select cast('abcde' as nvarchar(3))", match([]byte("abc"))},
-	{"select cast('' as nvarchar(5))", match([]byte(""))},
-	{"select cast(NULL as nvarchar(5))", match(nil)},
+	exec(t, db, "drop table dbo.temp")
+}
 
-	// datetime, smalldatetime
-	{"select cast('20151225' as datetime)", match(time.Date(2015, 12, 25, 0, 0, 0, 0, time.Local))},
-	{"select cast('2007-05-08 12:35:29.123' as datetime)", match(time.Date(2007, 5, 8, 12, 35, 29, 123e6, time.Local))},
-	{"select cast(NULL as datetime)", match(nil)},
-	{"select cast('2007-05-08 12:35:29.123' as smalldatetime)", match(time.Date(2007, 5, 8, 12, 35, 0, 0, time.Local))},
+func TestMSSQLIsolationLevels(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
 
-	// uniqueidentifier
-	{"select cast('0e984725-c51c-4bf4-9960-e1c80e27aba0' as uniqueidentifier)", match("0e984725-c51c-4bf4-9960-e1c80e27aba0")},
-	{"select cast(NULL as uniqueidentifier)", match(nil)},
+	// values from sys.dm_exec_sessions.transaction_isolation_level
+	tests := []struct {
+		level sql.IsolationLevel
+		want  int
+	}{
+		{sql.LevelReadUncommitted, 1},
+		{sql.LevelReadCommitted, 2},
+		{sql.LevelRepeatableRead, 3},
+		{sql.LevelSerializable, 4},
+		{sql.LevelSnapshot, 5},
+	}
+	for _, test := range tests {
+		if test.level == sql.LevelSnapshot {
+			_, err := db.Exec("alter database current set allow_snapshot_isolation on")
+			if err != nil {
+				t.Logf("skipping %v: %v", test.level, err)
+				continue
+			}
+		}
+		tx, err := db.BeginTx(context.Background(), &sql.TxOptions{Isolation: test.level})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got int
+		err = tx.QueryRow("select transaction_isolation_level from sys.dm_exec_sessions where session_id = @@spid").Scan(&got)
+		if err != nil {
+			tx.Rollback()
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("%v: unexpected isolation level %d, want %d", test.level, got, test.want)
+		}
+	}
 
-	// string blobs
-	{"select cast('abc' as varchar(max))", match([]byte("abc"))},
-	{"select cast('' as varchar(max))", match([]byte(""))},
-	{fmt.Sprintf("select cast('%s' as varchar(max))", veryLongString), match([]byte(veryLongString))},
-	{"select cast(NULL as varchar(max))", match(nil)},
-	{"select cast('abc' as nvarchar(max))", match([]byte("abc"))},
-	{"select cast('' as nvarchar(max))", match([]byte(""))},
-	{fmt.Sprintf("select cast('%s' as nvarchar(max))", veryLongString), match([]byte(veryLongString))},
-	{"select cast(NULL as nvarchar(max))", match(nil)},
-	{"select cast('abc' as text)", match([]byte("abc"))},
-	{"select cast('' as text)", match([]byte(""))},
-	{fmt.Sprintf("select cast('%s' as text)", veryLongString), match([]byte(veryLongString))},
-	{"select cast(NULL as text)", match(nil)},
-	{"select cast('abc' as ntext)", match([]byte("abc"))},
-	{"select cast('' as ntext)", match([]byte(""))},
-	{fmt.Sprintf("select cast('%s' as ntext)", veryLongString), match([]byte(veryLongString))},
-	{"select cast(NULL as ntext)", match(nil)},
+	for _, level := range []sql.IsolationLevel{sql.LevelWriteCommitted, sql.LevelLinearizable} {
+		tx, err := db.BeginTx(context.Background(), &sql.TxOptions{Isolation: level})
+		if err == nil {
+			tx.Rollback()
+			t.Errorf("%v: unexpected success, expected error", level)
+		}
+	}
+}
 
-	// xml
-	{"select cast(N'<root>hello</root>' as xml)", match([]byte("<root>hello</root>"))},
-	{"select cast(N'<root><doc><item1>dd</item1></doc></root>' as xml)", match([]byte("<root><doc><item1>dd</item1></doc></root>"))},
-
-	// binary blobs
-	{"select cast('abc' as binary(5))", match([]byte{'a', 'b', 'c', 0, 0})},
-	{"select cast('' as binary(5))", match([]byte{0, 0, 0, 0, 0})},
-	{"select cast(NULL as binary(5))", match(nil)},
-	{"select cast('abc' as varbinary(5))", match([]byte{'a', 'b', 'c'})},
-	{"select cast('' as varbinary(5))", match([]byte(""))},
-	{"select cast(NULL as varbinary(5))", match(nil)},
-	{"select cast('abc' as varbinary(max))", match([]byte{'a', 'b', 'c'})},
-	{"select cast('' as varbinary(max))", match([]byte(""))},
-	{fmt.Sprintf("select cast('%s' as varbinary(max))", veryLongString), match([]byte(veryLongString))},
-	{"select cast(NULL as varbinary(max))", match(nil)},
-}
-
-// TODO(brainman): see why typeMSSpecificTests do not work on freetds
-
-var typeMSSpecificTests = []typeTest{
-	{"select cast(N'\u0421\u0430\u0448\u0430' as nvarchar(5))", match([]byte("\u0421\u0430\u0448\u0430"))},
-	{"select cast(N'\u0421\u0430\u0448\u0430' as nvarchar(max))", match([]byte("\u0421\u0430\u0448\u0430"))},
-	{"select cast(N'\u0421\u0430\u0448\u0430' as ntext)", match([]byte("\u0421\u0430\u0448\u0430"))},
-	{"select cast(N'Саша' as nvarchar(max))", match([]byte("Саша"))},
-	{"select cast(N'你好世界' as nvarchar(21))", match([]byte("你好世界"))},
-	{"select cast(N'Γεια σου κόσμε' as nvarchar(21))", match([]byte("Γεια σου κόσμε"))},
-	{"select cast(N'Hello, 世界' as nvarchar(max))", match([]byte("Hello, 世界"))},
-}
-
-var typeMSSQL2008Tests = []typeTest{
-	// datetime2
-	{"select cast('20151225' as datetime2)", match(time.Date(2015, 12, 25, 0, 0, 0, 0, time.Local))},
-	{"select cast('2007-05-08 12:35:29.1234567' as datetime2)", match(time.Date(2007, 5, 8, 12, 35, 29, 1234567e2, time.Local))},
-	{"select cast(NULL as datetime2)", match(nil)},
-
-	// time(7)
-	{"select cast('12:35:29.1234567' as time(7))", match(time.Date(1, 1, 1, 12, 35, 29, 1234567e2, time.Local))},
-	{"select cast(NULL as time(7))", match(nil)},
-}
+func TestMSSQLSetIsolationLevel(t *testing.T) {
+	dc, err := drv.Open(newConnParams().makeODBCConnectionString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dc.Close()
+	c := dc.(*Conn)
 
-var typeTestsToFail = []string{
-	// int
-	"select cast(-1 as tinyint)",
-	"select cast(256 as tinyint)",
-	"select cast(-32769 as smallint)",
-	"select cast(32768 as smallint)",
-	"select cast(-9223372036854775809 as bigint)",
-	"select cast(9223372036854775808 as bigint)",
+	level := func() int {
+		st, err := c.Prepare("select transaction_isolation_level from sys.dm_exec_sessions where session_id = @@spid")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer st.Close()
+		rows, err := st.Query(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		dest := make([]driver.Value, 1)
+		if err := rows.Next(dest); err != nil {
+			t.Fatal(err)
+		}
+		return int(dest[0].(int16))
+	}
 
-	// decimal
-	"select cast(1234.5 as decimal(5, 2))",
+	before := level()
+	if err := c.SetIsolationLevel(sql.LevelSerializable); err != nil {
+		t.Fatal(err)
+	}
+	if got := level(); got != 4 {
+		t.Errorf("unexpected isolation level %d, want 4", got)
+	}
+	if err := c.ResetSession(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := level(); got != before {
+		t.Errorf("ResetSession did not restore isolation level: got %d, want %d", got, before)
+	}
 
-	// uniqueidentifier
-	"select cast('0x984725-c51c-4bf4-9960-e1c80e27aba0' as uniqueidentifier)",
+	tx, err := c.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if err := c.SetIsolationLevel(sql.LevelSerializable); err == nil {
+		t.Error("SetIsolationLevel inside transaction should fail, but succeeded")
+	}
 }
 
-func TestMSSQLTypes(t *testing.T) {
+func TestMSSQLResetSession(t *testing.T) {
 	db, sc, err := mssqlConnect()
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer closeDB(t, db, sc, sc)
 
-	tests := typeTests
-	if !isFreeTDS() {
-		tests = append(tests, typeMSSpecificTests...)
+	db.Exec("drop table dbo.temp")
+	exec(t, db, "create table dbo.temp (id int)")
+	defer exec(t, db, "drop table dbo.temp")
+
+	dc, err := drv.Open(newConnParams().makeODBCConnectionString())
+	if err != nil {
+		t.Fatal(err)
 	}
-	if is2008OrLater(db) {
-		tests = append(tests, typeMSSQL2008Tests...)
+	defer dc.Close()
+	c := dc.(*Conn)
+
+	// leave transaction open and connection read-only
+	if _, err := c.BeginTx(context.Background(), driver.TxOptions{}); err != nil {
+		t.Fatal(err)
 	}
-	for _, r := range tests {
-		func() {
-			rows, err := db.Query(r.query)
-			if err != nil {
-				t.Errorf("db.Query(%q) failed: %v", r.query, err)
-				return
-			}
-			defer rows.Close()
-			for rows.Next() {
-				var got interface{}
-				err := rows.Scan(&got)
-				if err != nil {
-					t.Errorf("rows.Scan for %q failed: %v", r.query, err)
-					return
-				}
-				err = r.match(got)
-				if err != nil {
-					t.Errorf("test %q failed: %v", r.query, err)
-				}
-			}
-			err = rows.Err()
-			if err != nil {
-				t.Error(err)
-				return
-			}
-		}()
+	if err := c.execQuery("insert into dbo.temp values (1)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ResetSession(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if c.tx != nil {
+		t.Error("ResetSession did not end transaction")
+	}
+	if ac, err := c.GetAttr(api.SQL_ATTR_AUTOCOMMIT); err != nil || ac != api.SQL_AUTOCOMMIT_ON {
+		t.Errorf("autocommit is not on after ResetSession: %v %v", ac, err)
+	}
+	var n int
+	if err := db.QueryRow("select count(*) from dbo.temp").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("ResetSession did not roll back transaction: %d rows found", n)
 	}
 
-	for _, query := range typeTestsToFail {
-		rows, err := db.Query(query)
-		if err != nil {
-			continue
-		}
-		rows.Close()
-		t.Errorf("test %q passed, but should fail", query)
+	if err := c.setAccessMode(true); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ResetSession(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if mode, err := c.GetAttr(api.SQL_ATTR_ACCESS_MODE); err == nil && mode != api.SQL_MODE_READ_WRITE {
+		t.Errorf("ResetSession did not restore access mode: %v", mode)
 	}
 }
 
-// TestMSSQLIntAfterText verify that non-bindable column can
-// precede bindable column.
-func TestMSSQLIntAfterText(t *testing.T) {
-	db, sc, err := mssqlConnect()
+func TestMSSQLSetCatalog(t *testing.T) {
+	dc, err := drv.Open(newConnParams().makeODBCConnectionString())
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer closeDB(t, db, sc, sc)
+	defer dc.Close()
+	c := dc.(*Conn)
 
-	const query = "select cast('abc' as text), cast(123 as int)"
-	rows, err := db.Query(query)
-	if err != nil {
-		t.Fatalf("db.Query(%q) failed: %v", query, err)
-	}
-	defer rows.Close()
-	for rows.Next() {
-		var i int
-		var text string
-		err = rows.Scan(&text, &i)
+	dbName := func() string {
+		st, err := c.Prepare("select db_name()")
 		if err != nil {
-			t.Fatalf("rows.Scan for %q failed: %v", query, err)
+			t.Fatal(err)
 		}
-		if text != "abc" {
-			t.Errorf("expected \"abc\", but received %v", text)
+		defer st.Close()
+		rows, err := st.Query(nil)
+		if err != nil {
+			t.Fatal(err)
 		}
-		if i != 123 {
-			t.Errorf("expected 123, but received %v", i)
+		defer rows.Close()
+		dest := make([]driver.Value, 1)
+		if err := rows.Next(dest); err != nil {
+			t.Fatal(err)
 		}
+		return fmt.Sprintf("%s", dest[0])
 	}
-	err = rows.Err()
+
+	before, err := c.Catalog()
 	if err != nil {
 		t.Fatal(err)
 	}
+	if got := dbName(); got != before {
+		t.Errorf("Catalog returns %q, but current database is %q", before, got)
+	}
+	if err := c.SetCatalog("master"); err != nil {
+		t.Fatal(err)
+	}
+	if got := dbName(); got != "master" {
+		t.Errorf("unexpected database %q, want master", got)
+	}
+	if err := c.ResetSession(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := dbName(); got != before {
+		t.Errorf("ResetSession did not restore database: got %q, want %q", got, before)
+	}
 }
 
-func TestMSSQLStmtAndRows(t *testing.T) {
+func TestMSSQLCommitAll(t *testing.T) {
 	db, sc, err := mssqlConnect()
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer func() {
-		// not checking resources usage here, because these are
-		// unpredictable due to use of goroutines.
-		err := db.Close()
-		if err != nil {
-			t.Fatalf("error closing DB: %v", err)
-		}
-	}()
-
-	var staff = map[string][]string{
-		"acc": {"John", "Mary", "Moe"},
-		"eng": {"Bar", "Foo", "Uno"},
-		"sls": {"Scrudge", "Sls2", "Sls3"},
-	}
+	defer closeDB(t, db, sc, sc)
 
 	db.Exec("drop table dbo.temp")
-	exec(t, db, "create table dbo.temp (dept char(3), name varchar(20))")
+	exec(t, db, "create table dbo.temp (id int)")
+	defer exec(t, db, "drop table dbo.temp")
 
-	func() {
-		// test 1 Stmt and many Exec's
-		s, err := db.Prepare("insert into dbo.temp (dept, name) values (?, ?)")
-		if err != nil {
-			t.Fatal(err)
-		}
-		defer s.Close()
-		for dept, people := range staff {
-			for _, person := range people {
-				_, err := s.Exec(dept, person)
-				if err != nil {
-					t.Fatal(err)
-				}
-			}
+	var conns []driver.Conn
+	defer func() {
+		for _, c := range conns {
+			c.Close()
 		}
 	}()
-
-	func() {
-		// test Stmt is closed before Rows are
-		s, err := db.Prepare("select name from dbo.temp")
+	begin := func(id int) *Tx {
+		dc, err := drv.Open(newConnParams().makeODBCConnectionString())
 		if err != nil {
 			t.Fatal(err)
 		}
-
-		r, err := s.Query()
+		conns = append(conns, dc)
+		c := dc.(*Conn)
+		tx, err := c.Begin()
 		if err != nil {
-			s.Close()
 			t.Fatal(err)
 		}
-		defer r.Close()
-
-		// TODO(brainman): dangling statement(s) bug reported
-		// https://github.com/golang/go/issues/3865
-		err = s.Close()
-		if err != nil {
+		if err := c.execQuery(fmt.Sprintf("insert into dbo.temp values (%d)", id)); err != nil {
 			t.Fatal(err)
 		}
-
-		n := 0
-		for r.Next() {
-			var name string
-			err = r.Scan(&name)
-			if err != nil {
-				t.Fatal(err)
-			}
-			n++
-		}
-		err = r.Err()
+		return tx.(*Tx)
+	}
+	ids := func() string {
+		var ids []int
+		rows, err := db.Query("select id from dbo.temp order by id")
 		if err != nil {
 			t.Fatal(err)
 		}
-		const should = 9
-		if n != should {
-			t.Fatalf("expected %v, but received %v", should, n)
+		defer rows.Close()
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, id)
 		}
-	}()
+		return fmt.Sprint(ids)
+	}
 
-	if db.Driver().(*Driver).Stats.StmtCount != sc {
-		t.Fatalf("invalid statement count: expected %v, is %v", sc, db.Driver().(*Driver).Stats.StmtCount)
+	if err := CommitAll(begin(1), begin(2)); err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(); got != "[1 2]" {
+		t.Errorf("expect [1 2] rows after CommitAll, but got %v", got)
 	}
 
-	// no resource tracking past this point
+	if err := RollbackAll(begin(3), begin(4)); err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(); got != "[1 2]" {
+		t.Errorf("expect [1 2] rows after RollbackAll, but got %v", got)
+	}
 
-	func() {
-		// test 1 Stmt and many Query's executed one after the other
-		s, err := db.Prepare("select name from dbo.temp where dept = ? order by name")
-		if err != nil {
-			t.Fatal(err)
+	// commit of ended transaction fails, so the rest is rolled back
+	tx5, tx6, tx7 := begin(5), begin(6), begin(7)
+	if err := tx6.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	err = CommitAll(tx5, tx6, tx7)
+	e, ok := err.(*TxEndError)
+	if !ok {
+		t.Fatalf("expect *TxEndError, but got %T: %v", err, err)
+	}
+	if e.Committed != 1 || e.Errs[0] != nil || e.Errs[1] == nil || e.Errs[2] != ErrTxRolledBack {
+		t.Errorf("unexpected CommitAll error: %v (%v)", e, e.Errs)
+	}
+	if got := ids(); got != "[1 2 5 6]" {
+		t.Errorf("expect [1 2 5 6] rows, but got %v", got)
+	}
+}
+
+func TestMSSQLLeakReport(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	drv.SetLeakTracking(true)
+	defer drv.SetLeakTracking(false)
+
+	leakedStmts := func() (r []string) {
+		for _, s := range drv.LeakReport() {
+			if strings.HasPrefix(s, "statement handle") {
+				r = append(r, s)
+			}
+		}
+		return r
+	}
+
+	st, err := db.Prepare("select 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := leakedStmts()
+	if len(r) != 1 {
+		t.Fatalf("expected 1 open statement, but got %d: %v", len(r), r)
+	}
+	if !strings.Contains(r[0], "TestMSSQLLeakReport") {
+		t.Errorf("allocation site is missing from report: %v", r[0])
+	}
+	if err := st.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if r := leakedStmts(); len(r) != 0 {
+		t.Fatalf("unexpected open statements: %v", r)
+	}
+}
+
+func TestMSSQLExpandSliceArgs(t *testing.T) {
+	tests := []struct {
+		query     string
+		args      []driver.Value
+		wantQuery string
+		wantArgs  []driver.Value
+	}{
+		{"select ?", []driver.Value{int64(1)}, "select ?", []driver.Value{int64(1)}},
+		{"select ?", []driver.Value{[]byte("a")}, "select ?", []driver.Value{[]byte("a")}},
+		{"select * from t where a in (?) and b = ?", []driver.Value{[]int{1, 2}, "b"},
+			"select * from t where a in (?, ?) and b = ?", []driver.Value{int64(1), int64(2), "b"}},
+		{"select '?', [a?], \"b?\" /* ? */ from t -- ?\nwhere a in (?)", []driver.Value{[]string{"x", "y"}},
+			"select '?', [a?], \"b?\" /* ? */ from t -- ?\nwhere a in (?, ?)", []driver.Value{"x", "y"}},
+		{"select 'it''s ?' where a in (?)", []driver.Value{[]int64{1}},
+			"select 'it''s ?' where a in (?)", []driver.Value{int64(1)}},
+	}
+	check := func(query string, args []driver.Value, brackets bool, wantQuery string, wantArgs []driver.Value) {
+		q, args, err := expandSliceArgs(query, args, brackets)
+		if err != nil {
+			t.Errorf("%q: %v", query, err)
+			return
+		}
+		if q != wantQuery {
+			t.Errorf("%q: unexpected query %q, want %q", query, q, wantQuery)
+		}
+		if fmt.Sprint(args) != fmt.Sprint(wantArgs) {
+			t.Errorf("%q: unexpected args %v, want %v", query, args, wantArgs)
+		}
+	}
+	for _, test := range tests {
+		check(test.query, test.args, true, test.wantQuery, test.wantArgs)
+	}
+	// Brackets are array subscripts and constructors outside
+	// of SQL Server and Access, like in PostgreSQL.
+	check("select * from t where a = any(ARRAY[?])", []driver.Value{[]int{1, 2}}, false,
+		"select * from t where a = any(ARRAY[?, ?])", []driver.Value{int64(1), int64(2)})
+	check("select a[?] from t where b in (?)", []driver.Value{int64(1), []int{2, 3}}, false,
+		"select a[?] from t where b in (?, ?)", []driver.Value{int64(1), int64(2), int64(3)})
+	for _, args := range [][]driver.Value{
+		{[]int{}},
+		{[]int{1}, int64(2)},
+	} {
+		if _, _, err := expandSliceArgs("select ?", args, true); err == nil {
+			t.Errorf("%v: unexpected success, expected error", args)
+		}
+	}
+}
+
+func TestMSSQLExpandNamedArgs(t *testing.T) {
+	named := func(nvs ...interface{}) []driver.NamedValue {
+		var args []driver.NamedValue
+		for i := 0; i < len(nvs); i += 2 {
+			args = append(args, driver.NamedValue{Name: nvs[i].(string), Ordinal: i/2 + 1, Value: nvs[i+1]})
+		}
+		return args
+	}
+	tests := []struct {
+		query     string
+		args      []driver.NamedValue
+		wantQuery string
+		wantArgs  []driver.Value
+	}{
+		{"select ?", []driver.NamedValue{{Ordinal: 1, Value: int64(1)}}, "select ?", []driver.Value{int64(1)}},
+		{"select @a, @b, @a", named("a", int64(1), "b", "x"), "select ?, ?, ?", []driver.Value{int64(1), "x", int64(1)}},
+		{"declare @x int; select @x = @v; if @@rowcount = 0 select @v", named("v", int64(2)),
+			"declare @x int; select @x = ?; if @@rowcount = 0 select ?", []driver.Value{int64(2), int64(2)}},
+		{"select '@v', [@v], \"@v\" /* @v */ -- @v\n, @v, @vv", named("v", int64(3)),
+			"select '@v', [@v], \"@v\" /* @v */ -- @v\n, ?, @vv", []driver.Value{int64(3)}},
+	}
+	for _, test := range tests {
+		q, args, err := expandNamedArgs(test.query, test.args, true)
+		if err != nil {
+			t.Errorf("%q: %v", test.query, err)
+			continue
+		}
+		if q != test.wantQuery {
+			t.Errorf("%q: unexpected query %q, want %q", test.query, q, test.wantQuery)
+		}
+		if fmt.Sprint(args) != fmt.Sprint(test.wantArgs) {
+			t.Errorf("%q: unexpected args %v, want %v", test.query, args, test.wantArgs)
+		}
+	}
+	for _, test := range []struct {
+		query string
+		args  []driver.NamedValue
+	}{
+		{"select @a, ?", named("a", int64(1))},
+		{"select @a", append(named("a", int64(1)), driver.NamedValue{Ordinal: 2, Value: int64(2)})},
+		{"select @a", named("a", int64(1), "b", int64(2))},
+	} {
+		if _, _, err := expandNamedArgs(test.query, test.args, true); err == nil {
+			t.Errorf("%q: unexpected success, expected error", test.query)
+		}
+	}
+}
+
+func TestMSSQLNamedParams(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	db.Exec("drop table dbo.temp")
+	exec(t, db, "create table dbo.temp (id int, a varchar(255))")
+	defer exec(t, db, "drop table dbo.temp")
+
+	// the same statement as TestMSSQLIssue127, but with named arguments
+	query := `
+DECLARE @id INT, @a VARCHAR(255)
+SELECT @id = @newid, @a = @newa
+UPDATE dbo.temp SET a = @a WHERE id = @id
+IF @@ROWCOUNT = 0
+  INSERT INTO dbo.temp (id, a) VALUES (@newid, @newa)
+`
+	for _, a := range []string{"test", "test2"} {
+		if _, err := db.Exec(query, sql.Named("newid", 1), sql.Named("newa", a)); err != nil {
+			t.Fatalf("Failed to upsert record %q: %s", a, err)
+		}
+	}
+	var n int
+	var a string
+	err = db.QueryRow("select count(*), max(a) from dbo.temp where id = @id and a like @a + '%'",
+		sql.Named("id", 1), sql.Named("a", "test")).Scan(&n, &a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || a != "test2" {
+		t.Fatalf("expected 1 record with \"test2\", but got %d records with %q", n, a)
+	}
+}
+
+func TestMSSQLSliceParams(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	db.Exec("drop table dbo.temp")
+	exec(t, db, "create table dbo.temp (id int, name varchar(20))")
+	for i := 1; i <= 5; i++ {
+		_, err := db.Exec("insert into dbo.temp (id, name) values (?, ?)", i, fmt.Sprintf("n%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var n int
+	err = db.QueryRow("select count(*) from dbo.temp where id in (?) and name <> ?", []int{1, 2, 3}, "n2").Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("unexpected count %d, want 2", n)
+	}
+
+	st, err := db.Prepare("delete from dbo.temp where name in (?)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	r, err := st.Exec([]string{"n4", "n5", "n6"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cnt, err := r.RowsAffected(); err != nil || cnt != 2 {
+		t.Errorf("unexpected RowsAffected %d (%v), want 2", cnt, err)
+	}
+
+	if _, err := db.Exec("delete from dbo.temp where id in (?)", []int{}); err == nil {
+		t.Error("empty slice must fail")
+	}
+
+	exec(t, db, "drop table dbo.temp")
+}
+
+type matchFunc func(v interface{}) error
+
+func match(a interface{}) matchFunc {
+	return func(b interface{}) error {
+		switch got := b.(type) {
+		case nil:
+			switch expect := a.(type) {
+			case nil:
+				// matching
+			default:
+				return fmt.Errorf("expect %v, but got %v", expect, got)
+			}
+		case bool:
+			expect, ok := a.(bool)
+			if !ok {
+				return fmt.Errorf("couldn't convert expected value %v(%T) to %T", a, a, got)
+			}
+			if got != expect {
+				return fmt.Errorf("expect %v, but got %v", expect, got)
+			}
+		case int32:
+			expect, ok := a.(int32)
+			if !ok {
+				return fmt.Errorf("couldn't convert expected value %v(%T) to %T", a, a, got)
+			}
+			if got != expect {
+				return fmt.Errorf("expect %v, but got %v", expect, got)
+			}
+		case int64:
+			expect, ok := a.(int64)
+			if !ok {
+				return fmt.Errorf("couldn't convert expected value %v(%T) to %T", a, a, got)
+			}
+			if got != expect {
+				return fmt.Errorf("expect %v, but got %v", expect, got)
+			}
+		case float64:
+			switch expect := a.(type) {
+			case float64:
+				if got != expect {
+					return fmt.Errorf("expect %v, but got %v", expect, got)
+				}
+			case int64:
+				if got != float64(expect) {
+					return fmt.Errorf("expect %v, but got %v", expect, got)
+				}
+			default:
+				return fmt.Errorf("unsupported type %T", expect)
+			}
+		case string:
+			expect, ok := a.(string)
+			if !ok {
+				return fmt.Errorf("couldn't convert expected value %v(%T) to %T", a, a, got)
+			}
+			if got != expect {
+				return fmt.Errorf("expect %q, but got %q", expect, got)
+			}
+		case []byte:
+			expect, ok := a.([]byte)
+			if !ok {
+				return fmt.Errorf("couldn't convert expected value %v(%T) to %T", a, a, got)
+			}
+			if !bytes.Equal(got, expect) {
+				return fmt.Errorf("expect %v, but got %v", expect, got)
+			}
+		case time.Time:
+			expect, ok := a.(time.Time)
+			if !ok {
+				return fmt.Errorf("couldn't convert expected value %v(%T) to %T", a, a, got)
+			}
+			if !got.Equal(expect) {
+				return fmt.Errorf("expect %q, but got %q", expect, got)
+			}
+		default:
+			return fmt.Errorf("unsupported type %T", got)
+		}
+		return nil
+	}
+}
+
+type typeTest struct {
+	query string
+	match matchFunc
+}
+
+var veryLongString = strings.Repeat("abcd ", 206)
+
+var typeTests = []typeTest{
+	// bool
+	{"select cast(1 as bit)", match(true)},
+	{"select cast(2 as bit)", match(true)},
+	{"select cast(0 as bit)", match(false)},
+	{"select cast(NULL as bit)", match(nil)},
+
+	// int
+	{"select cast(0 as int)", match(int32(0))},
+	{"select cast(123 as int)", match(int32(123))},
+	{"select cast(-4 as int)", match(int32(-4))},
+	{"select cast(NULL as int)", match(nil)},
+	{"select cast(0 as tinyint)", match(int32(0))},
+	{"select cast(127 as tinyint)", match(int32(127))},
+	{"select cast(128 as tinyint)", match(int32(128))},
+	{"select cast(255 as tinyint)", match(int32(255))},
+	{"select cast(-32768 as smallint)", match(int32(-32768))},
+	{"select cast(32767 as smallint)", match(int32(32767))},
+	{"select cast(-9223372036854775808 as bigint)", match(int64(-9223372036854775808))},
+	{"select cast(9223372036854775807 as bigint)", match(int64(9223372036854775807))},
+
+	// decimal, float, real
+	{"select cast(123 as decimal(5, 0))", match(float64(123))},
+	{"select cast(-123 as decimal(5, 0))", match(float64(-123))},
+	{"select cast(123.5 as decimal(5, 0))", match(float64(124))},
+	{"select cast(NULL as decimal(5, 0))", match(nil)},
+	{"select cast(123.45 as decimal(5, 2))", match(123.45)},
+	{"select cast(-123.45 as decimal(5, 2))", match(-123.45)},
+	{"select cast(123.456 as decimal(5, 2))", match(123.46)},
+	{"select cast(0.123456789 as float)", match(0.123456789)},
+	{"select cast(NULL as float)", match(nil)},
+	{"select cast(3.6666667461395264 as real)", match(3.6666667461395264)},
+	{"select cast(NULL as real)", match(nil)},
+	{"select cast(1.2333333504e+10 as real)", match(1.2333333504e+10)},
+
+	// money
+	{"select cast(12 as money)", match(float64(12))},
+	{"select cast(-12 as money)", match(float64(-12))},
+	{"select cast(0.01 as money)", match(0.01)},
+	{"select cast(0.0123 as money)", match(0.0123)},
+	{"select cast(NULL as money)", match(nil)},
+	{"select cast(1 as smallmoney)", match(float64(1))},
+	{"select cast(0.0123 as smallmoney)", match(0.0123)},
+	{"select cast(NULL as smallmoney)", match(nil)},
+
+	// strings
+	{"select cast(123 as varchar(21))", match([]byte("123"))},
+	{"select cast(123 as char(5))", match([]byte("123  "))},
+	{"select cast('abcde' as varchar(3))", match([]byte("abc"))},
+	{"select cast('' as varchar(5))", match([]byte(""))},
+	{"select cast(NULL as varchar(5))", match(nil)},
+	{"select cast(123 as nvarchar(21))", match([]byte("123"))},
+	{"select cast('abcde' as nvarchar(3))", match([]byte("abc"))},
+	{"select cast('' as nvarchar(5))", match([]byte(""))},
+	{"select cast(NULL as nvarchar(5))", match(nil)},
+
+	// datetime, smalldatetime
+	{"select cast('20151225' as datetime)", match(time.Date(2015, 12, 25, 0, 0, 0, 0, time.Local))},
+	{"select cast('2007-05-08 12:35:29.123' as datetime)", match(time.Date(2007, 5, 8, 12, 35, 29, 123e6, time.Local))},
+	{"select cast(NULL as datetime)", match(nil)},
+	{"select cast('2007-05-08 12:35:29.123' as smalldatetime)", match(time.Date(2007, 5, 8, 12, 35, 0, 0, time.Local))},
+
+	// uniqueidentifier
+	{"select cast('0e984725-c51c-4bf4-9960-e1c80e27aba0' as uniqueidentifier)", match("0e984725-c51c-4bf4-9960-e1c80e27aba0")},
+	{"select cast(NULL as uniqueidentifier)", match(nil)},
+
+	// string blobs
+	{"select cast('abc' as varchar(max))", match([]byte("abc"))},
+	{"select cast('' as varchar(max))", match([]byte(""))},
+	{fmt.Sprintf("select cast('%s' as varchar(max))", veryLongString), match([]byte(veryLongString))},
+	{"select cast(NULL as varchar(max))", match(nil)},
+	{"select cast('abc' as nvarchar(max))", match([]byte("abc"))},
+	{"select cast('' as nvarchar(max))", match([]byte(""))},
+	{fmt.Sprintf("select cast('%s' as nvarchar(max))", veryLongString), match([]byte(veryLongString))},
+	{"select cast(NULL as nvarchar(max))", match(nil)},
+	{"select cast('abc' as text)", match([]byte("abc"))},
+	{"select cast('' as text)", match([]byte(""))},
+	{fmt.Sprintf("select cast('%s' as text)", veryLongString), match([]byte(veryLongString))},
+	{"select cast(NULL as text)", match(nil)},
+	{"select cast('abc' as ntext)", match([]byte("abc"))},
+	{"select cast('' as ntext)", match([]byte(""))},
+	{fmt.Sprintf("select cast('%s' as ntext)", veryLongString), match([]byte(veryLongString))},
+	{"select cast(NULL as ntext)", match(nil)},
+
+	// xml
+	{"select cast(N'<root>hello</root>' as xml)", match([]byte("<root>hello</root>"))},
+	{"select cast(N'<root><doc><item1>dd</item1></doc></root>' as xml)", match([]byte("<root><doc><item1>dd</item1></doc></root>"))},
+
+	// binary blobs
+	{"select cast('abc' as binary(5))", match([]byte{'a', 'b', 'c', 0, 0})},
+	{"select cast('' as binary(5))", match([]byte{0, 0, 0, 0, 0})},
+	{"select cast(NULL as binary(5))", match(nil)},
+	{"select cast('abc' as varbinary(5))", match([]byte{'a', 'b', 'c'})},
+	{"select cast('' as varbinary(5))", match([]byte(""))},
+	{"select cast(NULL as varbinary(5))", match(nil)},
+	{"select cast('abc' as varbinary(max))", match([]byte{'a', 'b', 'c'})},
+	{"select cast('' as varbinary(max))", match([]byte(""))},
+	{fmt.Sprintf("select cast('%s' as varbinary(max))", veryLongString), match([]byte(veryLongString))},
+	{"select cast(NULL as varbinary(max))", match(nil)},
+}
+
+// TODO(brainman): see why typeMSSpecificTests do not work on freetds
+
+var typeMSSpecificTests = []typeTest{
+	{"select cast(N'\u0421\u0430\u0448\u0430' as nvarchar(5))", match([]byte("\u0421\u0430\u0448\u0430"))},
+	{"select cast(N'\u0421\u0430\u0448\u0430' as nvarchar(max))", match([]byte("\u0421\u0430\u0448\u0430"))},
+	{"select cast(N'\u0421\u0430\u0448\u0430' as ntext)", match([]byte("\u0421\u0430\u0448\u0430"))},
+	{"select cast(N'Саша' as nvarchar(max))", match([]byte("Саша"))},
+	{"select cast(N'你好世界' as nvarchar(21))", match([]byte("你好世界"))},
+	{"select cast(N'Γεια σου κόσμε' as nvarchar(21))", match([]byte("Γεια σου κόσμε"))},
+	{"select cast(N'Hello, 世界' as nvarchar(max))", match([]byte("Hello, 世界"))},
+}
+
+var typeMSSQL2008Tests = []typeTest{
+	// datetime2
+	{"select cast('20151225' as datetime2)", match(time.Date(2015, 12, 25, 0, 0, 0, 0, time.Local))},
+	{"select cast('2007-05-08 12:35:29.1234567' as datetime2)", match(time.Date(2007, 5, 8, 12, 35, 29, 1234567e2, time.Local))},
+	{"select cast(NULL as datetime2)", match(nil)},
+
+	// time(7)
+	{"select cast('12:35:29.1234567' as time(7))", match(time.Date(1, 1, 1, 12, 35, 29, 1234567e2, time.Local))},
+	{"select cast(NULL as time(7))", match(nil)},
+}
+
+var typeTestsToFail = []string{
+	// int
+	"select cast(-1 as tinyint)",
+	"select cast(256 as tinyint)",
+	"select cast(-32769 as smallint)",
+	"select cast(32768 as smallint)",
+	"select cast(-9223372036854775809 as bigint)",
+	"select cast(9223372036854775808 as bigint)",
+
+	// decimal
+	"select cast(1234.5 as decimal(5, 2))",
+
+	// uniqueidentifier
+	"select cast('0x984725-c51c-4bf4-9960-e1c80e27aba0' as uniqueidentifier)",
+}
+
+func TestMSSQLTypes(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	tests := typeTests
+	if !isFreeTDS() {
+		tests = append(tests, typeMSSpecificTests...)
+	}
+	if is2008OrLater(db) {
+		tests = append(tests, typeMSSQL2008Tests...)
+	}
+	for _, r := range tests {
+		func() {
+			rows, err := db.Query(r.query)
+			if err != nil {
+				t.Errorf("db.Query(%q) failed: %v", r.query, err)
+				return
+			}
+			defer rows.Close()
+			for rows.Next() {
+				var got interface{}
+				err := rows.Scan(&got)
+				if err != nil {
+					t.Errorf("rows.Scan for %q failed: %v", r.query, err)
+					return
+				}
+				err = r.match(got)
+				if err != nil {
+					t.Errorf("test %q failed: %v", r.query, err)
+				}
+			}
+			err = rows.Err()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+		}()
+	}
+
+	for _, query := range typeTestsToFail {
+		rows, err := db.Query(query)
+		if err != nil {
+			continue
+		}
+		rows.Close()
+		t.Errorf("test %q passed, but should fail", query)
+	}
+}
+
+func TestMSSQLDatabaseTypeNames(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	// cast type in typeTests query -> expected database type name
+	names := map[string]string{
+		"bit":              "BIT",
+		"tinyint":          "TINYINT",
+		"smallint":         "SMALLINT",
+		"int":              "INT",
+		"bigint":           "BIGINT",
+		"decimal":          "DECIMAL",
+		"money":            "DECIMAL",
+		"smallmoney":       "DECIMAL",
+		"float":            "FLOAT",
+		"real":             "REAL",
+		"char":             "CHAR",
+		"varchar":          "VARCHAR",
+		"text":             "TEXT",
+		"nvarchar":         "NVARCHAR",
+		"ntext":            "NTEXT",
+		"xml":              "XML",
+		"binary":           "BINARY",
+		"varbinary":        "VARBINARY",
+		"datetime":         "DATETIME",
+		"smalldatetime":    "DATETIME",
+		"datetime2":        "DATETIME",
+		"time":             "TIME",
+		"uniqueidentifier": "UNIQUEIDENTIFIER",
+	}
+	tests := typeTests
+	if is2008OrLater(db) {
+		tests = append(tests, typeMSSQL2008Tests...)
+	}
+	for _, r := range tests {
+		castType := r.query[strings.LastIndex(r.query, " as ")+4:]
+		if i := strings.IndexAny(castType, "()"); i >= 0 {
+			castType = castType[:i]
+		}
+		want, ok := names[castType]
+		if !ok {
+			t.Errorf("no database type name for %q in %q", castType, r.query)
+			continue
+		}
+		rows, err := db.Query(r.query)
+		if err != nil {
+			t.Errorf("db.Query(%q) failed: %v", r.query, err)
+			continue
+		}
+		cts, err := rows.ColumnTypes()
+		rows.Close()
+		if err != nil {
+			t.Errorf("rows.ColumnTypes for %q failed: %v", r.query, err)
+			continue
+		}
+		if got := cts[0].DatabaseTypeName(); got != want {
+			t.Errorf("%q: expect database type name %q, but got %q", r.query, want, got)
+		}
+	}
+}
+
+// TestMSSQLIntAfterText verify that non-bindable column can
+// precede bindable column.
+func TestMSSQLIntAfterText(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	const query = "select cast('abc' as text), cast(123 as int)"
+	rows, err := db.Query(query)
+	if err != nil {
+		t.Fatalf("db.Query(%q) failed: %v", query, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var i int
+		var text string
+		err = rows.Scan(&text, &i)
+		if err != nil {
+			t.Fatalf("rows.Scan for %q failed: %v", query, err)
+		}
+		if text != "abc" {
+			t.Errorf("expected \"abc\", but received %v", text)
+		}
+		if i != 123 {
+			t.Errorf("expected 123, but received %v", i)
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMSSQLStmtAndRows(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		// not checking resources usage here, because these are
+		// unpredictable due to use of goroutines.
+		err := db.Close()
+		if err != nil {
+			t.Fatalf("error closing DB: %v", err)
+		}
+	}()
+
+	var staff = map[string][]string{
+		"acc": {"John", "Mary", "Moe"},
+		"eng": {"Bar", "Foo", "Uno"},
+		"sls": {"Scrudge", "Sls2", "Sls3"},
+	}
+
+	db.Exec("drop table dbo.temp")
+	exec(t, db, "create table dbo.temp (dept char(3), name varchar(20))")
+
+	func() {
+		// test 1 Stmt and many Exec's
+		s, err := db.Prepare("insert into dbo.temp (dept, name) values (?, ?)")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		for dept, people := range staff {
+			for _, person := range people {
+				_, err := s.Exec(dept, person)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}()
+
+	func() {
+		// test Stmt is closed before Rows are
+		s, err := db.Prepare("select name from dbo.temp")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := s.Query()
+		if err != nil {
+			s.Close()
+			t.Fatal(err)
+		}
+		defer r.Close()
+
+		// TODO(brainman): dangling statement(s) bug reported
+		// https://github.com/golang/go/issues/3865
+		err = s.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		n := 0
+		for r.Next() {
+			var name string
+			err = r.Scan(&name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			n++
+		}
+		err = r.Err()
+		if err != nil {
+			t.Fatal(err)
+		}
+		const should = 9
+		if n != should {
+			t.Fatalf("expected %v, but received %v", should, n)
+		}
+	}()
+
+	if db.Driver().(*Driver).Stats.StmtCount != sc {
+		t.Fatalf("invalid statement count: expected %v, is %v", sc, db.Driver().(*Driver).Stats.StmtCount)
+	}
+
+	// no resource tracking past this point
+
+	func() {
+		// test 1 Stmt and many Query's executed one after the other
+		s, err := db.Prepare("select name from dbo.temp where dept = ? order by name")
+		if err != nil {
+			t.Fatal(err)
 		}
 		defer s.Close()
 		for dept, people := range staff {
@@ -891,1090 +1603,4753 @@ func TestMSSQLStmtAndRows(t *testing.T) {
 				if err != nil {
 					t.Fatal(err)
 				}
-				defer r.Close()
-				i := 0
-				for r.Next() {
-					var is string
-					err = r.Scan(&is)
-					if err != nil {
-						t.Fatal(err)
-					}
-					if people[i] != is {
-						t.Fatalf("expected %v, but received %v", people[i], is)
-					}
-					i++
-				}
-				err = r.Err()
-				if err != nil {
+				defer r.Close()
+				i := 0
+				for r.Next() {
+					var is string
+					err = r.Scan(&is)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if people[i] != is {
+						t.Fatalf("expected %v, but received %v", people[i], is)
+					}
+					i++
+				}
+				err = r.Err()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+		}
+		// test 1 Stmt and many simultaneous Query's
+		eof := fmt.Errorf("eof")
+		ch := make(map[string]chan error)
+		for dept, people := range staff {
+			c := make(chan error)
+			go func(c chan error, dept string, people []string) {
+				c <- nil
+				// NOTE(brainman): this could actually re-prepare since
+				// we are running it simultaneously in multiple goroutines
+				r, err := s.Query(dept)
+				if err != nil {
+					c <- fmt.Errorf("%v", err)
+					return
+				}
+				defer r.Close()
+				i := 0
+				c <- nil
+				for r.Next() {
+					var is string
+					c <- nil
+					err = r.Scan(&is)
+					if err != nil {
+						c <- fmt.Errorf("%v", err)
+						return
+					}
+					c <- nil
+					if people[i] != is {
+						c <- fmt.Errorf("expected %v, but received %v", people[i], is)
+						return
+					}
+					i++
+				}
+				err = r.Err()
+				if err != nil {
+					c <- fmt.Errorf("%v", err)
+					return
+				}
+				c <- eof
+			}(c, dept, people)
+			ch[dept] = c
+		}
+		for len(ch) > 0 {
+			for dept, c := range ch {
+				err := <-c
+				if err != nil {
+					if err != eof {
+						t.Errorf("dept=%v: %v", dept, err)
+					}
+					delete(ch, dept)
+				}
+			}
+		}
+	}()
+
+	exec(t, db, "drop table dbo.temp")
+}
+
+func TestMSSQLIssue5(t *testing.T) {
+	// connections opened by this test execute statements slower
+	drv.hooks.beforeExecute = func() { time.Sleep(10 * time.Microsecond) }
+	defer func() {
+		drv.hooks.beforeExecute = nil
+	}()
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const nworkers = 8
+	defer closeDB(t, db, sc, sc)
+
+	db.Exec("drop table dbo.temp")
+	exec(t, db, `
+		create table dbo.temp (
+			id int,
+			value int,
+			constraint [pk_id] primary key ([id])
+		)
+	`)
+
+	var count int32
+
+	runCycle := func(waitch <-chan struct{}, errch chan<- error) (reterr error) {
+		defer func() {
+			errch <- reterr
+		}()
+		stmt, err := db.Prepare("insert into dbo.temp (id, value) values (?, ?)")
+		if err != nil {
+			return fmt.Errorf("Prepare failed: %v", err)
+		}
+		defer stmt.Close()
+		errch <- nil
+		<-waitch
+		for {
+			i := (int)(atomic.AddInt32(&count, 1))
+			_, err := stmt.Exec(i, i)
+			if err != nil {
+				return fmt.Errorf("Exec failed i=%d: %v", i, err)
+			}
+			runtime.GC()
+			if i >= 100 {
+				break
+			}
+		}
+		return
+	}
+
+	waitch := make(chan struct{})
+	errch := make(chan error, nworkers)
+	for i := 0; i < nworkers; i++ {
+		go runCycle(waitch, errch)
+	}
+	for i := 0; i < nworkers; i++ {
+		if err := <-errch; err != nil {
+			t.Error(err)
+		}
+	}
+	if t.Failed() {
+		return
+	}
+	close(waitch)
+	for i := 0; i < nworkers; i++ {
+		if err := <-errch; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// TODO: maybe I should verify dbo.temp records here
+
+	exec(t, db, "drop table dbo.temp")
+}
+
+func TestMSSQLDeleteNonExistent(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	db.Exec("drop table dbo.temp")
+	exec(t, db, "create table dbo.temp (name varchar(20))")
+	_, err = db.Exec("insert into dbo.temp (name) values ('alex')")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := db.Exec("delete from dbo.temp where name = 'bob'")
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	cnt, err := r.RowsAffected()
+	if err != nil {
+		t.Fatalf("RowsAffected failed: %v", err)
+	}
+	if cnt != 0 {
+		t.Fatalf("RowsAffected returns %d, but 0 expected", cnt)
+	}
+
+	exec(t, db, "drop table dbo.temp")
+}
+
+func TestMSSQLQueryNoResultSet(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	db.Exec("drop table dbo.temp")
+	exec(t, db, "create table dbo.temp (name varchar(20))")
+
+	rows, err := db.Query("insert into dbo.temp (name) values ('alex')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows.Next() {
+		t.Error("statement without result set should return no rows")
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := db.QueryRow("select count(*) from dbo.temp").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("insert executed by Query should add 1 row, but table has %d rows", n)
+	}
+
+	exec(t, db, "drop table dbo.temp")
+}
+
+// https://github.com/alexbrainman/odbc/issues/14
+func TestMSSQLDatetime2Param(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	if !is2008OrLater(db) {
+		t.Skip("skipping test; needs MS SQL Server 2008 or later")
+	}
+
+	db.Exec("drop table dbo.temp")
+	exec(t, db, "create table dbo.temp (dt datetime2)")
+
+	expect := time.Date(2007, 5, 8, 12, 35, 29, 1234567e2, time.Local)
+	_, err = db.Exec("insert into dbo.temp (dt) values (?)", expect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got time.Time
+	err = db.QueryRow("select top 1 dt from dbo.temp").Scan(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect != got {
+		t.Fatalf("expect %v, but got %v", expect, got)
+	}
+
+	exec(t, db, "drop table dbo.temp")
+}
+
+func TestMSSQLDateTimeScan(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"cast('2021-03-04' as date)", time.Date(2021, 3, 4, 0, 0, 0, 0, time.Local)},
+		{"cast('12:34:56.1234567' as time(7))", time.Date(1, 1, 1, 12, 34, 56, 123456700, time.Local)},
+		{"cast('12:34:56' as time(0))", time.Date(1, 1, 1, 12, 34, 56, 0, time.Local)},
+		{"cast('2021-03-04 12:34:56.123' as datetime)", time.Date(2021, 3, 4, 12, 34, 56, 123000000, time.Local)},
+		{"cast('2021-03-04 12:34:56.1234567' as datetime2(7))", time.Date(2021, 3, 4, 12, 34, 56, 123456700, time.Local)},
+		{"cast('2021-03-04 12:34' as smalldatetime)", time.Date(2021, 3, 4, 12, 34, 0, 0, time.Local)},
+	}
+	for _, test := range tests {
+		var got time.Time
+		if err := db.QueryRow("select " + test.expr).Scan(&got); err != nil {
+			t.Errorf("%s: %v", test.expr, err)
+			continue
+		}
+		if !got.Equal(test.want) || got.Location() != time.Local {
+			t.Errorf("%s: expect %v, but got %v", test.expr, test.want, got)
+		}
+	}
+}
+
+func TestMSSQLTime2Param(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	if !is2008OrLater(db) {
+		t.Skip("skipping test; needs MS SQL Server 2008 or later")
+	}
+
+	db.Exec("drop table dbo.temp")
+	exec(t, db, "create table dbo.temp (id int, t time(7))")
+	defer exec(t, db, "drop table dbo.temp")
+
+	expect := time.Date(1, 1, 1, 12, 35, 29, 1234567e2, time.Local)
+	_, err = db.Exec("insert into dbo.temp (id, t) values (?, ?)", 1, expect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// parameter type can be set explicitly too
+	_, err = db.Exec("insert into dbo.temp (id, t) values (?, ?)", 2, Typed{Value: expect, SQLType: api.SQL_SS_TIME2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id := 1; id <= 2; id++ {
+		var got time.Time
+		err = db.QueryRow("select t from dbo.temp where id = ?", id).Scan(&got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expect != got {
+			t.Errorf("%d: expect %v, but got %v", id, expect, got)
+		}
+	}
+}
+
+func TestMSSQLDatetime2ParamNotDescribed(t *testing.T) {
+	params := newConnParams()
+	params["describe_params"] = "false"
+	db, sc, err := mssqlConnectWithParams(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	if !is2008OrLater(db) {
+		t.Skip("skipping test; needs MS SQL Server 2008 or later")
+	}
+
+	db.Exec("drop table dbo.temp")
+	exec(t, db, "create table dbo.temp (id int, dt datetime2)")
+	defer exec(t, db, "drop table dbo.temp")
+
+	tests := []struct {
+		ns, want int
+	}{
+		{0, 0},
+		{123e6, 123e6},
+		{1234567e2, 1234567e2},
+		{123456789, 1234567e2}, // datetime2 keeps 100ns
+	}
+	for i, test := range tests {
+		v := time.Date(2007, 5, 8, 12, 35, 29, test.ns, time.Local)
+		_, err = db.Exec("insert into dbo.temp (id, dt) values (?, ?)", i, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got time.Time
+		err = db.QueryRow("select dt from dbo.temp where id = ?", i).Scan(&got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Nanosecond() != test.want {
+			t.Errorf("%d nanoseconds: expect %d, but got %d", test.ns, test.want, got.Nanosecond())
+		}
+	}
+}
+
+func TestMSSQLTimeFractionDigits(t *testing.T) {
+	var tests = []struct {
+		ns   int
+		want api.SQLSMALLINT
+	}{
+		{0, 3},
+		{1e8, 3},
+		{123e6, 3},
+		{1234e5, 4},
+		{1234567e2, 7},
+		{123456789, 7},
+	}
+	for _, test := range tests {
+		if got := timeFractionDigits(test.ns); got != test.want {
+			t.Errorf("timeFractionDigits(%d): expect %d, but got %d", test.ns, test.want, got)
+		}
+	}
+}
+
+func TestMSSQLFloat32Param(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	db.Exec("drop table dbo.temp")
+	exec(t, db, "create table dbo.temp (f real)")
+	defer exec(t, db, "drop table dbo.temp")
+
+	// pass float32 directly to the driver, without database/sql conversion
+	dc, err := drv.Open(newConnParams().makeODBCConnectionString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dc.Close()
+	st, err := dc.Prepare("insert into dbo.temp (f) values (?)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	const want = float32(1.1)
+	if _, err := st.Exec([]driver.Value{want}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got float32
+	if err := db.QueryRow("select f from dbo.temp").Scan(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("expected %v, but got %v", want, got)
+	}
+
+	// database/sql passes float32 to the driver unchanged too
+	exec(t, db, "delete from dbo.temp")
+	if _, err := db.Exec("insert into dbo.temp (f) values (?)", want); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow("select f from dbo.temp").Scan(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("expected %v, but got %v", want, got)
+	}
+	var typ string
+	if err := db.QueryRow("select cast(sql_variant_property(?, 'BaseType') as varchar(20))", want).Scan(&typ); err != nil {
+		t.Fatal(err)
+	}
+	if typ != "real" {
+		t.Errorf("float32 parameter bound as %q, want %q", typ, "real")
+	}
+}
+
+type testStatus int16
+
+type testName string
+
+func TestMSSQLNamedTypeParams(t *testing.T) {
+	dc, err := drv.Open(newConnParams().makeODBCConnectionString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dc.Close()
+
+	// pass named types directly to the driver, without database/sql conversion
+	tests := []struct {
+		v    driver.Value
+		want string
+	}{
+		{testStatus(3), "smallint"},
+		{Typed{Value: testStatus(3), SQLType: api.SQL_INTEGER}, "int"},
+	}
+	for _, test := range tests {
+		st, err := dc.Prepare("select cast(sql_variant_property(?, 'BaseType') as varchar(20))")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, err := st.Query([]driver.Value{test.v})
+		if err != nil {
+			st.Close()
+			t.Errorf("%v: %v", test.v, err)
+			continue
+		}
+		row := make([]driver.Value, 1)
+		err = rows.Next(row)
+		rows.Close()
+		st.Close()
+		if err != nil {
+			t.Errorf("%v: %v", test.v, err)
+			continue
+		}
+		if typ := string(row[0].([]byte)); typ != test.want {
+			t.Errorf("%v: parameter bound as %q, want %q", test.v, typ, test.want)
+		}
+	}
+
+	st, err := dc.Prepare("select ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	rows, err := st.Query([]driver.Value{testName("abc")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	row := make([]driver.Value, 1)
+	if err := rows.Next(row); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprintf("%s", row[0]); got != "abc" {
+		t.Errorf("expect %q, but got %q", "abc", got)
+	}
+}
+
+func TestMSSQLBasicValue(t *testing.T) {
+	type flag bool
+	type blob []byte
+	tests := []struct {
+		v, want driver.Value
+	}{
+		{testStatus(3), int16(3)},
+		{testName("abc"), "abc"},
+		{flag(true), true},
+		{blob{1, 2}, []byte{1, 2}},
+	}
+	for _, test := range tests {
+		got, ok := basicValue(test.v)
+		if !ok || fmt.Sprintf("%T %v", got, got) != fmt.Sprintf("%T %v", test.want, test.want) {
+			t.Errorf("basicValue(%T): expect %T %v, but got %T %v (%v)", test.v, test.want, test.want, got, got, ok)
+		}
+	}
+	for _, v := range []driver.Value{"abc", int64(1), []byte{1}, struct{}{}, []int{1}} {
+		if _, ok := basicValue(v); ok {
+			t.Errorf("basicValue(%T) should fail, but succeeded", v)
+		}
+	}
+}
+
+func TestMSSQLNilPointerParams(t *testing.T) {
+	dc, err := drv.Open(newConnParams().makeODBCConnectionString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dc.Close()
+
+	// pass typed nil directly to the driver, without database/sql conversion
+	for _, v := range []driver.Value{(*string)(nil), (*int64)(nil), (*time.Time)(nil)} {
+		st, err := dc.Prepare("select case when ? is null then 1 else 0 end")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, err := st.Query([]driver.Value{v})
+		if err != nil {
+			st.Close()
+			t.Errorf("%T: %v", v, err)
+			continue
+		}
+		row := make([]driver.Value, 1)
+		err = rows.Next(row)
+		rows.Close()
+		st.Close()
+		if err != nil {
+			t.Errorf("%T: %v", v, err)
+			continue
+		}
+		if row[0] != int32(1) {
+			t.Errorf("%T: parameter is not bound as NULL, got %#v", v, row[0])
+		}
+	}
+}
+
+func TestMSSQLNativeIntParams(t *testing.T) {
+	dc, err := drv.Open(newConnParams().makeODBCConnectionString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dc.Close()
+
+	// pass integers directly to the driver, without database/sql conversion
+	tests := []struct {
+		v    driver.Value
+		want string
+	}{
+		{int8(-8), "smallint"},
+		{uint8(255), "smallint"},
+		{int16(-16), "smallint"},
+		{uint16(65535), "int"},
+		{int32(-32), "int"},
+		{int(-1), "int"},
+		{uint32(1 << 31), "bigint"},
+		{uint64(1 << 40), "bigint"},
+	}
+	for _, test := range tests {
+		st, err := dc.Prepare("select cast(sql_variant_property(?, 'BaseType') as varchar(20))")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, err := st.Query([]driver.Value{test.v})
+		if err != nil {
+			st.Close()
+			t.Errorf("%T: %v", test.v, err)
+			continue
+		}
+		row := make([]driver.Value, 1)
+		err = rows.Next(row)
+		rows.Close()
+		st.Close()
+		if err != nil {
+			t.Errorf("%T: %v", test.v, err)
+			continue
+		}
+		if typ := string(row[0].([]byte)); typ != test.want {
+			t.Errorf("%T: parameter bound as %q, want %q", test.v, typ, test.want)
+		}
+	}
+
+	// database/sql passes small integers to the driver unchanged too
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+	for _, test := range tests {
+		switch test.v.(type) {
+		case int, uint64:
+			// converted to int64 by CheckNamedValue
+			continue
+		}
+		var typ string
+		err := db.QueryRow("select cast(sql_variant_property(?, 'BaseType') as varchar(20))", test.v).Scan(&typ)
+		if err != nil {
+			t.Errorf("%T: %v", test.v, err)
+			continue
+		}
+		if typ != test.want {
+			t.Errorf("%T: parameter bound as %q, want %q", test.v, typ, test.want)
+		}
+	}
+}
+
+func TestMSSQLNewNumeric(t *testing.T) {
+	for _, s := range []string{"0", "0.00", "1", "-1", "123.45", "-0.001", "12345678901234567890.1234567890", "99999999999999999999999999999999999999"} {
+		n, err := NewNumeric(s)
+		if err != nil {
+			t.Errorf("NewNumeric(%q) failed: %v", s, err)
+			continue
+		}
+		if got := n.String(); got != s {
+			t.Errorf("NewNumeric(%q).String() = %q", s, got)
+		}
+	}
+	n, err := NewNumeric("-0.00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := n.String(); got != "0.00" {
+		t.Errorf("NewNumeric(%q).String() = %q, want %q", "-0.00", got, "0.00")
+	}
+	n, err = NewNumeric("-123.45")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Precision != 5 || n.Scale != 2 || n.Sign != 0 || n.Val[0] != 0x39 || n.Val[1] != 0x30 {
+		t.Errorf("unexpected NewNumeric(%q) result: %+v", "-123.45", n)
+	}
+	for _, s := range []string{"", "-", "1.2.3", "1e5", "abc", "123456789012345678901234567890123456789"} {
+		if _, err := NewNumeric(s); err == nil {
+			t.Errorf("NewNumeric(%q) should fail, but succeeded", s)
+		}
+	}
+}
+
+type tvpItem struct {
+	ID      int64
+	Name    string
+	Price   *float64
+	Created time.Time
+	Note    string `tvp:"-"`
+	hidden  int
+}
+
+func TestMSSQLTVPValues(t *testing.T) {
+	price := 1.5
+	items := []tvpItem{
+		{ID: 1, Name: "a", Price: &price},
+		{ID: 2, Name: "hello"},
+	}
+	values, n, err := tvpValues(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || len(values) != 4 {
+		t.Fatalf("expect 2 rows of 4 columns, but got %d rows of %d columns", n, len(values))
+	}
+	c, err := newTVPColumn(values[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ctype != api.SQL_C_WCHAR || c.size != 5 || c.width != 12 {
+		t.Errorf("wrong Name column: ctype=%d size=%d width=%d", c.ctype, c.size, c.width)
+	}
+	c, err = newTVPColumn(values[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ctype != api.SQL_C_DOUBLE || c.ind[0] != 8 || c.ind[1] != api.SQL_NULL_DATA {
+		t.Errorf("wrong Price column: ctype=%d ind=%v", c.ctype, c.ind)
+	}
+
+	for _, v := range []interface{}{nil, 1, []int{1}, []struct{ a int }{{1}}} {
+		if _, _, err := tvpValues(v); err == nil {
+			t.Errorf("tvpValues(%#v) should fail, but succeeded", v)
+		}
+	}
+	if _, err := newTVPColumn([]driver.Value{int64(1), "a"}); err == nil {
+		t.Error("newTVPColumn should fail for values of different types, but succeeded")
+	}
+}
+
+func TestMSSQLTVP(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	db.Exec("drop procedure dbo.temp")
+	db.Exec("drop type dbo.tempItems")
+	exec(t, db, "create type dbo.tempItems as table (id int, name nvarchar(50), price float, created datetime2)")
+	defer exec(t, db, "drop type dbo.tempItems")
+	exec(t, db, `
+create procedure dbo.temp @items dbo.tempItems readonly
+as
+	select count(*), sum(id), max(name), sum(price), max(created) from @items
+`)
+	defer exec(t, db, "drop procedure dbo.temp")
+
+	price := 2.25
+	created := time.Date(2020, 1, 2, 3, 4, 5, 123456700, time.UTC)
+	var items []tvpItem
+	for i := 1; i <= 1000; i++ {
+		items = append(items, tvpItem{ID: int64(i), Name: fmt.Sprintf("item %04d \u263a", i), Price: &price, Created: created})
+	}
+	items[0].Price = nil // NULL
+
+	var n, sum int64
+	var name string
+	var total float64
+	var maxCreated time.Time
+	err = db.QueryRow("{call dbo.temp(?)}", TVP{TypeName: "dbo.tempItems", Value: items}).Scan(&n, &sum, &name, &total, &maxCreated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1000 || sum != 500500 || name != "item 1000 \u263a" || total != 999*price || !maxCreated.Equal(created) {
+		t.Errorf("unexpected result: %d %d %q %v %v", n, sum, name, total, maxCreated)
+	}
+
+	// empty table
+	var v1, v2, v3, v4 interface{}
+	err = db.QueryRow("{call dbo.temp(?)}", TVP{TypeName: "tempItems", Value: []tvpItem{}}).Scan(&n, &v1, &v2, &v3, &v4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 || v1 != nil || v2 != nil || v3 != nil || v4 != nil {
+		t.Errorf("unexpected result for empty table: %d %v %v %v %v", n, v1, v2, v3, v4)
+	}
+}
+
+func TestMSSQLNumericParam(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	db.Exec("drop table dbo.temp")
+	exec(t, db, "create table dbo.temp (id int, d decimal(38, 10))")
+	defer exec(t, db, "drop table dbo.temp")
+
+	for i, s := range []string{"0", "-1.5", "12345678901234567890.1234567890", "-0.0000000001"} {
+		n, err := NewNumeric(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec("insert into dbo.temp (id, d) values (?, ?)", i, n); err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+		var got string
+		err = db.QueryRow("select cast(d as varchar(50)) from dbo.temp where id = ?", i).Scan(&got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := new(big.Rat).SetString(s)
+		if r, ok := new(big.Rat).SetString(got); !ok || r.Cmp(want) != 0 {
+			t.Errorf("expected %s, but got %s", s, got)
+		}
+	}
+}
+
+func TestMSSQLDecimalParam(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	db.Exec("drop table dbo.temp")
+	exec(t, db, "create table dbo.temp (id int, d decimal(38, 10))")
+
+	bigInt, _ := new(big.Int).SetString("1234567890123456789012345678", 10)
+	bigRat, _ := new(big.Rat).SetString("-12345678901234567890.0123456789")
+	var tests = []struct {
+		value  interface{}
+		expect string
+	}{
+		{bigInt, "1234567890123456789012345678.0000000000"},
+		{bigRat, "-12345678901234567890.0123456789"},
+		{big.NewRat(1, 8), "0.1250000000"},
+	}
+	for i, test := range tests {
+		_, err = db.Exec("insert into dbo.temp (id, d) values (?, ?)", i, test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got string
+		err = db.QueryRow("select cast(d as varchar(50)) from dbo.temp where id = ?", i).Scan(&got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.expect != got {
+			t.Errorf("expect %v, but got %v", test.expect, got)
+		}
+	}
+
+	_, err = db.Exec("insert into dbo.temp (id, d) values (?, ?)", 100, big.NewRat(1, 3))
+	if err == nil {
+		t.Error("inserting 1/3 should fail, but succeeded")
+	}
+
+	exec(t, db, "drop table dbo.temp")
+}
+
+// https://github.com/alexbrainman/odbc/issues/19
+func TestMSSQLMerge(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	if !is2008OrLater(db) {
+		t.Skip("skipping test; needs MS SQL Server 2008 or later")
+	}
+
+	db.Exec("drop table dbo.temp")
+	exec(t, db, `
+		create table dbo.temp (
+			id int not null,
+			name varchar(20),
+			constraint pk_temp primary key(id)
+		)
+	`)
+	for i := 0; i < 5; i++ {
+		_, err = db.Exec("insert into dbo.temp (id, name) values (?, ?)", i, fmt.Sprintf("gordon%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := db.Prepare(`
+		merge into dbo.temp as dest
+		using ( values (?, ?) ) as src (id, name) on src.id = dest.id
+		when matched then update set dest.name = src.name
+		when not matched then insert values (src.id, src.name);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var tests = []struct {
+		id   int
+		name string
+	}{
+		{id: 1, name: "new name1"},
+		{id: 8, name: "hohoho"},
+	}
+	for _, test := range tests {
+		_, err = s.Exec(test.id, test.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, test := range tests {
+		var got string
+		err = db.QueryRow("select name from dbo.temp where id = ?", test.id).Scan(&got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.name != got {
+			t.Fatalf("expect %v, but got %v", test.name, got)
+		}
+	}
+
+	exec(t, db, "drop table dbo.temp")
+}
+
+// https://github.com/alexbrainman/odbc/issues/20
+func TestMSSQLSelectInt(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	const expect = 123456
+	var got int
+	if err := db.QueryRow("select ?", expect).Scan(&got); err != nil {
+		t.Fatal(err)
+	}
+	if expect != got {
+		t.Fatalf("expect %v, but got %v", expect, got)
+	}
+}
+
+// https://github.com/alexbrainman/odbc/issues/21
+func TestMSSQLTextColumnParam(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	db.Exec("drop table dbo.temp")
+	exec(t, db, `create table dbo.temp(id int primary key not null, v1 text, v2 text, v3 text, v4 text, v5 text, v6 text, v7 text, v8 text)`)
+
+	s, err := db.Prepare(`insert into dbo.temp(id, v1, v2, v3, v4, v5, v6, v7, v8) values (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	b := "string string string string string string string string string"
+	for i := 0; i < 100; i++ {
+		_, err := s.Exec(i, b, b, b, b, b, b, b, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exec(t, db, "drop table dbo.temp")
+}
+
+func digestString(s string) string {
+	if len(s) < 40 {
+		return s
+	}
+	return fmt.Sprintf("%s ... (%d bytes long)", s[:40], len(s))
+}
+
+func digestBytes(b []byte) string {
+	if len(b) < 20 {
+		return fmt.Sprintf("%v", b)
+	}
+	s := ""
+	for _, v := range b[:20] {
+		if s != "" {
+			s += " "
+		}
+		s += fmt.Sprintf("%d", v)
+	}
+	return fmt.Sprintf("[%v ...] (%d bytes long)", s, len(b))
+}
+
+var paramTypeTests = []struct {
+	description string
+	sqlType     string
+	value       interface{}
+}{
+	// nil parameters
+	{"NULL for bit", "bit", nil},
+	{"NULL for text", "text", nil},
+	{"NULL for int", "int", nil},
+	// strings
+	{"non empty string", "varchar(10)", "abc"},
+	{"one character string", "varchar(10)", "a"},
+	{"empty string", "varchar(10)", ""},
+	{"empty unicode string", "nvarchar(10)", ""},
+	{"3999 large unicode string", "nvarchar(max)", strings.Repeat("a", 3999)},
+	{"4000 large unicode string", "nvarchar(max)", strings.Repeat("a", 4000)},
+	{"4000 large non-ascii unicode string", "nvarchar(max)", strings.Repeat("\u0421", 4000)},
+	{"4001 large unicode string", "nvarchar(max)", strings.Repeat("a", 4001)},
+	{"4001 large non-ascii unicode string", "nvarchar(max)", strings.Repeat("\u0421", 4001)},
+	{"10000 large unicode string", "nvarchar(max)", strings.Repeat("a", 10000)},
+	{"empty unicode null string", "nvarchar(10) null", ""},
+	{"3999 large string value", "text", strings.Repeat("a", 3999)},
+	{"4000 large string value", "text", strings.Repeat("a", 4000)},
+	{"4000 large unicode string value", "ntext", strings.Repeat("\u0421", 4000)},
+	{"4001 large string value", "text", strings.Repeat("a", 4001)},
+	{"4001 large unicode string value", "ntext", strings.Repeat("\u0421", 4001)},
+	{"very large string value", "text", strings.Repeat("a", 10000)},
+	{"unicode with russian letters", "nvarchar(10)", "\u0421\u0430\u0448\u0430"},
+	{"unicode with russian letters", "nvarchar(max)", "\u0421\u0430\u0448\u0430"},
+	{"unicode with russian letters", "ntext", "Саша"},
+	{"unicode with chinese letters", "nvarchar(max)", "Hello, 世界"},
+	{"unicode with chinese letters", "nvarchar(21)", "你好世界"},
+	{"unicode with greek letters", "nvarchar(21)", "Γεια σου κόσμε"},
+	// datetime
+	{"datetime overflow", "datetime", time.Date(2013, 9, 9, 14, 07, 15, 123e6, time.Local)},
+	// binary blobs
+	{"small blob", "varbinary", make([]byte, 1)},
+	{"very large blob", "varbinary(max)", make([]byte, 100000)},
+	{"7999 large image", "image", make([]byte, 7999)},
+	{"8000 large image", "image", make([]byte, 8000)},
+	{"8001 large image", "image", make([]byte, 8001)},
+	{"very large image", "image", make([]byte, 10000)},
+}
+
+func TestMSSQLTextColumnParamTypes(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	for _, test := range paramTypeTests {
+		db.Exec("drop table dbo.temp")
+		exec(t, db, fmt.Sprintf("create table dbo.temp(v %s)", test.sqlType))
+		_, err = db.Exec("insert into dbo.temp(v) values(?)", test.value)
+		if err != nil {
+			t.Errorf("%s insert test failed: %s", test.description, err)
+		}
+		var v interface{}
+		err = db.QueryRow("select v from dbo.temp").Scan(&v)
+		if err != nil {
+			t.Errorf("%s select test failed: %s", test.description, err)
+			continue
+		}
+		switch want := test.value.(type) {
+		case string:
+			have := string(v.([]byte))
+			if have != want {
+				t.Errorf("%s wrong return value: have %q; want %q", test.description, digestString(have), digestString(want))
+			}
+		case []byte:
+			have := v.([]byte)
+			if !bytes.Equal(have, want) {
+				t.Errorf("%s wrong return value: have %v; want %v", test.description, digestBytes(have), digestBytes(want))
+			}
+		case time.Time:
+			have := v.(time.Time)
+			if have != want {
+				t.Errorf("%s wrong return value: have %v; want %v", test.description, have, want)
+			}
+		case nil:
+			if v != nil {
+				t.Errorf("%s wrong return value: have %v; want nil", test.description, v)
+			}
+		}
+	}
+	exec(t, db, "drop table dbo.temp")
+}
+
+func TestMSSQLEmptyStringVsNULL(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	for _, sqlType := range []string{"varchar(10)", "nvarchar(10)", "varchar(max)", "nvarchar(max)"} {
+		db.Exec("drop table dbo.temp")
+		exec(t, db, fmt.Sprintf("create table dbo.temp(id int, v %s null)", sqlType))
+		if _, err := db.Exec("insert into dbo.temp(id, v) values(1, ?)", ""); err != nil {
+			t.Fatalf("%s: %v", sqlType, err)
+		}
+		if _, err := db.Exec("insert into dbo.temp(id, v) values(2, ?)", nil); err != nil {
+			t.Fatalf("%s: %v", sqlType, err)
+		}
+		for _, test := range []struct {
+			id     int
+			isNull bool
+		}{
+			{1, false},
+			{2, true},
+		} {
+			var l sql.NullInt64
+			err := db.QueryRow("select datalength(v) from dbo.temp where id = ?", test.id).Scan(&l)
+			if err != nil {
+				t.Fatalf("%s: %v", sqlType, err)
+			}
+			if l.Valid == test.isNull {
+				t.Errorf("%s row %d: expect NULL=%v, but got %v", sqlType, test.id, test.isNull, !l.Valid)
+			}
+			if l.Valid && l.Int64 != 0 {
+				t.Errorf("%s row %d: expect zero-length value, but got %d bytes", sqlType, test.id, l.Int64)
+			}
+		}
+	}
+	exec(t, db, "drop table dbo.temp")
+}
+
+func TestMSSQLLongColumnNames(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	query := fmt.Sprintf("select 'hello' as %s", strings.Repeat("a", 110))
+	var s string
+	err = db.QueryRow(query).Scan(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "hello" {
+		t.Errorf("expected \"hello\", but received %v", s)
+	}
+}
+
+func TestMSSQLColumnBufferOptions(t *testing.T) {
+	params := newConnParams()
+	params["name_buffer_size"] = "10"
+	params["max_bind_width"] = "5"
+	db, sc, err := mssqlConnectWithParams(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	name := strings.Repeat("a", 110)
+	value := strings.Repeat("b", 100)
+	rows, err := db.Query(fmt.Sprintf("select cast('%s' as varchar(100)) as %s, cast('abc' as varchar(5)) as b", value, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cols) != 2 || cols[0] != name || cols[1] != "b" {
+		t.Errorf("unexpected column names: %v", cols)
+	}
+	if !rows.Next() {
+		t.Fatal(rows.Err())
+	}
+	var a, b string
+	if err := rows.Scan(&a, &b); err != nil {
+		t.Fatal(err)
+	}
+	if a != value || b != "abc" {
+		t.Errorf("unexpected values: %q, %q", a, b)
+	}
+}
+
+func TestMSSQLNoDescribeParams(t *testing.T) {
+	params := newConnParams()
+	params["describe_params"] = "false"
+	db, sc, err := mssqlConnectWithParams(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	db.Exec("drop table dbo.temp")
+	exec(t, db, "create table dbo.temp (id int, name nvarchar(20))")
+	defer exec(t, db, "drop table dbo.temp")
+
+	_, err = db.Exec("insert into dbo.temp (id, name) values (?, ?)", 1, "alex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var id int
+	var name string
+	err = db.QueryRow("select id, name from dbo.temp where id = ?", 1).Scan(&id, &name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 1 || name != "alex" {
+		t.Errorf("unexpected values: %v, %q", id, name)
+	}
+}
+
+func TestMSSQLTextMode(t *testing.T) {
+	params := newConnParams()
+	params["text_mode"] = "true"
+	db, sc, err := mssqlConnectWithParams(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	want := []string{"123", "1.50", "2020-01-02", "0102", "abc", "1"}
+	row := make([]interface{}, len(want))
+	for i := range row {
+		row[i] = new(interface{})
+	}
+	err = db.QueryRow(`select cast(123 as int), cast(1.5 as decimal(5,2)),
+		cast('2020-01-02' as date), cast(0x0102 as varbinary(2)),
+		cast('abc' as varchar(5)), cast(1 as bit)`).Scan(row...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, w := range want {
+		v := *row[i].(*interface{})
+		b, ok := v.([]byte)
+		if !ok {
+			t.Errorf("column %d: expected text, but got %T", i, v)
+			continue
+		}
+		if string(b) != w {
+			t.Errorf("column %d: expected %q, but got %q", i, w, b)
+		}
+	}
+}
+
+func TestMSSQLCharAsString(t *testing.T) {
+	params := newConnParams()
+	params["char"] = "string"
+	db, sc, err := mssqlConnectWithParams(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	want := []string{"abc", "déf", "", strings.Repeat("x", 5000)}
+	row := make([]interface{}, len(want)+1)
+	for i := range row {
+		row[i] = new(interface{})
+	}
+	err = db.QueryRow(`select cast('abc' as varchar(5)), cast(N'déf' as nvarchar(5)),
+		cast('' as varchar(5)), replicate(cast(N'x' as nvarchar(max)), 5000),
+		cast(0x0102 as varbinary(2))`).Scan(row...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, w := range want {
+		v := *row[i].(*interface{})
+		if v != w {
+			t.Errorf("column %d: expected %q, but got %#v", i, w, v)
+		}
+	}
+	// binary columns are still returned as []byte
+	if v, ok := (*row[len(want)].(*interface{})).([]byte); !ok || !bytes.Equal(v, []byte{1, 2}) {
+		t.Errorf("binary column: expected []byte{1, 2}, but got %#v", v)
+	}
+}
+
+func TestMSSQLTrimChar(t *testing.T) {
+	const query = `select cast(123 as char(5)), cast(N'ab' as nchar(4)),
+		cast('ab  ' as varchar(5)), cast(' ' as char(3))`
+	for _, trim := range []bool{false, true} {
+		params := newConnParams()
+		params["char"] = "string"
+		want := []string{"123  ", "ab  ", "ab  ", "   "}
+		if trim {
+			params["trim_char"] = "true"
+			want = []string{"123", "ab", "ab  ", ""}
+		}
+		func() {
+			db, sc, err := mssqlConnectWithParams(params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer closeDB(t, db, sc, sc)
+
+			got := make([]string, len(want))
+			row := make([]interface{}, len(got))
+			for i := range got {
+				row[i] = &got[i]
+			}
+			if err := db.QueryRow(query).Scan(row...); err != nil {
+				t.Fatal(err)
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("trim_char=%v column %d: expected %q, but got %q", trim, i, want[i], got[i])
+				}
+			}
+		}()
+	}
+}
+
+func TestMSSQLRawBytes(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	db.Exec("drop table dbo.temp")
+	exec(t, db, `create table dbo.temp(ascii char(7), utf16 nchar(7), blob binary(3))`)
+	_, err = db.Exec(`insert into dbo.temp (ascii, utf16, blob) values (?, ?, ?)`, "alex", "alex", []byte{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := db.Query("select ascii, utf16, blob from dbo.temp")
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ascii, utf16 sql.RawBytes
+		var blob []byte
+		err = rows.Scan(&ascii, &utf16, &blob)
+		if err != nil {
+			t.Fatalf("Scan: %v", err)
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exec(t, db, "drop table dbo.temp")
+}
+
+func TestMSSQLRawBytesUnboundColumn(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	rows, err := db.Query(`
+select cast(replicate(cast('a' as varchar(max)), 5000) as varbinary(max)), cast(replicate(cast('a' as varchar(max)), 5000) as varbinary(max))
+union all select cast('bb' as varbinary(max)), cast('bb' as varbinary(max))
+union all select cast(replicate(cast('c' as varchar(max)), 3000) as varbinary(max)), cast(replicate(cast('c' as varchar(max)), 3000) as varbinary(max))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	// []byte values must stay intact, while memory
+	// of sql.RawBytes values is reused for every row
+	var kept [][]byte
+	want := []string{strings.Repeat("a", 5000), "bb", strings.Repeat("c", 3000)}
+	for i := 0; rows.Next(); i++ {
+		var raw sql.RawBytes
+		var b []byte
+		if err := rows.Scan(&raw, &b); err != nil {
+			t.Fatal(err)
+		}
+		if string(raw) != want[i] {
+			t.Errorf("row %d: wrong sql.RawBytes value of %d bytes", i, len(raw))
+		}
+		kept = append(kept, b)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	for i, b := range kept {
+		if string(b) != want[i] {
+			t.Errorf("row %d: []byte value of %d bytes is changed", i, len(b))
+		}
+	}
+}
+
+// https://github.com/alexbrainman/odbc/issues/27
+func TestMSSQLUTF16ToUTF8(t *testing.T) {
+	tests := []struct {
+		s    []uint16
+		want string
+	}{
+		{[]uint16{0x47, 0x75, 0x73, 0x74, 0x61, 0x66, 0x27, 0x73, 0x20, 0x4b, 0x6e, 0xe4, 0x63, 0x6b, 0x65, 0x62, 0x72, 0xf6, 0x64}, "Gustaf's Knäckebröd"},
+		{[]uint16{0x61, 0, 0x62}, "a"},
+		{[]uint16{0xd83d, 0xde00, 0x20, 0xd834, 0xdd1e}, "\U0001F600 \U0001D11E"},
+		{[]uint16{0xd83d}, "\uFFFD"},
+		{[]uint16{0xde00, 0x61}, "\uFFFDa"},
+		{[]uint16{0xd83d, 0x61}, "\uFFFDa"},
+		{[]uint16{0xd83d, 0xd83d, 0xde00}, "\uFFFD\U0001F600"},
+	}
+	for _, test := range tests {
+		if got := string(api.UTF16ToUTF8(test.s)); got != test.want {
+			t.Errorf("UTF16ToUTF8(%x): expect %q, but got %q", test.s, test.want, got)
+		}
+		if got := api.UTF16ToString(test.s); got != test.want {
+			t.Errorf("UTF16ToString(%x): expect %q, but got %q", test.s, test.want, got)
+		}
+	}
+	// every non-BMP character survives round trip
+	s := "emoji \U0001F600\U0001F44D\U0001F3FD, music \U0001D11E, CJK \U00020000"
+	if got := api.UTF16ToString(api.StringToUTF16(s)); got != s {
+		t.Errorf("expect %q, but got %q", s, got)
+	}
+}
+
+func TestMSSQLNonBMPStrings(t *testing.T) {
+	params := newConnParams()
+	params["char"] = "string"
+	db, sc, err := mssqlConnectWithParams(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	db.Exec("drop table dbo.temp")
+	exec(t, db, "create table dbo.temp (id int, s nvarchar(50), l nvarchar(max))")
+	defer exec(t, db, "drop table dbo.temp")
+
+	want := "\U0001F600 \U0001F44D\U0001F3FD \U0001D11E \U00020000"
+	long := strings.Repeat(want, 1000)
+	if _, err := db.Exec("insert into dbo.temp (id, s, l) values (1, ?, ?)", want, long); err != nil {
+		t.Fatal(err)
+	}
+	var s, l string
+	var n int
+	err = db.QueryRow("select s, l, datalength(s) from dbo.temp where id = 1").Scan(&s, &l, &n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != want {
+		t.Errorf("expect %q, but got %q", want, s)
+	}
+	if l != long {
+		t.Errorf("nvarchar(max) value of %d bytes differs from %d bytes inserted", len(l), len(long))
+	}
+	// every non-BMP character takes 4 bytes (surrogate pair) in UTF-16
+	if wantn := 2 * (len(api.StringToUTF16(want)) - 1); n != wantn {
+		t.Errorf("expect %d bytes stored, but got %d", wantn, n)
+	}
+	// parameter value round trips too
+	if err := db.QueryRow("select cast(? as nvarchar(50))", want).Scan(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s != want {
+		t.Errorf("expect %q, but got %q", want, s)
+	}
+}
+
+func TestMSSQLParseConnString(t *testing.T) {
+	var tests = []struct {
+		s      string
+		attrs  []connAttr
+		useDSN bool
+	}{
+		{"", nil, false},
+		{"dsn=mydsn", []connAttr{{"dsn", "mydsn"}}, true},
+		{"DSN=mydsn;UID=me;PWD={p;w}}d};", []connAttr{{"DSN", "mydsn"}, {"UID", "me"}, {"PWD", "p;w}d"}}, true},
+		{"uid=me;pwd=secret", []connAttr{{"uid", "me"}, {"pwd", "secret"}}, false},
+		{"driver={SQL Server}; server = srv ;dsn=mydsn", []connAttr{{"driver", "SQL Server"}, {"server", "srv"}, {"dsn", "mydsn"}}, false},
+	}
+	for _, test := range tests {
+		attrs, err := parseConnString(test.s)
+		if err != nil {
+			t.Errorf("parseConnString(%q) failed: %v", test.s, err)
+			continue
+		}
+		if fmt.Sprint(attrs) != fmt.Sprint(test.attrs) {
+			t.Errorf("parseConnString(%q): expect %v, but got %v", test.s, test.attrs, attrs)
+		}
+		if _, _, _, ok := dsnConnectArgs(test.s); ok != test.useDSN {
+			t.Errorf("dsnConnectArgs(%q): expect %v, but got %v", test.s, test.useDSN, ok)
+		}
+	}
+	for _, s := range []string{"dsn", "pwd={abc", "pwd={abc}d"} {
+		if _, err := parseConnString(s); err == nil {
+			t.Errorf("parseConnString(%q) should fail, but succeeded", s)
+		}
+	}
+}
+
+func TestMSSQLMergeConnString(t *testing.T) {
+	var tests = []struct {
+		s        string
+		keywords map[string]string
+		want     string
+	}{
+		{"dsn=mydsn", nil, "dsn=mydsn"},
+		{"dsn=mydsn;database=a", map[string]string{"Database": "b"}, "dsn=mydsn;database=b"},
+		{"dsn=mydsn", map[string]string{"pwd": "p;w}d", "app": "x{y}"}, "dsn=mydsn;app={x{y}}};pwd={p;w}}d}"},
+		{"driver={SQL Server}", map[string]string{"ApplicationIntent": "ReadOnly"}, "driver=SQL Server;ApplicationIntent=ReadOnly"},
+	}
+	for _, test := range tests {
+		got, err := mergeConnString(test.s, test.keywords)
+		if err != nil {
+			t.Errorf("mergeConnString(%q, %v) failed: %v", test.s, test.keywords, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("mergeConnString(%q, %v): expect %q, but got %q", test.s, test.keywords, test.want, got)
+		}
+		// merged values must survive parsing
+		attrs, err := parseConnString(got)
+		if err != nil {
+			t.Errorf("parseConnString(%q) failed: %v", got, err)
+			continue
+		}
+		for _, a := range attrs {
+			if v, ok := test.keywords[a.key]; ok && v != a.value {
+				t.Errorf("%q value in %q: expect %q, but got %q", a.key, got, v, a.value)
+			}
+		}
+	}
+	for _, k := range []string{"", "a=b", "a;b", " a", "{a}"} {
+		if _, err := mergeConnString("dsn=mydsn", map[string]string{k: "v"}); err == nil {
+			t.Errorf("mergeConnString with %q keyword should fail, but succeeded", k)
+		}
+	}
+}
+
+func TestMSSQLKeywordsConnector(t *testing.T) {
+	params := newConnParams()
+	database := params["database"]
+	delete(params, "database")
+	c, err := drv.KeywordsConnector(params.makeODBCConnectionString(), map[string]string{"database": database})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(c)
+	defer db.Close()
+	var name string
+	if err := db.QueryRow("select db_name()").Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	if name != database {
+		t.Errorf("expect %q database, but got %q", database, name)
+	}
+}
+
+func TestMSSQLIntegratedAuthConnector(t *testing.T) {
+	if err := checkIntegratedAuth("linux", "dsn=mydsn"); err != ErrIntegratedAuthUnavailable {
+		t.Errorf("expect ErrIntegratedAuthUnavailable, but got %v", err)
+	}
+	for _, name := range []string{"dsn=mydsn;UID=me", "dsn=mydsn;pwd=secret"} {
+		if err := checkIntegratedAuth("windows", name); err == nil {
+			t.Errorf("%q: unexpected success, expected error", name)
+		}
+	}
+	if err := checkIntegratedAuth("windows", "driver={SQL Server};server=srv"); err != nil {
+		t.Error(err)
+	}
+
+	if isFreeTDS() || len(*msuser) != 0 || runtime.GOOS != "windows" {
+		t.Skip("integrated authentication is not used by tests")
+	}
+	params := newConnParams()
+	delete(params, "trusted_connection")
+	c, err := drv.IntegratedAuthConnector(params.makeODBCConnectionString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(c)
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMSSQLReadOnlyIntentConnector(t *testing.T) {
+	c, err := drv.ReadOnlyIntentConnector(newConnParams().makeODBCConnectionString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(c)
+	defer db.Close()
+	// Without Availability Group the server accepts the intent
+	// and connects to the same database.
+	var n int
+	if err := db.QueryRow("select 1").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	s, err := c.(*connector).connString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(s, ";ApplicationIntent=ReadOnly") {
+		t.Errorf("ApplicationIntent=ReadOnly is missing in %q", s)
+	}
+}
+
+func TestMSSQLFindWriteKeyword(t *testing.T) {
+	var tests = []struct {
+		q, want string
+	}{
+		{"select * from t", ""},
+		{"insert into t values (1)", "INSERT"},
+		{"  Update t set a = 1", "UPDATE"},
+		{"select 1; drop table t", "DROP"},
+		{"select 'delete' from t", ""},
+		{"select [update] from t", ""},
+		{"select a -- delete\nfrom t", ""},
+		{"select /* drop */ a from t", ""},
+		{"select updated_at from t", ""},
+		{"{call dbo.proc(?)}", "CALL"},
+		{"replace into t values (1)", "REPLACE"},
+		{"select replace(a, 'x', 'y') from t", ""},
+		{"select 1; replace into t values (1)", "REPLACE"},
+	}
+	for _, test := range tests {
+		if got := findWriteKeyword(test.q, true); got != test.want {
+			t.Errorf("findWriteKeyword(%q): expect %q, but got %q", test.q, test.want, got)
+		}
+	}
+	// brackets do not quote identifiers outside of SQL Server and Access
+	for _, test := range []struct {
+		q, want string
+	}{
+		{"select [update] from t", "UPDATE"},
+		{"select a[1] from t; drop table t", "DROP"},
+	} {
+		if got := findWriteKeyword(test.q, false); got != test.want {
+			t.Errorf("findWriteKeyword(%q, false): expect %q, but got %q", test.q, test.want, got)
+		}
+	}
+}
+
+func TestMSSQLReadOnlyCheck(t *testing.T) {
+	params := newConnParams()
+	params["read_only_check"] = "true"
+	db, sc, err := mssqlConnectWithParams(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	db.Exec("drop table dbo.temp")
+	exec(t, db, "create table dbo.temp (a int)")
+	defer exec(t, db, "drop table dbo.temp")
+
+	tx, err := db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := tx.QueryRow("select count(*) from dbo.temp").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("insert into dbo.temp (a) values (1)"); err == nil {
+		t.Error("insert in read-only transaction should fail")
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	// writes are allowed outside of read-only transaction
+	if _, err := db.Exec("insert into dbo.temp (a) values (1)"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMSSQLConnectionStringRewriter(t *testing.T) {
+	defer SetConnectionStringRewriter(nil)
+
+	errInsecure := errors.New("insecure connection string")
+	SetConnectionStringRewriter(func(s string) (string, error) {
+		if strings.Contains(strings.ToLower(s), "trustservercertificate=yes") {
+			return "", errInsecure
+		}
+		return s + ";app=go_rewriter", nil
+	})
+
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	var app string
+	if err := db.QueryRow("select app_name()").Scan(&app); err != nil {
+		t.Fatal(err)
+	}
+	if app != "go_rewriter" {
+		t.Errorf("expected application name %q, but got %q", "go_rewriter", app)
+	}
+
+	_, err = drv.Open(newConnParams().makeODBCConnectionString() + ";TrustServerCertificate=yes")
+	if err != errInsecure {
+		t.Errorf("expected %v, but got %v", errInsecure, err)
+	}
+}
+
+func TestMSSQLEncodeAccessToken(t *testing.T) {
+	got := encodeAccessToken("ab")
+	want := []byte{4, 0, 0, 0, 'a', 0, 'b', 0}
+	if !bytes.Equal(got, want) {
+		t.Errorf("expected %v, but got %v", want, got)
+	}
+
+	if _, err := drv.AccessTokenConnector("driver=x", nil); err == nil {
+		t.Error("AccessTokenConnector with nil token function should fail")
+	}
+	c, err := drv.AccessTokenConnector("driver=x", func(ctx context.Context) (string, error) {
+		return "", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Connect(context.Background()); err == nil {
+		t.Error("Connect with empty access token should fail")
+	}
+}
+
+func TestMSSQLPacketSize(t *testing.T) {
+	params := newConnParams()
+	params["packet_size"] = "16384"
+	db, sc, err := mssqlConnectWithParams(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	var size int
+	err = db.QueryRow("select net_packet_size from sys.dm_exec_connections where session_id = @@spid").Scan(&size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 16384 {
+		t.Errorf("expected packet size 16384, but got %d", size)
+	}
+}
+
+// BenchmarkMSSQLPacketSize reads wide rows with different network
+// packet sizes. Bigger packets need fewer round trips to the server.
+func BenchmarkMSSQLPacketSize(b *testing.B) {
+	for _, size := range []string{"4096", "32767"} {
+		b.Run(size, func(b *testing.B) {
+			params := newConnParams()
+			params["packet_size"] = size
+			db, sc, err := mssqlConnectWithParams(params)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer closeDB(b, db, sc, sc)
+
+			q := "select top 1000 replicate(cast('x' as varchar(max)), 4000) from sys.all_objects"
+			for i := 0; i < b.N; i++ {
+				rows, err := db.Query(q)
+				if err != nil {
+					b.Fatal(err)
+				}
+				for rows.Next() {
+					var s []byte
+					if err := rows.Scan(&s); err != nil {
+						b.Fatal(err)
+					}
+				}
+				if err := rows.Err(); err != nil {
+					b.Fatal(err)
+				}
+				rows.Close()
+			}
+		})
+	}
+}
+
+type jsonPoint struct{ X, Y int }
+
+func (p jsonPoint) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`{"x":%d,"y":%d}`, p.X, p.Y)), nil
+}
+
+func TestMSSQLCheckNamedValue(t *testing.T) {
+	now := time.Now()
+	var tests = []struct {
+		v    interface{}
+		want driver.Value
+	}{
+		{int8(-1), int8(-1)},
+		{uint32(7), uint32(7)},
+		{int(42), int64(42)},
+		{float32(1.5), float32(1.5)},
+		{"abc", "abc"},
+		{now, now},
+		{jsonPoint{1, 2}, `{"x":1,"y":2}`},
+		{JSON{Value: map[string]int{"a": 1}}, `{"a":1}`},
+		{JSON{Value: []jsonPoint{{1, 2}}}, `[{"x":1,"y":2}]`},
+		{JSON{}, "null"},
+		{json.RawMessage(`{"b":2}`), `{"b":2}`},
+		{sql.NullInt64{Int64: 3, Valid: true}, int64(3)},
+	}
+	var c Conn
+	for _, test := range tests {
+		nv := driver.NamedValue{Ordinal: 1, Value: test.v}
+		if err := c.CheckNamedValue(&nv); err != nil {
+			t.Errorf("CheckNamedValue(%#v) failed: %v", test.v, err)
+			continue
+		}
+		if nv.Value != test.want {
+			t.Errorf("CheckNamedValue(%#v): expect %#v, but got %#v", test.v, test.want, nv.Value)
+		}
+	}
+	for _, v := range []interface{}{struct{}{}, uint64(1 << 63), make(chan int), JSON{Value: make(chan int)}} {
+		nv := driver.NamedValue{Ordinal: 1, Value: v}
+		if err := c.CheckNamedValue(&nv); err == nil {
+			t.Errorf("CheckNamedValue(%T) should fail, but succeeded", v)
+		}
+	}
+}
+
+func TestMSSQLJSONParams(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	doc := JSON{Value: map[string]interface{}{"name": "Knäckebröd \U0001F600", "tags": []string{"a", "b"}}}
+	var name, tag string
+	err = db.QueryRow("select json_value(?, '$.name'), json_value(?, '$.tags[1]')", doc, doc).Scan(&name, &tag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "Knäckebröd \U0001F600" || tag != "b" {
+		t.Fatalf("unexpected values %q and %q", name, tag)
+	}
+}
+
+// BenchmarkMSSQLSelectNumericRows reads many rows of fixed-width
+// columns, that are all bound with SQLBindCol.
+func BenchmarkMSSQLSelectNumericRows(b *testing.B) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer closeDB(b, db, sc, sc)
+
+	q := `select top 10000 cast(a.object_id as int), cast(a.object_id as bigint),
+		cast(a.object_id as float), cast(a.is_ms_shipped as bit), a.create_date
+		from sys.all_objects a cross join sys.all_objects b`
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rows, err := db.Query(q)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var (
+			i32 int32
+			i64 int64
+			f   float64
+			bit bool
+			t   time.Time
+		)
+		for rows.Next() {
+			if err := rows.Scan(&i32, &i64, &f, &bit, &t); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			b.Fatal(err)
+		}
+		rows.Close()
+	}
+}
+
+// BenchmarkMSSQLBoundColumnValue measures cost of reading values
+// of bound fixed-width columns, that Rows.Next does for every row.
+func BenchmarkMSSQLBoundColumnValue(b *testing.B) {
+	newCol := func(sqltype, ctype api.SQLSMALLINT, size int) *BindableColumn {
+		c := NewBindableColumn(&BaseColumn{SQLType: sqltype}, ctype, size)
+		c.IsBound = true
+		c.Len = BufferLen(size)
+		return c
+	}
+	var ts api.SQL_TIMESTAMP_STRUCT
+	cols := []*BindableColumn{
+		newCol(api.SQL_INTEGER, api.SQL_C_LONG, 4),
+		newCol(api.SQL_BIGINT, api.SQL_C_SBIGINT, 8),
+		newCol(api.SQL_DOUBLE, api.SQL_C_DOUBLE, 8),
+		newCol(api.SQL_BIT, api.SQL_C_BIT, 1),
+		newCol(api.SQL_TYPE_TIMESTAMP, api.SQL_C_TYPE_TIMESTAMP, int(unsafe.Sizeof(ts))),
+	}
+	for _, c := range cols {
+		for i := range c.Buffer {
+			c.Buffer[i] = 0x11
+		}
+	}
+	var h api.SQLHSTMT // not used by bound columns
+	dest := make([]driver.Value, len(cols))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j, c := range cols {
+			v, err := c.Value(h, j)
+			if err != nil {
+				b.Fatal(err)
+			}
+			dest[j] = v
+		}
+	}
+}
+
+func TestMSSQLNextOnClosedRows(t *testing.T) {
+	dc, err := drv.Open(newConnParams().makeODBCConnectionString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dc.Close()
+
+	for _, closeStmtFirst := range []bool{false, true} {
+		st, err := dc.Prepare("select 1 union all select 2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, err := st.Query(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if closeStmtFirst {
+			// statement handle is released, when rows are closed
+			if err := st.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := rows.Close(); err != nil {
+			t.Fatal(err)
+		}
+		dest := make([]driver.Value, 1)
+		if err := rows.Next(dest); err != errRowsClosed {
+			t.Errorf("Next on closed rows: expected %v, but got %v", errRowsClosed, err)
+		}
+		if err := rows.(driver.RowsNextResultSet).NextResultSet(); err != errRowsClosed {
+			t.Errorf("NextResultSet on closed rows: expected %v, but got %v", errRowsClosed, err)
+		}
+		if !closeStmtFirst {
+			st.Close()
+		}
+	}
+}
+
+func TestMSSQLSetColumnValue(t *testing.T) {
+	tests := []struct {
+		ctype  api.SQLSMALLINT
+		size   int
+		v      driver.Value
+		len    BufferLen
+		failed bool
+	}{
+		{api.SQL_C_LONG, 4, int64(7), 4, false},
+		{api.SQL_C_LONG, 4, int64(1 << 40), 0, true},
+		{api.SQL_C_LONG, 4, "a", 0, true},
+		{api.SQL_C_SBIGINT, 8, int64(1 << 40), 8, false},
+		{api.SQL_C_DOUBLE, 8, 1.5, 8, false},
+		{api.SQL_C_BIT, 1, true, 1, false},
+		{api.SQL_C_WCHAR, 8, "abc", 6, false},
+		{api.SQL_C_WCHAR, 8, "abcd", 0, true},
+		{api.SQL_C_CHAR, 4, []byte("abc"), 3, false},
+		{api.SQL_C_BINARY, 2, []byte{1, 2}, 2, false},
+		{api.SQL_C_LONG, 4, nil, api.SQL_NULL_DATA, false},
+	}
+	for _, test := range tests {
+		c := NewBindableColumn(&BaseColumn{}, test.ctype, test.size)
+		err := c.setValue(test.v)
+		if test.failed {
+			if err == nil {
+				t.Errorf("setting %#v into column of C type %d should fail, but succeeded", test.v, test.ctype)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("setting %#v into column of C type %d failed: %v", test.v, test.ctype, err)
+			continue
+		}
+		if c.Len != test.len {
+			t.Errorf("setting %#v into column of C type %d: expect length %d, but got %d", test.v, test.ctype, test.len, c.Len)
+		}
+	}
+}
+
+func TestMSSQLUpdatableCursor(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	db.Exec("drop table dbo.temp")
+	exec(t, db, "create table dbo.temp (id int primary key, name varchar(20), price float)")
+	defer exec(t, db, "drop table dbo.temp")
+	exec(t, db, "insert into dbo.temp values (1, 'one', 1.5), (2, 'two', 2.5), (3, 'three', 3.5)")
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	err = conn.Raw(func(dc interface{}) error {
+		c := dc.(*Conn)
+		rows, err := c.QueryContext(WithUpdatableCursor(ctx), "select id, name, price from dbo.temp", nil)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		r := rows.(*Rows)
+		dest := make([]driver.Value, len(r.Columns()))
+		for {
+			err := r.Next(dest)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			switch dest[0] {
+			case int32(1):
+				err = r.UpdateRow(map[int]driver.Value{1: "uno", 2: nil})
+			case int32(2):
+				err = r.DeleteRow()
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return r.InsertRow(map[int]driver.Value{0: 4, 1: "four"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := db.Query("select id, name, price from dbo.temp order by id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var id int
+		var name string
+		var price sql.NullFloat64
+		if err := rows.Scan(&id, &name, &price); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%d %s %v", id, name, price.Float64))
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := "1 uno 0, 3 three 3.5, 4 four 0"
+	if s := strings.Join(got, ", "); s != want {
+		t.Errorf("expect %q, but got %q", want, s)
+	}
+
+	// cursor is not updatable by default
+	dc, err := drv.Open(newConnParams().makeODBCConnectionString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dc.Close()
+	drows, err := dc.(*Conn).QueryContext(ctx, "select id from dbo.temp", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer drows.Close()
+	if err := drows.(*Rows).DeleteRow(); err != errNotUpdatable {
+		t.Errorf("expect %v error, but got %v", errNotUpdatable, err)
+	}
+}
+
+func TestMSSQLRowNumber(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	db.Exec("drop table dbo.temp")
+	exec(t, db, "create table dbo.temp (id int primary key)")
+	defer exec(t, db, "drop table dbo.temp")
+	exec(t, db, "insert into dbo.temp values (1), (2), (3)")
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	err = conn.Raw(func(dc interface{}) error {
+		rows, err := dc.(*Conn).QueryContext(WithUpdatableCursor(ctx), "select id from dbo.temp order by id", nil)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		r := rows.(*Rows)
+		dest := make([]driver.Value, 1)
+		for want := int64(1); want <= 3; want++ {
+			if err := r.Next(dest); err != nil {
+				return err
+			}
+			n, err := r.RowNumber()
+			if err != nil {
+				return err
+			}
+			if n != want {
+				t.Errorf("expect row number %d, but got %d", want, n)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMSSQLStmtUseAfterClose(t *testing.T) {
+	dc, err := drv.Open(newConnParams().makeODBCConnectionString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dc.Close()
+
+	st, err := dc.Prepare("waitfor delay '00:00:00.100'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := st.Exec(nil)
+			if err != nil && err != errStmtClosed {
+				t.Errorf("unexpected Exec error: %v", err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := st.Close(); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	if _, err := st.Exec(nil); err != errStmtClosed {
+		t.Errorf("Exec on closed statement: expected %v, but got %v", errStmtClosed, err)
+	}
+	if _, err := st.Query(nil); err != errStmtClosed {
+		t.Errorf("Query on closed statement: expected %v, but got %v", errStmtClosed, err)
+	}
+	if err := st.(*Stmt).Cancel(); err != errStmtClosed {
+		t.Errorf("Cancel on closed statement: expected %v, but got %v", errStmtClosed, err)
+	}
+}
+
+func TestMSSQLDisableConnectionPooling(t *testing.T) {
+	if err := SetConnectionPooling(false); err != nil {
+		t.Fatal(err)
+	}
+	defer SetConnectionPooling(true)
+
+	_, baseConns, _ := drv.Counts()
+	params := newConnParams()
+	params["app"] = "go_no_pooling"
+	db, sc, err := mssqlConnectWithParams(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxIdleConns(0)
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	closeDB(t, db, sc, sc)
+	if _, conns, _ := drv.Counts(); conns != baseConns {
+		t.Errorf("expected %d connections, but got %d", baseConns, conns)
+	}
+
+	// connection must be disconnected, not returned to ODBC pool
+	db2, sc2, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db2, sc2, sc2)
+	var n int
+	err = db2.QueryRow("select count(*) from sys.dm_exec_sessions where program_name = 'go_no_pooling'").Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("expected no sessions left, but found %d", n)
+	}
+}
+
+func TestMSSQLExtractConnOptions(t *testing.T) {
+	var tests = []struct {
+		s, rest string
+		opts    connOptions
+	}{
+		{"dsn=mydsn", "dsn=mydsn", connOptions{}},
+		{"dsn=mydsn;decimal=float", "dsn=mydsn", connOptions{}},
+		{"Decimal=String;driver={SQL Server};pwd={a;b}", "driver=SQL Server;pwd={a;b}", connOptions{decimalAsString: true}},
+		{"dsn=mydsn;decimal=numeric", "dsn=mydsn", connOptions{decimalAsNumeric: true}},
+		{"dsn=mydsn;Date=Civil", "dsn=mydsn", connOptions{civilDate: true}},
+		{"dsn=mydsn;name_buffer_size=300;max_bind_width=4000", "dsn=mydsn", connOptions{nameBufSize: 300, bindWidth: 4000}},
+		{"dsn=mydsn;describe_params=false", "dsn=mydsn", connOptions{noDescribeParam: true}},
+		{"dsn=mydsn;Describe_Params=TRUE", "dsn=mydsn", connOptions{}},
+		{"dsn=mydsn;text_mode=true", "dsn=mydsn", connOptions{textMode: true}},
+		{"dsn=mydsn;read_only_check=true", "dsn=mydsn", connOptions{readOnlyCheck: true}},
+		{"dsn=mydsn;char=string", "dsn=mydsn", connOptions{charAsString: true}},
+		{"dsn=mydsn;trim_char=true", "dsn=mydsn", connOptions{trimChar: true}},
+		{"dsn=mydsn;Async=True", "dsn=mydsn", connOptions{async: true}},
+		{"dsn=mydsn;packet_size=32767", "dsn=mydsn", connOptions{packetSize: 32767}},
+		{"dsn=mydsn;max_data_size=1048576", "dsn=mydsn", connOptions{maxDataSize: 1 << 20}},
+		{"Driver={ODBC Driver 18 for SQL Server};ApplicationIntent=ReadOnly", "Driver={ODBC Driver 18 for SQL Server};ApplicationIntent=ReadOnly", connOptions{}},
+		{"dsn=mydsn;connect_retries=3;connect_retry_delay=500ms", "dsn=mydsn", connOptions{connectRetries: 3, connectRetryDelay: 500 * time.Millisecond}},
+		{"dsn=mydsn;ping_query={select 1 from dual}", "dsn=mydsn", connOptions{pingQuery: "select 1 from dual"}},
+	}
+	for _, test := range tests {
+		rest, opts, err := extractConnOptions(test.s)
+		if err != nil {
+			t.Errorf("extractConnOptions(%q) failed: %v", test.s, err)
+			continue
+		}
+		if rest != test.rest || opts != test.opts {
+			t.Errorf("extractConnOptions(%q): expect %q %+v, but got %q %+v", test.s, test.rest, test.opts, rest, opts)
+		}
+	}
+	for _, s := range []string{"dsn=mydsn;decimal=double", "dsn=mydsn;max_bind_width=0", "name_buffer_size=abc", "describe_params=no", "char=text", "packet_size=100", "packet_size=65536", "max_data_size=0", "trim_char=yes", "async=1", "connect_retries=-1", "connect_retry_delay=1", "connect_retry_delay=0s", "ping_query= "} {
+		if _, _, err := extractConnOptions(s); err == nil {
+			t.Errorf("extractConnOptions(%q) should fail, but succeeded", s)
+		}
+	}
+}
+
+func TestMSSQLErrorRecords(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	db.Exec("drop table dbo.temp")
+	exec(t, db, "create table dbo.temp (id int primary key)")
+	defer exec(t, db, "drop table dbo.temp")
+	exec(t, db, "insert into dbo.temp values (1)")
+
+	// SQL Server reports constraint violation followed
+	// by "The statement has been terminated." message.
+	_, err = db.Exec("insert into dbo.temp values (1)")
+	e, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("expect *Error, but got %T: %v", err, err)
+	}
+	rs := e.Records()
+	if len(rs) < 2 {
+		t.Fatalf("expect at least 2 diagnostic records, but got %v", rs)
+	}
+	if rs[0].State != "23000" || rs[0].NativeError != 2627 {
+		t.Errorf("expect first record to be primary key violation, but got %v", rs[0])
+	}
+	if !strings.Contains(rs[1].Message, "terminated") {
+		t.Errorf("expect second record to say that statement is terminated, but got %v", rs[1])
+	}
+	rs[0].State = "changed"
+	if e.Diag[0].State != "23000" {
+		t.Error("Records does not return copy of diagnostic records")
+	}
+}
+
+func TestMSSQLQuoteIdentifier(t *testing.T) {
+	var tests = []struct {
+		q, name, want string
+	}{
+		{`"`, "t", `"t"`},
+		{`"`, `my "table"`, `"my ""table"""`},
+		{"`", "a`b", "`a``b`"},
+	}
+	for _, test := range tests {
+		got, err := quoteIdentifier(test.q, test.name)
+		if err != nil {
+			t.Errorf("quoteIdentifier(%q, %q) failed: %v", test.q, test.name, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("quoteIdentifier(%q, %q): expect %q, but got %q", test.q, test.name, test.want, got)
+		}
+	}
+	for _, test := range []struct{ q, name string }{{" ", "t"}, {"", "t"}, {`"`, ""}, {`"`, "a\x00b"}} {
+		if _, err := quoteIdentifier(test.q, test.name); err == nil {
+			t.Errorf("quoteIdentifier(%q, %q) should fail, but succeeded", test.q, test.name)
+		}
+	}
+
+	dc, err := drv.Open(newConnParams().makeODBCConnectionString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dc.Close()
+	c := dc.(*Conn)
+	name := `a "b" ]c`
+	q, err := c.QuoteIdentifier(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := c.Prepare("select 1 as " + q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	rows, err := st.Query(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	if cols := rows.Columns(); len(cols) != 1 || cols[0] != name {
+		t.Errorf("expect column %q, but got %q", name, cols)
+	}
+}
+
+func TestMSSQLConnectRetryCancel(t *testing.T) {
+	// nothing listens on port 1, so every attempt fails with
+	// network error, and is retried after long delay
+	params := newConnParams()
+	params["server"] = "127.0.0.1,1"
+	params["connect_retries"] = "3"
+	params["connect_retry_delay"] = "1m"
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err := drv.open(ctx, params.makeODBCConnectionString(), 0, "")
+	if err == nil {
+		t.Fatal("connection to closed port should fail, but succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 30*time.Second {
+		t.Errorf("connection retries were not stopped by done context: %v", elapsed)
+	}
+}
+
+func TestMSSQLIsTransientConnectError(t *testing.T) {
+	var tests = []struct {
+		states []string
+		want   bool
+	}{
+		{[]string{"08001"}, true},
+		{[]string{"01000", "08S01"}, true},
+		{[]string{"HYT00"}, true},
+		{[]string{"28000"}, false},
+		{[]string{"08001", "28000"}, false},
+		{[]string{"42000"}, false},
+	}
+	for _, test := range tests {
+		e := &Error{APIName: "SQLDriverConnect"}
+		for _, s := range test.states {
+			e.Diag = append(e.Diag, DiagRecord{State: s})
+		}
+		if got := isTransientConnectError(e); got != test.want {
+			t.Errorf("isTransientConnectError(%v): expect %v, but got %v", test.states, test.want, got)
+		}
+	}
+	if isTransientConnectError(errors.New("some error")) {
+		t.Error("non ODBC error is reported as transient")
+	}
+
+	// broken connection is reported as driver.ErrBadConn, but
+	// only after all records are checked
+	e := &Error{APIName: "SQLDriverConnect", Diag: []DiagRecord{{State: "08S01"}, {State: "28000"}}}
+	if isTransientConnectError(e) {
+		t.Error("authentication failure after 08S01 is reported as transient")
+	}
+	if err := badConnError(e); err != driver.ErrBadConn {
+		t.Errorf("expect %v, but got %v", driver.ErrBadConn, err)
+	}
+}
+
+func TestMSSQLConnectRetry(t *testing.T) {
+	params := newConnParams()
+	address, err := params.getConnAddress()
+	if err != nil {
+		t.Skipf("Skipping test: %v", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	err = params.updateConnAddress(ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// first connection is reset during handshake, the rest are proxied
+	proxy := new(tcpProxy)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		c.(*net.TCPConn).SetLinger(0)
+		c.Close()
+		proxy.run(ln, address)
+	}()
+	defer proxy.pause()
+
+	params["connect_retries"] = "2"
+	params["connect_retry_delay"] = "10ms"
+	dc, err := drv.Open(params.makeODBCConnectionString())
+	if err != nil {
+		t.Fatalf("connection reset during handshake was not retried: %v", err)
+	}
+	dc.Close()
+}
+
+func TestMSSQLExecStoredProcedure(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	db.Exec("drop procedure dbo.temp")
+	exec(t, db, `
+create procedure dbo.temp
+	@a	int,
+	@b	int
+as
+begin
+	return @a + @b
+end
+`)
+	qry := `
+declare @ret int
+exec @ret = dbo.temp @a = ?, @b = ?
+select @ret
+`
+	var ret int64
+	if err := db.QueryRow(qry, 2, 3).Scan(&ret); err != nil {
+		t.Fatal(err)
+	}
+	if ret != 5 {
+		t.Fatalf("unexpected return value: should=5, is=%v", ret)
+	}
+	exec(t, db, `drop procedure dbo.temp`)
+}
+
+func TestMSSQLParseProcCall(t *testing.T) {
+	tests := []struct {
+		query    string
+		name     string
+		ordinals []int
+		ok       bool
+	}{
+		{"{call dbo.temp(?, ?)}", "dbo.temp", []int{1, 2}, true},
+		{" { ? = call temp (?) } ", "temp", []int{0, 1}, true},
+		{"{CALL temp}", "temp", nil, true},
+		{"{call temp(1, ?, 'a,?', ?)}", "temp", []int{2, 4}, true},
+		{"{call temp(?+1)}", "", nil, false},
+		{"exec temp ?", "", nil, false},
+		{"{fn now()}", "", nil, false},
+	}
+	for _, test := range tests {
+		name, ordinals, ok := parseProcCall(test.query)
+		if ok != test.ok || name != test.name || fmt.Sprint(ordinals) != fmt.Sprint(test.ordinals) {
+			t.Errorf("%q: got (%q, %v, %v), want (%q, %v, %v)", test.query,
+				name, ordinals, ok, test.name, test.ordinals, test.ok)
+		}
+	}
+}
+
+func TestMSSQLStoredProcedureOutParams(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	db.Exec("drop procedure dbo.temp")
+	exec(t, db, `
+create procedure dbo.temp
+	@a	int,
+	@b	int output,
+	@s	nvarchar(20) output
+as
+begin
+	set @b = @a + @b
+	set @s = 'sum is ' + cast(@b as nvarchar(10))
+	return 7
+end
+`)
+	defer exec(t, db, `drop procedure dbo.temp`)
+
+	// directions are discovered from procedure definition
+	var ret, b int64
+	var s string
+	b = 3
+	_, err = db.Exec("{? = call dbo.temp(?, ?, ?)}", &ret, 2, &b, &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret != 7 || b != 5 || s != "sum is 5" {
+		t.Errorf("unexpected results: ret=%v b=%v s=%q, expected 7, 5 and \"sum is 5\"", ret, b, s)
+	}
+
+	// sql.Out works for any statement
+	b = 10
+	_, err = db.Exec("{call dbo.temp(?, ?, ?)}", 1, sql.Out{Dest: &b, In: true}, sql.Out{Dest: &s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b != 11 || s != "sum is 11" {
+		t.Errorf("unexpected results: b=%v s=%q, expected 11 and \"sum is 11\"", b, s)
+	}
+}
+
+func TestMSSQLQueryOutParams(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	db.Exec("drop procedure dbo.temp")
+	exec(t, db, `
+create procedure dbo.temp
+	@n	int,
+	@sum	int output,
+	@s	nvarchar(20) output
+as
+begin
+	set nocount on
+	select number from master.dbo.spt_values where type = 'P' and number between 1 and @n order by number
+	select @sum = sum(number) from master.dbo.spt_values where type = 'P' and number between 1 and @n
+	set @s = 'sum is ' + cast(@sum as nvarchar(10))
+end
+`)
+	defer exec(t, db, `drop procedure dbo.temp`)
+
+	var sum int64
+	var s string
+	rows, err := db.Query("{call dbo.temp(?, ?, ?)}", 4, sql.Out{Dest: &sum}, sql.Out{Dest: &s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []int
+	for rows.Next() {
+		var n int
+		if err := rows.Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, n)
+	}
+	if fmt.Sprint(got) != "[1 2 3 4]" {
+		t.Errorf("unexpected result set %v, expected [1 2 3 4]", got)
+	}
+	for rows.NextResultSet() {
+		for rows.Next() {
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if sum != 10 || s != "sum is 10" {
+		t.Errorf("unexpected results: sum=%v s=%q, expected 10 and \"sum is 10\"", sum, s)
+	}
+}
+
+func TestMSSQLTypedParam(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	tests := []struct {
+		arg  interface{}
+		want string
+	}{
+		{"abc", "nvarchar"},
+		{Typed{Value: "abc", SQLType: api.SQL_VARCHAR}, "varchar"},
+		{Typed{Value: 123, SQLType: api.SQL_SMALLINT}, "smallint"},
+		{Typed{Value: "1.5", SQLType: api.SQL_DECIMAL, Size: 10, Decimal: 2}, "decimal"},
+	}
+	for _, test := range tests {
+		var typ string
+		err := db.QueryRow("select cast(sql_variant_property(?, 'BaseType') as varchar(20))", test.arg).Scan(&typ)
+		if err != nil {
+			t.Errorf("%v: %v", test.arg, err)
+			continue
+		}
+		if typ != test.want {
+			t.Errorf("%v: parameter bound as %q, want %q", test.arg, typ, test.want)
+		}
+	}
+}
+
+func TestMSSQLXMLParam(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	db.Exec("drop table dbo.temp")
+	exec(t, db, "create table dbo.temp (doc xml)")
+	defer exec(t, db, "drop table dbo.temp")
+
+	const doc = "<root>héllo</root>"
+	_, err = db.Exec("insert into dbo.temp (doc) values (?)", XML(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s string
+	err = db.QueryRow("select doc from dbo.temp").Scan(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != doc {
+		t.Errorf("expected %q, but got %q", doc, s)
+	}
+
+	_, err = db.Exec("insert into dbo.temp (doc) values (?)", XML("<root>"))
+	if err == nil {
+		t.Fatal("invalid xml document must be rejected")
+	}
+}
+
+func TestMSSQLSingleCharParam(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	db.Exec("drop table dbo.temp")
+	exec(t, db, `create table dbo.temp(name nvarchar(50), age int)`)
+
+	rows, err := db.Query("select age from dbo.temp where name=?", "v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+
+	exec(t, db, "drop table dbo.temp")
+}
+
+type tcpProxy struct {
+	mu      sync.Mutex
+	stopped bool
+	conns   []net.Conn
+}
+
+func (p *tcpProxy) run(ln net.Listener, remote string) {
+	for {
+		defer p.pause()
+		c1, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func(c1 net.Conn) {
+			defer c1.Close()
+
+			if p.paused() {
+				return
+			}
+
+			p.addConn(c1)
+
+			c2, err := net.Dial("tcp", remote)
+			if err != nil {
+				panic(err)
+			}
+			p.addConn(c2)
+			defer c2.Close()
+
+			go func() {
+				io.Copy(c2, c1)
+			}()
+			io.Copy(c1, c2)
+		}(c1)
+	}
+}
+
+func (p *tcpProxy) pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stopped = true
+	for _, c := range p.conns {
+		c.Close()
+	}
+	p.conns = p.conns[:0]
+}
+
+func (p *tcpProxy) paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stopped
+}
+
+func (p *tcpProxy) addConn(c net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.conns = append(p.conns, c)
+}
+
+func (p *tcpProxy) restart() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stopped = false
+}
+
+func TestMSSQLReconnect(t *testing.T) {
+	params := newConnParams()
+	address, err := params.getConnAddress()
+	if err != nil {
+		t.Skipf("Skipping test: %v", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	err = params.updateConnAddress(ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proxy := new(tcpProxy)
+	go proxy.run(ln, address)
+
+	db, sc, err := mssqlConnectWithParams(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	testConn := func() error {
+		var n int64
+		err := db.QueryRow("select count(*) from dbo.temp").Scan(&n)
+		if err != nil {
+			return err
+		}
+		if n != 1 {
+			return fmt.Errorf("unexpected return value: should=1, is=%v", n)
+		}
+		return nil
+	}
+
+	db.Exec("drop table dbo.temp")
+	exec(t, db, `create table dbo.temp (name varchar(50))`)
+	exec(t, db, `insert into dbo.temp (name) values ('alex')`)
+
+	err = testConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proxy.pause()
+	time.Sleep(100 * time.Millisecond)
+
+	err = testConn()
+	if err == nil {
+		t.Fatal("database IO should fail, but succeeded")
+	}
+
+	proxy.restart()
+
+	err = testConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exec(t, db, "drop table dbo.temp")
+}
+
+func TestMSSQLMarkTxBadConn(t *testing.T) {
+	params := newConnParams()
+
+	address, err := params.getConnAddress()
+	if err != nil {
+		t.Skipf("Skipping test: %v", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	err = params.updateConnAddress(ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proxy := new(tcpProxy)
+	go proxy.run(ln, address)
+
+	testFn := func(endTx func(driver.Tx) error, nextFn func(driver.Conn) error) {
+		proxy.restart()
+
+		cc, sc := drv.Stats.ConnCount, drv.Stats.StmtCount
+		defer func() {
+			if should, is := sc, drv.Stats.StmtCount; should != is {
+				t.Errorf("leaked statement, should=%d, is=%d", should, is)
+			}
+			if should, is := cc, drv.Stats.ConnCount; should != is {
+				t.Errorf("leaked connection, should=%d, is=%d", should, is)
+			}
+		}()
+
+		dc, err := drv.Open(params.makeODBCConnectionString())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			if err := dc.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}()
+
+		driverExec(nil, dc, "drop table dbo.temp")
+		driverExec(t, dc, `create table dbo.temp (name varchar(50))`)
+
+		tx, err := dc.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		driverExec(t, dc, `insert into dbo.temp (name) values ('alex')`)
+
+		proxy.pause()
+		time.Sleep(100 * time.Millisecond)
+
+		// the connection is broken, ending the transaction should fail
+		if err := endTx(tx); err == nil {
+			t.Fatal("unexpected success, expected error")
+		}
+
+		// database/sql might return the broken driver.Conn to the pool in
+		// that case the next operation must fail.
+		if err := nextFn(dc); err == nil {
+			t.Fatal("unexpected success, expected error")
+		}
+	}
+
+	beginFn := func(dc driver.Conn) error {
+		tx, err := dc.Begin()
+		if err != nil {
+			return err
+		}
+		tx.Rollback()
+		return nil
+	}
+
+	prepareFn := func(dc driver.Conn) error {
+		st, err := dc.Prepare(`insert into dbo.temp (name) values ('alex')`)
+		if err != nil {
+			return err
+		}
+		st.Close()
+		return nil
+	}
+
+	// Test all the permutations.
+	for _, endTx := range []func(driver.Tx) error{
+		driver.Tx.Commit,
+		driver.Tx.Rollback,
+	} {
+		for _, nextFn := range []func(driver.Conn) error{
+			beginFn,
+			prepareFn,
+		} {
+			testFn(endTx, nextFn)
+		}
+	}
+}
+
+func TestMSSQLMarkBeginBadConn(t *testing.T) {
+	params := newConnParams()
+
+	testFn := func(label string, nextFn func(driver.Conn) error) {
+		cc, sc := drv.Stats.ConnCount, drv.Stats.StmtCount
+		defer func() {
+			if should, is := sc, drv.Stats.StmtCount; should != is {
+				t.Errorf("leaked statement, should=%d, is=%d", should, is)
+			}
+			if should, is := cc, drv.Stats.ConnCount; should != is {
+				t.Errorf("leaked connection, should=%d, is=%d", should, is)
+			}
+		}()
+
+		dc, err := drv.Open(params.makeODBCConnectionString())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			if err := dc.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}()
+
+		driverExec(nil, dc, "drop table dbo.temp")
+		driverExec(t, dc, `create table dbo.temp (name varchar(50))`)
+
+		// force an error starting a transaction
+		func() {
+			c := dc.(*Conn)
+			c.hooks.setAutoCommit = func(uintptr) error { return errors.New("cannot start tx") }
+			defer func() { c.hooks.setAutoCommit = nil }()
+
+			if _, err := dc.Begin(); err == nil {
+				t.Fatal("unexpected success, expected error")
+			}
+		}()
+
+		// database/sql might return the broken driver.Conn to the pool. The
+		// next operation on the driver connection must return
+		// driver.ErrBadConn to prevent the bad connection from getting used
+		// again.
+		if should, is := driver.ErrBadConn, nextFn(dc); should != is {
+			t.Errorf("%s: should=\"%v\", is=\"%v\"", label, should, is)
+		}
+	}
+
+	beginFn := func(dc driver.Conn) error {
+		tx, err := dc.Begin()
+		if err != nil {
+			return err
+		}
+		tx.Rollback()
+		return nil
+	}
+
+	prepareFn := func(dc driver.Conn) error {
+		st, err := dc.Prepare(`insert into dbo.temp (name) values ('alex')`)
+		if err != nil {
+			return err
+		}
+		st.Close()
+		return nil
+	}
+
+	// Test all the permutations.
+	for _, next := range []struct {
+		label string
+		fn    func(driver.Conn) error
+	}{
+		{"begin", beginFn},
+		{"prepare", prepareFn},
+	} {
+		testFn(next.label, next.fn)
+	}
+}
+
+func testMSSQLNextResultSet(t *testing.T, verifyBatch func(rows *sql.Rows)) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	db.Exec("drop table dbo.temp")
+	exec(t, db, `create table dbo.temp (name varchar(50))`)
+	exec(t, db, `insert into dbo.temp (name) values ('russ')`)
+	exec(t, db, `insert into dbo.temp (name) values ('brad')`)
+
+	rows, err := db.Query(`
+select name from dbo.temp where name = 'russ';
+select name from dbo.temp where name = 'brad';
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	verifyBatch(rows)
+
+	exec(t, db, "drop table dbo.temp")
+}
+
+func TestMSSQLNextResultSet(t *testing.T) {
+	checkName := func(rows *sql.Rows, name string) {
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				t.Fatalf("executing Next for %q failed: %v", name, err)
+			}
+			t.Fatalf("checking %q: at least one row expected", name)
+		}
+		var have string
+		err := rows.Scan(&have)
+		if err != nil {
+			t.Fatalf("executing Scan for %q failed: %v", name, err)
+		}
+		if name != have {
+			t.Fatalf("want %q, but %q found", name, have)
+		}
+	}
+	testMSSQLNextResultSet(t,
+		func(rows *sql.Rows) {
+			checkName(rows, "russ")
+			if !rows.NextResultSet() {
+				if err := rows.Err(); err != nil {
+					t.Fatal(err)
+				}
+				t.Fatal("more result sets expected")
+			}
+			checkName(rows, "brad")
+			if isFreeTDS() { // not sure why it does not work on FreeTDS
+				t.Log("skipping broken part of the test on FreeTDS")
+				return
+			}
+			if rows.NextResultSet() {
+				t.Fatal("unexpected result set found")
+			} else if err := rows.Err(); err != nil {
+				t.Fatal(err)
+			}
+		})
+}
+
+func TestMSSQLNextResultSetWithDifferentColumnsInResultSets(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+	rows, err := db.Query("select 1 select 2,3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		t.Fatal("expected at least 1 result")
+	}
+	var v1, v2 int
+	err = rows.Scan(&v1)
+	if err != nil {
+		t.Fatalf("unable to scan select 1 underlying error: %v", err)
+	}
+	if v1 != 1 {
+		t.Fatalf("expected: %v got %v", 1, v1)
+	}
+	if rows.Next() {
+		t.Fatal("unexpected row")
+	}
+	if !rows.NextResultSet() {
+		t.Fatal("expected another result set")
+	}
+	if !rows.Next() {
+		t.Fatal("expected a single row")
+	}
+	err = rows.Scan(&v1, &v2)
+	if err != nil {
+		t.Fatalf("unable to scan select 2,3 underlying error: %v", err)
+	}
+	if v1 != 2 || v2 != 3 {
+		t.Fatalf("got wrong values expected v1=%v v2=%v. got v1=%v v2=%v", 2, 3, v1, v2)
+	}
+
+}
+
+func TestMSSQLForEachResultSet(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	rows, err := db.Query(`
+select 1 as a
+select 2 as b, 'x' as c
+select 3 as d where 1 = 0
+select 4 as e, 5 as f, 6 as g union all select 7, 8, 9`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var got []string
+	err = ForEachResultSet(rows, func(columns []string, rows *sql.Rows) error {
+		s := strings.Join(columns, ",") + ":"
+		for rows.Next() {
+			vals := make([]interface{}, len(columns))
+			ptrs := make([]interface{}, len(columns))
+			for i := range vals {
+				ptrs[i] = &vals[i]
+			}
+			if err := rows.Scan(ptrs...); err != nil {
+				return err
+			}
+			s += fmt.Sprintf("%v", vals)
+		}
+		got = append(got, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "a:[1] b,c:[2 [120]] d: e,f,g:[4 5 6][7 8 9]"
+	if s := strings.Join(got, " "); s != want {
+		t.Errorf("expect %q, but got %q", want, s)
+	}
+
+	// error returned by fn stops iteration
+	rows, err = db.Query("select 1 select 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	errStop := errors.New("stop")
+	n := 0
+	err = ForEachResultSet(rows, func(columns []string, rows *sql.Rows) error {
+		n++
+		return errStop
+	})
+	if err != errStop {
+		t.Errorf("expect %v error, but got %v", errStop, err)
+	}
+	if n != 1 {
+		t.Errorf("expect fn to be called once, but called %d times", n)
+	}
+}
+
+func TestMSSQLNextResultSetSameShape(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	rows, err := db.Query(`
+select 1 as a, cast('x' as varchar(5)) as b
+select 2 as c, cast('y' as varchar(5)) as d
+select 3 as e, cast('z' as varchar(5)) as f
+select cast('long' as varchar(10)) as g`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for i, want := range []struct {
+		cols string
+		n    int
+		s    string
+	}{
+		{"[a b]", 1, "x"},
+		{"[c d]", 2, "y"},
+		{"[e f]", 3, "z"},
+	} {
+		if i > 0 && !rows.NextResultSet() {
+			t.Fatalf("expected result set %d", i)
+		}
+		cols, err := rows.Columns()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(cols) != want.cols {
+			t.Errorf("result set %d: expected columns %v, but got %v", i, want.cols, cols)
+		}
+		if !rows.Next() {
+			t.Fatalf("result set %d: expected a row", i)
+		}
+		var n int
+		var s string
+		if err := rows.Scan(&n, &s); err != nil {
+			t.Fatal(err)
+		}
+		if n != want.n || s != want.s {
+			t.Errorf("result set %d: expected %v %q, but got %v %q", i, want.n, want.s, n, s)
+		}
+	}
+	if !rows.NextResultSet() || !rows.Next() {
+		t.Fatal("expected last result set with a row")
+	}
+	var s string
+	if err := rows.Scan(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s != "long" {
+		t.Errorf("expected \"long\", but got %q", s)
+	}
+}
+
+func TestMSSQLHasNextResultSet(t *testing.T) {
+	checkName := func(rows *sql.Rows, name string) {
+		var reccount int
+		for rows.Next() { // reading till the end of data set to trigger call into HasNextResultSet
+			var have string
+			err := rows.Scan(&have)
+			if err != nil {
+				t.Fatalf("executing Scan for %q failed: %v", name, err)
+			}
+			if name != have {
+				t.Fatalf("want %q, but %q found", name, have)
+			}
+			reccount++
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatalf("executing Next for %q failed: %v", name, err)
+		}
+		if reccount != 1 {
+			t.Fatalf("checking %q: expected 1 row returned, but %v found", name, reccount)
+		}
+	}
+	testMSSQLNextResultSet(t,
+		func(rows *sql.Rows) {
+			checkName(rows, "russ")
+			if !rows.NextResultSet() {
+				if err := rows.Err(); err != nil {
 					t.Fatal(err)
 				}
-			}()
-		}
-		// test 1 Stmt and many simultaneous Query's
-		eof := fmt.Errorf("eof")
-		ch := make(map[string]chan error)
-		for dept, people := range staff {
-			c := make(chan error)
-			go func(c chan error, dept string, people []string) {
-				c <- nil
-				// NOTE(brainman): this could actually re-prepare since
-				// we are running it simultaneously in multiple goroutines
-				r, err := s.Query(dept)
-				if err != nil {
-					c <- fmt.Errorf("%v", err)
-					return
-				}
-				defer r.Close()
-				i := 0
-				c <- nil
-				for r.Next() {
-					var is string
-					c <- nil
-					err = r.Scan(&is)
-					if err != nil {
-						c <- fmt.Errorf("%v", err)
-						return
-					}
-					c <- nil
-					if people[i] != is {
-						c <- fmt.Errorf("expected %v, but received %v", people[i], is)
-						return
-					}
-					i++
-				}
-				err = r.Err()
-				if err != nil {
-					c <- fmt.Errorf("%v", err)
-					return
-				}
-				c <- eof
-			}(c, dept, people)
-			ch[dept] = c
-		}
-		for len(ch) > 0 {
-			for dept, c := range ch {
-				err := <-c
-				if err != nil {
-					if err != eof {
-						t.Errorf("dept=%v: %v", dept, err)
-					}
-					delete(ch, dept)
+				t.Fatal("more result sets expected")
+			}
+			checkName(rows, "brad")
+			if rows.NextResultSet() {
+				t.Fatal("unexpected result set found")
+			} else {
+				if err := rows.Err(); err != nil {
+					t.Fatal(err)
 				}
 			}
-		}
-	}()
-
-	exec(t, db, "drop table dbo.temp")
+		})
 }
 
-func TestMSSQLIssue5(t *testing.T) {
-	testingIssue5 = true
-	defer func() {
-		testingIssue5 = false
-	}()
+func TestMSSQLIssue127(t *testing.T) {
 	db, sc, err := mssqlConnect()
 	if err != nil {
 		t.Fatal(err)
 	}
+	defer closeDB(t, db, sc, sc)
 
-	const nworkers = 8
+	db.Exec("drop table dbo.temp")
+	exec(t, db, "create table dbo.temp (id int, a varchar(255))")
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stmt, err := tx.Prepare(`
+DECLARE @id INT, @a VARCHAR(255)
+SELECT @id = ?, @a = ?
+UPDATE dbo.temp SET a = @a WHERE id = @id
+IF @@ROWCOUNT = 0
+  INSERT INTO dbo.temp (id, a) VALUES (@id, @a)
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = stmt.Exec(1, "test"); err != nil {
+		t.Errorf("Failed to insert record with ID 1: %s", err)
+	}
+	if _, err = stmt.Exec(1, "test2"); err != nil {
+		t.Errorf("Failed to update record with ID 1: %s", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMSSQLBatchRowCounts(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
 	defer closeDB(t, db, sc, sc)
 
 	db.Exec("drop table dbo.temp")
-	exec(t, db, `
-		create table dbo.temp (
-			id int,
-			value int,
-			constraint [pk_id] primary key ([id])
-		)
-	`)
+	exec(t, db, "create table dbo.temp (id int, a varchar(255))")
+	defer db.Exec("drop table dbo.temp")
 
-	var count int32
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
 
-	runCycle := func(waitch <-chan struct{}, errch chan<- error) (reterr error) {
-		defer func() {
-			errch <- reterr
-		}()
-		stmt, err := db.Prepare("insert into dbo.temp (id, value) values (?, ?)")
+	err = conn.Raw(func(dc interface{}) error {
+		st, err := dc.(*Conn).Prepare(`
+INSERT INTO dbo.temp (id, a) VALUES (1, 'a'), (2, 'b'), (3, 'c');
+UPDATE dbo.temp SET a = 'd' WHERE id = 1;
+DELETE FROM dbo.temp WHERE id > 1;
+`)
 		if err != nil {
-			return fmt.Errorf("Prepare failed: %v", err)
+			return err
 		}
-		defer stmt.Close()
-		errch <- nil
-		<-waitch
-		for {
-			i := (int)(atomic.AddInt32(&count, 1))
-			_, err := stmt.Exec(i, i)
-			if err != nil {
-				return fmt.Errorf("Exec failed i=%d: %v", i, err)
-			}
-			runtime.GC()
-			if i >= 100 {
-				break
-			}
+		defer st.Close()
+		r, err := st.Exec(nil)
+		if err != nil {
+			return err
 		}
-		return
+		want := []int64{3, 1, 2}
+		got := r.(*Result).RowCounts()
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("expect row counts %v, but got %v", want, got)
+		}
+		if n, _ := r.RowsAffected(); n != 6 {
+			t.Errorf("expect 6 rows affected, but got %d", n)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
 	}
+}
 
-	waitch := make(chan struct{})
-	errch := make(chan error, nworkers)
-	for i := 0; i < nworkers; i++ {
-		go runCycle(waitch, errch)
+func TestMSSQLLastInsertId(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
 	}
-	for i := 0; i < nworkers; i++ {
-		if err := <-errch; err != nil {
-			t.Error(err)
+	defer closeDB(t, db, sc, sc)
+
+	db.Exec("drop table dbo.temp")
+	exec(t, db, "create table dbo.temp (id int identity, a varchar(255))")
+	defer db.Exec("drop table dbo.temp")
+
+	for _, query := range []string{
+		"insert into dbo.temp (a) values ('a')",
+		"update dbo.temp set a = 'b'",
+	} {
+		r, err := db.Exec(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.LastInsertId(); !errors.Is(err, ErrLastInsertIdUnsupported) {
+			t.Errorf("%q: expect ErrLastInsertIdUnsupported, but got %v", query, err)
 		}
 	}
-	if t.Failed() {
-		return
+}
+
+func TestMSSQLQueryContextTimeout(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
 	}
-	close(waitch)
-	for i := 0; i < nworkers; i++ {
-		if err := <-errch; err != nil {
-			t.Fatal(err)
-		}
+	defer closeDB(t, db, sc, sc)
+
+	contextTimeout := time.Millisecond * 500
+	queryWaitFor := time.Second * 1
+
+	ctx, cancel := context.WithTimeout(context.Background(), contextTimeout)
+	defer cancel()
+
+	start := time.Now()
+	_, err = db.QueryContext(ctx, "WAITFOR DELAY '00:01';")
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Fatal("Unexpected success, expected error")
+	}
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Unexpected error value: should=%s, is=%s", context.DeadlineExceeded, err)
+	}
+	if elapsed > queryWaitFor {
+		t.Fatalf("Unexpected query duration: should=>%s, is=%s", queryWaitFor, elapsed)
+	}
+	if elapsed < contextTimeout {
+		t.Fatalf("Query did not delay: should=<%s, is=%s", contextTimeout, elapsed)
+	}
+}
+
+func TestMSSQLQueryContextAsync(t *testing.T) {
+	params := newConnParams()
+	params["async"] = "true"
+	db, sc, err := mssqlConnectWithParams(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	db.SetMaxOpenConns(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = db.QueryContext(ctx, "WAITFOR DELAY '00:01';")
+	elapsed := time.Since(start)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Unexpected error value: should=%s, is=%v", context.DeadlineExceeded, err)
+	}
+	if elapsed > 5*time.Second {
+		t.Fatalf("Unexpected query duration: %s", elapsed)
+	}
+
+	// the same connection is used after cancelled statement
+	var n int
+	err = db.QueryRowContext(context.Background(), "select ?", 123).Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 123 {
+		t.Fatalf("expected 123, but got %d", n)
+	}
+}
+
+// WAITFOR is cancelled promptly, so QueryContext sees the statement
+// finish within queryCancelWait, and connection can be used and
+// closed right away.
+func TestMSSQLQueryContextTimeoutThenClose(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err = conn.QueryContext(ctx, "WAITFOR DELAY '00:01';")
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Unexpected error value: should=%s, is=%v", context.DeadlineExceeded, err)
+	}
+	var n int
+	if err := conn.QueryRowContext(context.Background(), "select 1").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("unexpected value %d", n)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Connection is returned to the pool, not discarded,
+// when QueryContext statement is cancelled cleanly.
+func TestMSSQLQueryContextTimeoutKeepsConn(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+
+	var spid1, spid2 int
+	if err := db.QueryRow("select @@spid").Scan(&spid1); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err = db.QueryContext(ctx, "WAITFOR DELAY '00:01';")
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Unexpected error value: should=%s, is=%v", context.DeadlineExceeded, err)
+	}
+	if err := db.QueryRow("select @@spid").Scan(&spid2); err != nil {
+		t.Fatal(err)
+	}
+	if spid1 != spid2 {
+		t.Fatalf("connection is not reused after cancelled query: session %d, then %d", spid1, spid2)
 	}
-	// TODO: maybe I should verify dbo.temp records here
-
-	exec(t, db, "drop table dbo.temp")
 }
 
-func TestMSSQLDeleteNonExistent(t *testing.T) {
+func TestMSSQLQueryContextCancel(t *testing.T) {
 	db, sc, err := mssqlConnect()
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer closeDB(t, db, sc, sc)
 
-	db.Exec("drop table dbo.temp")
-	exec(t, db, "create table dbo.temp (name varchar(20))")
-	_, err = db.Exec("insert into dbo.temp (name) values ('alex')")
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cancel()
+	_, err = db.QueryContext(ctx, "WAITFOR DELAY '00:01';")
+
+	if err == nil {
+		t.Fatal("Unexpected success, expected error")
+	}
+	if err != context.Canceled {
+		t.Fatalf("Unexpected error value: should=%s, is=%s", context.Canceled, err)
+	}
+}
+
+func TestMSSQLStmtAttr(t *testing.T) {
+	dc, err := drv.Open(newConnParams().makeODBCConnectionString())
 	if err != nil {
 		t.Fatal(err)
 	}
+	defer dc.Close()
 
-	r, err := db.Exec("delete from dbo.temp where name = 'bob'")
+	ds, err := dc.Prepare("select 1")
 	if err != nil {
-		t.Fatalf("Exec failed: %v", err)
+		t.Fatal(err)
 	}
-	cnt, err := r.RowsAffected()
-	if err != nil {
-		t.Fatalf("RowsAffected failed: %v", err)
+	defer ds.Close()
+	st := ds.(*Stmt)
+
+	if err := st.SetAttr(api.SQL_ATTR_QUERY_TIMEOUT, 5); err != nil {
+		t.Fatal(err)
 	}
-	if cnt != 0 {
-		t.Fatalf("RowsAffected returns %d, but 0 expected", cnt)
+	checkTimeout := func() {
+		t.Helper()
+		v, err := st.GetAttr(api.SQL_ATTR_QUERY_TIMEOUT)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != 5 {
+			t.Errorf("expect query timeout of 5, but got %d", v)
+		}
 	}
+	checkTimeout()
 
-	exec(t, db, "drop table dbo.temp")
-}
-
-// https://github.com/alexbrainman/odbc/issues/14
-func TestMSSQLDatetime2Param(t *testing.T) {
-	db, sc, err := mssqlConnect()
+	// Query followed by Exec makes Stmt use new statement
+	// handle, but attributes must be preserved.
+	rows, err := st.Query(nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer closeDB(t, db, sc, sc)
-
-	if !is2008OrLater(db) {
-		t.Skip("skipping test; needs MS SQL Server 2008 or later")
+	dest := make([]driver.Value, 1)
+	for rows.Next(dest) == nil {
 	}
+	if _, err := st.Exec(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+	checkTimeout()
 
-	db.Exec("drop table dbo.temp")
-	exec(t, db, "create table dbo.temp (dt datetime2)")
-
-	expect := time.Date(2007, 5, 8, 12, 35, 29, 1234567e2, time.Local)
-	_, err = db.Exec("insert into dbo.temp (dt) values (?)", expect)
+	// Slice arguments are expanded into new statement,
+	// that must have the same attributes.
+	ds2, err := dc.Prepare("select id from (values (1), (2), (3)) as t(id) where id in (?)")
 	if err != nil {
 		t.Fatal(err)
 	}
-	var got time.Time
-	err = db.QueryRow("select top 1 dt from dbo.temp").Scan(&got)
+	defer ds2.Close()
+	st = ds2.(*Stmt)
+	if err := st.SetAttr(api.SQL_ATTR_MAX_ROWS, 1); err != nil {
+		t.Fatal(err)
+	}
+	rows, err = st.Query([]driver.Value{[]int{1, 2, 3}})
 	if err != nil {
 		t.Fatal(err)
 	}
-	if expect != got {
-		t.Fatalf("expect %v, but got %v", expect, got)
+	defer rows.Close()
+	n := 0
+	for rows.Next(dest) == nil {
+		n++
+	}
+	if n != 1 {
+		t.Errorf("expect 1 row limited by SQL_ATTR_MAX_ROWS, but got %d", n)
 	}
-
-	exec(t, db, "drop table dbo.temp")
 }
 
-// https://github.com/alexbrainman/odbc/issues/19
-func TestMSSQLMerge(t *testing.T) {
+func TestMSSQLMaxRows(t *testing.T) {
 	db, sc, err := mssqlConnect()
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer closeDB(t, db, sc, sc)
 
-	if !is2008OrLater(db) {
-		t.Skip("skipping test; needs MS SQL Server 2008 or later")
-	}
-
 	db.Exec("drop table dbo.temp")
+	exec(t, db, "create table dbo.temp (id int)")
+	defer exec(t, db, "drop table dbo.temp")
 	exec(t, db, `
-		create table dbo.temp (
-			id int not null,
-			name varchar(20),
-			constraint pk_temp primary key(id)
-		)
-	`)
-	for i := 0; i < 5; i++ {
-		_, err = db.Exec("insert into dbo.temp (id, name) values (?, ?)", i, fmt.Sprintf("gordon%d", i))
+declare @i int = 0
+while @i < 100
+begin
+	insert into dbo.temp (id) values (@i)
+	set @i = @i + 1
+end`)
+
+	count := func(ctx context.Context) int {
+		t.Helper()
+		rows, err := db.QueryContext(ctx, "select id from dbo.temp where id >= ?", 0)
 		if err != nil {
 			t.Fatal(err)
 		}
+		defer rows.Close()
+		n := 0
+		for rows.Next() {
+			n++
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	if n := count(WithMaxRows(context.Background(), 5)); n != 5 {
+		t.Errorf("expected 5 rows, but got %d", n)
+	}
+	if n := count(context.Background()); n != 100 {
+		t.Errorf("expected 100 rows, but got %d", n)
 	}
+	if n := count(WithMaxRows(context.Background(), -1)); n != 100 {
+		t.Errorf("expected negative limit to be ignored, but got %d rows", n)
+	}
+}
 
-	s, err := db.Prepare(`
-		merge into dbo.temp as dest
-		using ( values (?, ?) ) as src (id, name) on src.id = dest.id
-		when matched then update set dest.name = src.name
-		when not matched then insert values (src.id, src.name);
-	`)
+func TestMSSQLDescribeQuery(t *testing.T) {
+	db, sc, err := mssqlConnect()
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer s.Close()
+	defer closeDB(t, db, sc, sc)
 
-	var tests = []struct {
-		id   int
-		name string
-	}{
-		{id: 1, name: "new name1"},
-		{id: 8, name: "hohoho"},
-	}
-	for _, test := range tests {
-		_, err = s.Exec(test.id, test.name)
-		if err != nil {
-			t.Fatal(err)
-		}
+	db.Exec("drop table dbo.temp")
+	exec(t, db, "create table dbo.temp (id int not null, name nvarchar(20) null, amount decimal(10,2))")
+	defer exec(t, db, "drop table dbo.temp")
+
+	dc, err := drv.Open(newConnParams().makeODBCConnectionString())
+	if err != nil {
+		t.Fatal(err)
 	}
+	defer dc.Close()
 
-	for _, test := range tests {
-		var got string
-		err = db.QueryRow("select name from dbo.temp where id = ?", test.id).Scan(&got)
-		if err != nil {
-			t.Fatal(err)
+	cols, err := dc.(*Conn).DescribeQuery("select id, name, amount from dbo.temp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ColumnInfo{
+		{Name: "id", SQLType: api.SQL_INTEGER, Size: 10, Nullable: false},
+		{Name: "name", SQLType: api.SQL_WVARCHAR, Size: 20, Nullable: true},
+		{Name: "amount", SQLType: api.SQL_DECIMAL, Size: 10, DecimalDigits: 2, Nullable: true},
+	}
+	if len(cols) != len(want) {
+		t.Fatalf("expected %d columns, but got %d: %+v", len(want), len(cols), cols)
+	}
+	for i := range want {
+		got := ColumnInfo{
+			Name:          cols[i].Name,
+			SQLType:       cols[i].SQLType,
+			Size:          cols[i].Size,
+			DecimalDigits: cols[i].DecimalDigits,
+			Nullable:      cols[i].Nullable,
 		}
-		if test.name != got {
-			t.Fatalf("expect %v, but got %v", test.name, got)
+		if got != want[i] {
+			t.Errorf("column %d: expected %+v, but got %+v", i, want[i], got)
 		}
 	}
-
-	exec(t, db, "drop table dbo.temp")
 }
 
-// https://github.com/alexbrainman/odbc/issues/20
-func TestMSSQLSelectInt(t *testing.T) {
+func TestMSSQLDescribeQuerySource(t *testing.T) {
 	db, sc, err := mssqlConnect()
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer closeDB(t, db, sc, sc)
 
-	const expect = 123456
-	var got int
-	if err := db.QueryRow("select ?", expect).Scan(&got); err != nil {
+	db.Exec("drop table dbo.temp")
+	exec(t, db, "create table dbo.temp (id int identity not null, name nvarchar(20) null)")
+	defer exec(t, db, "drop table dbo.temp")
+
+	dc, err := drv.Open(newConnParams().makeODBCConnectionString())
+	if err != nil {
 		t.Fatal(err)
 	}
-	if expect != got {
-		t.Fatalf("expect %v, but got %v", expect, got)
+	defer dc.Close()
+
+	cols, err := dc.(*Conn).DescribeQuery("select id, name, id + 1 as next from dbo.temp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cols) != 3 {
+		t.Fatalf("expected 3 columns, but got %d: %+v", len(cols), cols)
+	}
+	if !cols[0].AutoIncrement {
+		t.Errorf("identity column %q is not reported as auto-increment", cols[0].Name)
+	}
+	for _, c := range cols[1:] {
+		if c.AutoIncrement {
+			t.Errorf("column %q is reported as auto-increment", c.Name)
+		}
+	}
+	if cols[1].Searchable == api.SQL_PRED_NONE {
+		t.Errorf("column %q is reported as not searchable", cols[1].Name)
+	}
+	for _, c := range cols[:2] {
+		if c.BaseTableName != "" && c.BaseTableName != "temp" {
+			t.Errorf("column %q: unexpected base table %q", c.Name, c.BaseTableName)
+		}
 	}
 }
 
-// https://github.com/alexbrainman/odbc/issues/21
-func TestMSSQLTextColumnParam(t *testing.T) {
+func TestMSSQLPrepareContext(t *testing.T) {
 	db, sc, err := mssqlConnect()
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer closeDB(t, db, sc, sc)
 
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	if _, err := db.PrepareContext(ctx, "select 1"); err != context.DeadlineExceeded {
+		t.Fatalf("expected %v, but got %v", context.DeadlineExceeded, err)
+	}
+
+	if isFreeTDS() {
+		return
+	}
 	db.Exec("drop table dbo.temp")
-	exec(t, db, `create table dbo.temp(id int primary key not null, v1 text, v2 text, v3 text, v4 text, v5 text, v6 text, v7 text, v8 text)`)
+	exec(t, db, "create table dbo.temp (id int)")
+	defer exec(t, db, "drop table dbo.temp")
 
-	s, err := db.Prepare(`insert into dbo.temp(id, v1, v2, v3, v4, v5, v6, v7, v8) values (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	// Schema change lock blocks parameter description of
+	// statements using dbo.temp, until tx is rolled back.
+	tx, err := db.Begin()
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer s.Close()
-
-	b := "string string string string string string string string string"
-	for i := 0; i < 100; i++ {
-		_, err := s.Exec(i, b, b, b, b, b, b, b, b)
-		if err != nil {
-			t.Fatal(err)
-		}
+	if _, err := tx.Exec("alter table dbo.temp add a int"); err != nil {
+		tx.Rollback()
+		t.Fatal(err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = db.PrepareContext(ctx, "select id from dbo.temp where id = ?")
+	elapsed := time.Since(start)
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected %v, but got %v", context.DeadlineExceeded, err)
+	}
+	if elapsed > 5*time.Second {
+		t.Fatalf("prepare was not cancelled in time: %v", elapsed)
 	}
 
-	exec(t, db, "drop table dbo.temp")
+	// prepare works after cancelled one
+	st, err := db.PrepareContext(context.Background(), "select id from dbo.temp where id = ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.Close()
 }
 
-func digestString(s string) string {
-	if len(s) < 40 {
-		return s
+func TestMSSQLPrepareContextAbandoned(t *testing.T) {
+	// connections opened by this test hang in SQLPrepare
+	// of hangQuery, until release is closed
+	const hangQuery = "select 1 as hang"
+	release := make(chan struct{})
+	drv.hooks.beforePrepare = func(query string) {
+		if query == hangQuery {
+			<-release
+		}
 	}
-	return fmt.Sprintf("%s ... (%d bytes long)", s[:40], len(s))
-}
+	defer func() {
+		drv.hooks.beforePrepare = nil
+	}()
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, cc, _ := drv.Counts()
 
-func digestBytes(b []byte) string {
-	if len(b) < 20 {
-		return fmt.Sprintf("%v", b)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = db.PrepareContext(ctx, hangQuery)
+	elapsed := time.Since(start)
+	if err != context.DeadlineExceeded {
+		close(release)
+		t.Fatalf("expected %v, but got %v", context.DeadlineExceeded, err)
 	}
-	s := ""
-	for _, v := range b[:20] {
-		if s != "" {
-			s += " "
-		}
-		s += fmt.Sprintf("%d", v)
+	if elapsed > queryCancelWait+time.Second {
+		close(release)
+		t.Fatalf("prepare was not abandoned in time: %v", elapsed)
 	}
-	return fmt.Sprintf("[%v ...] (%d bytes long)", s, len(b))
-}
 
-var paramTypeTests = []struct {
-	description string
-	sqlType     string
-	value       interface{}
-}{
-	// nil parameters
-	{"NULL for bit", "bit", nil},
-	{"NULL for text", "text", nil},
-	{"NULL for int", "int", nil},
-	// strings
-	{"non empty string", "varchar(10)", "abc"},
-	{"one character string", "varchar(10)", "a"},
-	{"empty string", "varchar(10)", ""},
-	{"empty unicode string", "nvarchar(10)", ""},
-	{"3999 large unicode string", "nvarchar(max)", strings.Repeat("a", 3999)},
-	{"4000 large unicode string", "nvarchar(max)", strings.Repeat("a", 4000)},
-	{"4000 large non-ascii unicode string", "nvarchar(max)", strings.Repeat("\u0421", 4000)},
-	{"4001 large unicode string", "nvarchar(max)", strings.Repeat("a", 4001)},
-	{"4001 large non-ascii unicode string", "nvarchar(max)", strings.Repeat("\u0421", 4001)},
-	{"10000 large unicode string", "nvarchar(max)", strings.Repeat("a", 10000)},
-	{"empty unicode null string", "nvarchar(10) null", ""},
-	{"3999 large string value", "text", strings.Repeat("a", 3999)},
-	{"4000 large string value", "text", strings.Repeat("a", 4000)},
-	{"4000 large unicode string value", "ntext", strings.Repeat("\u0421", 4000)},
-	{"4001 large string value", "text", strings.Repeat("a", 4001)},
-	{"4001 large unicode string value", "ntext", strings.Repeat("\u0421", 4001)},
-	{"very large string value", "text", strings.Repeat("a", 10000)},
-	{"unicode with russian letters", "nvarchar(10)", "\u0421\u0430\u0448\u0430"},
-	{"unicode with russian letters", "nvarchar(max)", "\u0421\u0430\u0448\u0430"},
-	{"unicode with russian letters", "ntext", "Саша"},
-	{"unicode with chinese letters", "nvarchar(max)", "Hello, 世界"},
-	{"unicode with chinese letters", "nvarchar(21)", "你好世界"},
-	{"unicode with greek letters", "nvarchar(21)", "Γεια σου κόσμε"},
-	// datetime
-	{"datetime overflow", "datetime", time.Date(2013, 9, 9, 14, 07, 15, 123e6, time.Local)},
-	// binary blobs
-	{"small blob", "varbinary", make([]byte, 1)},
-	{"very large blob", "varbinary(max)", make([]byte, 100000)},
-	{"7999 large image", "image", make([]byte, 7999)},
-	{"8000 large image", "image", make([]byte, 8000)},
-	{"8001 large image", "image", make([]byte, 8001)},
-	{"very large image", "image", make([]byte, 10000)},
+	// abandoned connection is discarded without waiting for SQLPrepare
+	closed := make(chan error, 1)
+	go func() {
+		closed <- db.Close()
+	}()
+	select {
+	case err := <-closed:
+		if err != nil {
+			close(release)
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		close(release)
+		t.Fatal("db.Close waits for abandoned prepare")
+	}
+
+	// connection is released, once SQLPrepare returns
+	close(release)
+	for i := 0; ; i++ {
+		_, n, m := drv.Counts()
+		if n == cc && int(m) == sc {
+			break
+		}
+		if i == 50 {
+			t.Fatalf("abandoned connection is not released: ConnCount=%v, StmtCount=%v", n, m)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
 }
 
-func TestMSSQLTextColumnParamTypes(t *testing.T) {
-	db, sc, err := mssqlConnect()
+func TestMSSQLStmtCancel(t *testing.T) {
+	dc, err := drv.Open(newConnParams().makeODBCConnectionString())
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer closeDB(t, db, sc, sc)
+	defer dc.Close()
 
-	for _, test := range paramTypeTests {
-		db.Exec("drop table dbo.temp")
-		exec(t, db, fmt.Sprintf("create table dbo.temp(v %s)", test.sqlType))
-		_, err = db.Exec("insert into dbo.temp(v) values(?)", test.value)
-		if err != nil {
-			t.Errorf("%s insert test failed: %s", test.description, err)
+	ds, err := dc.Prepare("WAITFOR DELAY '00:01'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ds.Close()
+	st := ds.(*Stmt)
+
+	done := make(chan error)
+	go func() {
+		_, err := st.Exec(nil)
+		done <- err
+	}()
+	time.Sleep(500 * time.Millisecond)
+	if err := st.Cancel(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("Unexpected success, expected error")
 		}
-		var v interface{}
-		err = db.QueryRow("select v from dbo.temp").Scan(&v)
-		if err != nil {
-			t.Errorf("%s select test failed: %s", test.description, err)
-			continue
+		var e *Error
+		if !errors.As(err, &e) || len(e.Diag) == 0 || e.Diag[0].State != "HY008" {
+			t.Errorf("expected operation canceled (HY008) error, but got %v", err)
 		}
-		switch want := test.value.(type) {
-		case string:
-			have := string(v.([]byte))
-			if have != want {
-				t.Errorf("%s wrong return value: have %q; want %q", test.description, digestString(have), digestString(want))
+	case <-time.After(30 * time.Second):
+		t.Fatal("statement was not cancelled")
+	}
+}
+
+func TestMSSQLReadDataChunks(t *testing.T) {
+	// fakeGetData returns getData function that behaves like
+	// SQLGetData reading data in chunks. If noTotal is set,
+	// it reports SQL_NO_TOTAL instead of remaining data size.
+	fakeGetData := func(ctype api.SQLSMALLINT, data []byte, noTotal bool) func(b []byte, l *BufferLen) (api.SQLRETURN, error) {
+		var termLen, charLen int
+		switch ctype {
+		case api.SQL_C_WCHAR:
+			termLen, charLen = 2, 2
+		case api.SQL_C_CHAR:
+			termLen, charLen = 1, 1
+		default:
+			termLen, charLen = 0, 1
+		}
+		pos := 0
+		return func(b []byte, l *BufferLen) (api.SQLRETURN, error) {
+			left := len(data) - pos
+			room := (len(b) - termLen) / charLen * charLen
+			for i := range b {
+				b[i] = 0xff // garbage that must not be returned
 			}
-		case []byte:
-			have := v.([]byte)
-			if !bytes.Equal(have, want) {
-				t.Errorf("%s wrong return value: have %v; want %v", test.description, digestBytes(have), digestBytes(want))
+			if left <= room {
+				copy(b, data[pos:])
+				for i := 0; i < termLen; i++ {
+					b[left+i] = 0
+				}
+				*l = BufferLen(left)
+				pos = len(data)
+				return api.SQL_SUCCESS, nil
 			}
-		case time.Time:
-			have := v.(time.Time)
-			if have != want {
-				t.Errorf("%s wrong return value: have %v; want %v", test.description, have, want)
+			copy(b, data[pos:pos+room])
+			for i := 0; i < termLen; i++ {
+				b[room+i] = 0
 			}
-		case nil:
-			if v != nil {
-				t.Errorf("%s wrong return value: have %v; want nil", test.description, v)
+			*l = BufferLen(left)
+			if noTotal {
+				*l = api.SQL_NO_TOTAL
+			}
+			pos += room
+			return api.SQL_SUCCESS_WITH_INFO, nil
+		}
+	}
+	for _, ctype := range []api.SQLSMALLINT{api.SQL_C_BINARY, api.SQL_C_CHAR, api.SQL_C_WCHAR} {
+		for _, size := range []int{0, 1, 1021, 1022, 1023, 1024, 1025, 2046, 2047, 2048, 3001, 3000000} {
+			if ctype == api.SQL_C_WCHAR && size%2 != 0 {
+				continue
+			}
+			data := make([]byte, size)
+			for i := range data {
+				data[i] = byte(i%250 + 1)
+			}
+			for _, noTotal := range []bool{false, true} {
+				// read data twice into the same buffer
+				var buf dataBuffer
+				for i := 0; i < 2; i++ {
+					got, isNull, err := readData(ctype, &buf, 0, fakeGetData(ctype, data, noTotal))
+					if err != nil {
+						t.Fatalf("ctype=%d size=%d noTotal=%v: %v", ctype, size, noTotal, err)
+					}
+					if isNull {
+						t.Fatalf("ctype=%d size=%d noTotal=%v: unexpected NULL", ctype, size, noTotal)
+					}
+					if !bytes.Equal(got, data) {
+						t.Errorf("ctype=%d size=%d noTotal=%v: data does not match (%d bytes read)", ctype, size, noTotal, len(got))
+					}
+					if size > 0 && &got[0] != &buf.chunk[0] && &got[0] != &buf.total[0] {
+						t.Errorf("ctype=%d size=%d noTotal=%v: data is not stored in buffer memory", ctype, size, noTotal)
+					}
+				}
+			}
+		}
+	}
+
+	// data larger than limit is refused
+	data := make([]byte, 3000000)
+	for _, noTotal := range []bool{false, true} {
+		for _, test := range []struct {
+			limit int
+			fail  bool
+		}{
+			{len(data), false},
+			{len(data) - 1, true},
+			{1000, true},
+		} {
+			_, _, err := readData(api.SQL_C_BINARY, nil, test.limit, fakeGetData(api.SQL_C_BINARY, data, noTotal))
+			if fail := err != nil; fail != test.fail {
+				t.Errorf("limit=%d noTotal=%v: expect failure %v, but got %v", test.limit, noTotal, test.fail, err)
 			}
 		}
 	}
-	exec(t, db, "drop table dbo.temp")
 }
 
-func TestMSSQLLongColumnNames(t *testing.T) {
-	db, sc, err := mssqlConnect()
+func TestMSSQLMaxDataSize(t *testing.T) {
+	params := newConnParams()
+	params["max_data_size"] = "1000"
+	db, sc, err := mssqlConnectWithParams(params)
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer closeDB(t, db, sc, sc)
 
-	query := fmt.Sprintf("select 'hello' as %s", strings.Repeat("a", 110))
 	var s string
-	err = db.QueryRow(query).Scan(&s)
+	err = db.QueryRow("select cast(replicate('a', 1000) as varchar(max))").Scan(&s)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if s != "hello" {
-		t.Errorf("expected \"hello\", but received %v", s)
+	err = db.QueryRow("select cast(replicate('a', 1001) as varchar(max))").Scan(&s)
+	if err == nil || !strings.Contains(err.Error(), "max_data_size") {
+		t.Errorf("expect max_data_size error, but got %v", err)
 	}
 }
 
-func TestMSSQLRawBytes(t *testing.T) {
-	db, sc, err := mssqlConnect()
+func TestMSSQLDecimalAsString(t *testing.T) {
+	params := newConnParams()
+	params["decimal"] = "string"
+	db, sc, err := mssqlConnectWithParams(params)
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer closeDB(t, db, sc, sc)
 
-	db.Exec("drop table dbo.temp")
-	exec(t, db, `create table dbo.temp(ascii char(7), utf16 nchar(7), blob binary(3))`)
-	_, err = db.Exec(`insert into dbo.temp (ascii, utf16, blob) values (?, ?, ?)`, "alex", "alex", []byte{1, 2, 3})
-	if err != nil {
-		t.Fatal(err)
+	tests := []struct {
+		query string
+		want  interface{}
+	}{
+		{"select cast(0.0123 as money)", "0.0123"},
+		{"select cast(-922337203685477.5808 as money)", "-922337203685477.5808"},
+		{"select cast(-0.5 as smallmoney)", "-0.5000"},
+		{"select cast(12345678901234567890.123456789 as decimal(38,9))", "12345678901234567890.123456789"},
+		{"select cast(null as money)", nil},
+		{"select cast(1.5 as float)", 1.5},
 	}
-
-	rows, err := db.Query("select ascii, utf16, blob from dbo.temp")
-	if err != nil {
-		t.Fatalf("Query: %v", err)
+	for _, test := range tests {
+		var v interface{}
+		if err := db.QueryRow(test.query).Scan(&v); err != nil {
+			t.Errorf("%s: %v", test.query, err)
+			continue
+		}
+		if v != test.want {
+			t.Errorf("%s: expect %#v, but got %#v", test.query, test.want, v)
+		}
 	}
-	defer rows.Close()
+}
 
-	for rows.Next() {
-		var ascii, utf16 sql.RawBytes
-		var blob []byte
-		err = rows.Scan(&ascii, &utf16, &blob)
+// streamedData is sql.Scanner, that reads streamed column data
+// in small pieces.
+type streamedData struct {
+	data   []byte
+	reads  int
+	isNull bool
+}
+
+func (d *streamedData) Scan(src interface{}) error {
+	if src == nil {
+		d.isNull = true
+		return nil
+	}
+	r, ok := src.(io.Reader)
+	if !ok {
+		return fmt.Errorf("expect io.Reader, but got %T", src)
+	}
+	b := make([]byte, 1000)
+	for {
+		n, err := r.Read(b)
+		d.data = append(d.data, b[:n]...)
+		d.reads++
+		if err == io.EOF {
+			return nil
+		}
 		if err != nil {
-			t.Fatalf("Scan: %v", err)
+			return err
 		}
 	}
-	err = rows.Err()
+}
+
+func TestMSSQLStreamedLongData(t *testing.T) {
+	db, sc, err := mssqlConnect()
 	if err != nil {
 		t.Fatal(err)
 	}
+	defer closeDB(t, db, sc, sc)
 
-	exec(t, db, "drop table dbo.temp")
-}
+	ctx := WithStreamedLongData(context.Background())
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"select 1, replicate(cast('abc' as varchar(max)), 100000)", strings.Repeat("abc", 100000)},
+		{"select 1, replicate(cast(N'\u263a\U0001F600' as nvarchar(max)), 50000)", strings.Repeat("\u263a\U0001F600", 50000)},
+		{"select 1, cast(replicate(cast('x' as varchar(max)), 20000) as varbinary(max))", strings.Repeat("x", 20000)},
+		{"select 1, cast('' as nvarchar(max))", ""},
+	}
+	for _, test := range tests {
+		var n int
+		var d streamedData
+		if err := db.QueryRowContext(ctx, test.query).Scan(&n, &d); err != nil {
+			t.Errorf("%s: %v", test.query, err)
+			continue
+		}
+		if string(d.data) != test.want {
+			t.Errorf("%s: wrong data returned: %d bytes expected, but got %d bytes", test.query, len(test.want), len(d.data))
+		}
+		if len(test.want) > 10000 && d.reads < len(test.want)/1000 {
+			t.Errorf("%s: data should be read in many pieces, but read in %d", test.query, d.reads)
+		}
+	}
 
-// https://github.com/alexbrainman/odbc/issues/27
-func TestMSSQLUTF16ToUTF8(t *testing.T) {
-	s := []uint16{0x47, 0x75, 0x73, 0x74, 0x61, 0x66, 0x27, 0x73, 0x20, 0x4b, 0x6e, 0xe4, 0x63, 0x6b, 0x65, 0x62, 0x72, 0xf6, 0x64}
-	if api.UTF16ToString(s) != string(utf16toutf8(s)) {
-		t.Fatal("comparison fails")
+	var d streamedData
+	if err := db.QueryRowContext(ctx, "select cast(null as nvarchar(max))").Scan(&d); err != nil {
+		t.Fatal(err)
+	}
+	if !d.isNull {
+		t.Error("NULL value expected")
 	}
-}
 
-func TestMSSQLExecStoredProcedure(t *testing.T) {
-	db, sc, err := mssqlConnect()
+	// only last unbound column is streamed
+	var s string
+	d = streamedData{}
+	err = db.QueryRowContext(ctx, "select cast('abc' as varchar(max)), cast('def' as varchar(max))").Scan(&s, &d)
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer closeDB(t, db, sc, sc)
+	if s != "abc" || string(d.data) != "def" {
+		t.Errorf("expect %q and %q, but got %q and %q", "abc", "def", s, d.data)
+	}
 
-	db.Exec("drop procedure dbo.temp")
-	exec(t, db, `
-create procedure dbo.temp
-	@a	int,
-	@b	int
-as
-begin
-	return @a + @b
-end
-`)
-	qry := `
-declare @ret int
-exec @ret = dbo.temp @a = ?, @b = ?
-select @ret
-`
-	var ret int64
-	if err := db.QueryRow(qry, 2, 3).Scan(&ret); err != nil {
+	// reader cannot be used after Next
+	rows, err := db.QueryContext(ctx, "select replicate(cast('a' as varchar(max)), 100000) union all select 'b'")
+	if err != nil {
 		t.Fatal(err)
 	}
-	if ret != 5 {
-		t.Fatalf("unexpected return value: should=5, is=%v", ret)
+	defer rows.Close()
+	var r interface{}
+	if !rows.Next() {
+		t.Fatal("expected at least 1 row")
+	}
+	if err := rows.Scan(&r); err != nil {
+		t.Fatal(err)
+	}
+	if !rows.Next() {
+		t.Fatal("expected 2 rows")
+	}
+	b := make([]byte, 100000)
+	if _, err := io.ReadFull(r.(io.Reader), b); err != errStaleReader {
+		t.Errorf("expect %v error, but got %v", errStaleReader, err)
 	}
-	exec(t, db, `drop procedure dbo.temp`)
 }
 
-func TestMSSQLSingleCharParam(t *testing.T) {
-	db, sc, err := mssqlConnect()
+func TestMSSQLUnboundColumnValueTwice(t *testing.T) {
+	dc, err := drv.Open(newConnParams().makeODBCConnectionString())
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer closeDB(t, db, sc, sc)
+	defer dc.Close()
 
-	db.Exec("drop table dbo.temp")
-	exec(t, db, `create table dbo.temp(name nvarchar(50), age int)`)
-
-	rows, err := db.Query("select age from dbo.temp where name=?", "v")
+	st, err := dc.Prepare("select cast('abc' as varchar(max)), cast(N'def' as nvarchar(max)) union all select 'ghi', N'jkl'")
 	if err != nil {
 		t.Fatal(err)
 	}
-	rows.Close()
-
-	exec(t, db, "drop table dbo.temp")
-}
-
-type tcpProxy struct {
-	mu      sync.Mutex
-	stopped bool
-	conns   []net.Conn
-}
+	defer st.Close()
+	rows, err := st.Query(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	r := rows.(*Rows)
 
-func (p *tcpProxy) run(ln net.Listener, remote string) {
-	for {
-		defer p.pause()
-		c1, err := ln.Accept()
-		if err != nil {
-			return
+	dest := make([]driver.Value, 2)
+	for _, want := range [][2]string{{"abc", "def"}, {"ghi", "jkl"}} {
+		if err := r.Next(dest); err != nil {
+			t.Fatal(err)
 		}
-		go func(c1 net.Conn) {
-			defer c1.Close()
-
-			if p.paused() {
-				return
-			}
-
-			p.addConn(c1)
-
-			c2, err := net.Dial("tcp", remote)
-			if err != nil {
-				panic(err)
+		// read both columns again, like scanning them into second destination
+		for i, w := range want {
+			for j := 0; j < 2; j++ {
+				v, err := r.os.Cols[i].Value(r.os.h, i)
+				if err != nil {
+					t.Fatalf("column %d, read %d: %v", i, j, err)
+				}
+				var s string
+				switch v := v.(type) {
+				case []byte:
+					s = string(v)
+				case string:
+					s = v
+				}
+				if s != w {
+					t.Errorf("column %d, read %d: expect %q, but got %#v", i, j, w, v)
+				}
 			}
-			p.addConn(c2)
-			defer c2.Close()
-
-			go func() {
-				io.Copy(c2, c1)
-			}()
-			io.Copy(c1, c2)
-		}(c1)
+		}
 	}
-}
 
-func (p *tcpProxy) pause() {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	p.stopped = true
-	for _, c := range p.conns {
-		c.Close()
+	// database/sql rows can be scanned twice
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+	srows, err := db.Query("select cast('abc' as varchar(max))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srows.Close()
+	if !srows.Next() {
+		t.Fatal("expected at least 1 row")
+	}
+	var s1 string
+	var s2 []byte
+	if err := srows.Scan(&s1); err != nil {
+		t.Fatal(err)
+	}
+	if err := srows.Scan(&s2); err != nil {
+		t.Fatal(err)
+	}
+	if s1 != "abc" || string(s2) != "abc" {
+		t.Errorf("expect %q twice, but got %q and %q", "abc", s1, s2)
 	}
-	p.conns = p.conns[:0]
-}
-
-func (p *tcpProxy) paused() bool {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	return p.stopped
-}
-
-func (p *tcpProxy) addConn(c net.Conn) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	p.conns = append(p.conns, c)
 }
 
-func (p *tcpProxy) restart() {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	p.stopped = false
+func TestMSSQLDateScan(t *testing.T) {
+	want := Date{2021, time.March, 14}
+	for _, src := range []interface{}{
+		want,
+		time.Date(2021, time.March, 14, 23, 59, 0, 0, time.FixedZone("x", -12*3600)),
+		"2021-03-14",
+		[]byte("2021-03-14"),
+	} {
+		var d Date
+		if err := d.Scan(src); err != nil {
+			t.Errorf("Scan(%#v) failed: %v", src, err)
+			continue
+		}
+		if d != want {
+			t.Errorf("Scan(%#v): expect %v, but got %v", src, want, d)
+		}
+	}
+	for _, src := range []interface{}{nil, 1, "2021-03-14 10:00:00"} {
+		var d Date
+		if err := d.Scan(src); err == nil {
+			t.Errorf("Scan(%#v) should fail, but succeeded", src)
+		}
+	}
+	if s := want.String(); s != "2021-03-14" {
+		t.Errorf("expect %q, but got %q", "2021-03-14", s)
+	}
 }
 
-func TestMSSQLReconnect(t *testing.T) {
+func TestMSSQLCivilDate(t *testing.T) {
 	params := newConnParams()
-	address, err := params.getConnAddress()
+	params["date"] = "civil"
+	db, sc, err := mssqlConnectWithParams(params)
 	if err != nil {
-		t.Skipf("Skipping test: %v", err)
+		t.Fatal(err)
 	}
+	defer closeDB(t, db, sc, sc)
 
-	ln, err := net.Listen("tcp", "127.0.0.1:0")
-	if err != nil {
+	want := Date{2021, time.March, 14}
+	var v interface{}
+	if err := db.QueryRow("select cast('2021-03-14' as date)").Scan(&v); err != nil {
 		t.Fatal(err)
 	}
-	defer ln.Close()
+	if v != want {
+		t.Errorf("expect %#v, but got %#v", want, v)
+	}
 
-	err = params.updateConnAddress(ln.Addr().String())
-	if err != nil {
+	// Date parameter is bound as date
+	var d Date
+	if err := db.QueryRow("select cast(? as date)", want).Scan(&d); err != nil {
 		t.Fatal(err)
 	}
+	if d != want {
+		t.Errorf("expect %v, but got %v", want, d)
+	}
 
-	proxy := new(tcpProxy)
-	go proxy.run(ln, address)
+	// timestamp columns can be scanned into Date too
+	if err := db.QueryRow("select cast('2021-03-14 23:30:00' as datetime2)").Scan(&d); err != nil {
+		t.Fatal(err)
+	}
+	if d != want {
+		t.Errorf("expect %v, but got %v", want, d)
+	}
+}
 
+func TestMSSQLDecimalAsNumeric(t *testing.T) {
+	params := newConnParams()
+	params["decimal"] = "numeric"
 	db, sc, err := mssqlConnectWithParams(params)
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer closeDB(t, db, sc, sc)
 
-	testConn := func() error {
-		var n int64
-		err := db.QueryRow("select count(*) from dbo.temp").Scan(&n)
-		if err != nil {
-			return err
+	tests := []struct {
+		query string
+		want  interface{}
+	}{
+		{"select cast(12345678901234567890.1234567890 as decimal(38,10))", "12345678901234567890.1234567890"},
+		{"select cast(-12345678901234567890.1234567890 as decimal(38,10))", "-12345678901234567890.1234567890"},
+		{"select cast(-0.0000000001 as decimal(38,10))", "-0.0000000001"},
+		{"select cast(0 as decimal(38,10))", "0.0000000000"},
+		{"select cast(123 as decimal(5,0))", "123"},
+		{"select cast(-0.5 as smallmoney)", "-0.5000"},
+		{"select cast(null as decimal(38,10))", nil},
+	}
+	for _, test := range tests {
+		var v interface{}
+		if err := db.QueryRow(test.query).Scan(&v); err != nil {
+			t.Errorf("%s: %v", test.query, err)
+			continue
 		}
-		if n != 1 {
-			return fmt.Errorf("unexpected return value: should=1, is=%v", n)
+		if v != test.want {
+			t.Errorf("%s: expect %#v, but got %#v", test.query, test.want, v)
 		}
-		return nil
 	}
 
-	db.Exec("drop table dbo.temp")
-	exec(t, db, `create table dbo.temp (name varchar(50))`)
-	exec(t, db, `insert into dbo.temp (name) values ('alex')`)
-
-	err = testConn()
+	// decimal column after varchar(max) is not bound, and read with SQLGetData
+	var s string
+	var v interface{}
+	err = db.QueryRow("select cast('a' as varchar(max)), cast(-98765432109876543210.0123456789 as decimal(38,10))").Scan(&s, &v)
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	proxy.pause()
-	time.Sleep(100 * time.Millisecond)
-
-	err = testConn()
-	if err == nil {
-		t.Fatal("database IO should fail, but succeeded")
+	if want := "-98765432109876543210.0123456789"; v != want {
+		t.Errorf("expect %#v, but got %#v", want, v)
 	}
 
-	proxy.restart()
-
-	err = testConn()
+	// result sets, that differ only in scale, must not share bindings
+	rows, err := db.Query("select cast(1.5 as decimal(10,1))\nselect cast(2.25 as decimal(10,2))")
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	exec(t, db, "drop table dbo.temp")
+	defer rows.Close()
+	for i, want := range []string{"1.5", "2.25"} {
+		if i > 0 && !rows.NextResultSet() {
+			t.Fatalf("expected result set %d", i)
+		}
+		if !rows.Next() {
+			t.Fatalf("result set %d: expected a row", i)
+		}
+		if err := rows.Scan(&v); err != nil {
+			t.Fatal(err)
+		}
+		if v != want {
+			t.Errorf("result set %d: expect %#v, but got %#v", i, want, v)
+		}
+	}
 }
 
-func TestMSSQLMarkTxBadConn(t *testing.T) {
-	params := newConnParams()
-
-	address, err := params.getConnAddress()
+func TestMSSQLColumnTypeSQLType(t *testing.T) {
+	dc, err := drv.Open(newConnParams().makeODBCConnectionString())
 	if err != nil {
-		t.Skipf("Skipping test: %v", err)
+		t.Fatal(err)
 	}
+	defer dc.Close()
 
-	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	st, err := dc.Prepare("select cast('a' as varchar(10)), cast('a' as nvarchar(10)), cast(1 as int)")
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer ln.Close()
-
-	err = params.updateConnAddress(ln.Addr().String())
+	defer st.Close()
+	rows, err := st.Query(nil)
 	if err != nil {
 		t.Fatal(err)
 	}
+	defer rows.Close()
 
-	proxy := new(tcpProxy)
-	go proxy.run(ln, address)
-
-	testFn := func(endTx func(driver.Tx) error, nextFn func(driver.Conn) error) {
-		proxy.restart()
-
-		cc, sc := drv.Stats.ConnCount, drv.Stats.StmtCount
-		defer func() {
-			if should, is := sc, drv.Stats.StmtCount; should != is {
-				t.Errorf("leaked statement, should=%d, is=%d", should, is)
-			}
-			if should, is := cc, drv.Stats.ConnCount; should != is {
-				t.Errorf("leaked connection, should=%d, is=%d", should, is)
-			}
-		}()
-
-		dc, err := drv.Open(params.makeODBCConnectionString())
-		if err != nil {
-			t.Fatal(err)
+	want := []int16{api.SQL_VARCHAR, api.SQL_WVARCHAR, api.SQL_INTEGER}
+	r := rows.(*Rows)
+	for i, w := range want {
+		if got := r.ColumnTypeSQLType(i); got != w {
+			t.Errorf("column %d: expect SQL type %d, but got %d", i, w, got)
 		}
-		defer func() {
-			if err := dc.Close(); err != nil {
-				t.Fatal(err)
-			}
-		}()
+	}
+}
 
-		driverExec(nil, dc, "drop table dbo.temp")
-		driverExec(t, dc, `create table dbo.temp (name varchar(50))`)
+func TestMSSQLColumnTypeLength(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
 
-		tx, err := dc.Begin()
-		if err != nil {
-			t.Fatal(err)
+	rows, err := db.Query("select cast('a' as varchar(50)), cast('a' as varchar(max)), cast('a' as nvarchar(10)), cast('a' as nvarchar(max)), cast(0x01 as varbinary(max)), cast(1 as int)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	cts, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		name   string
+		length int64
+		ok     bool
+	}{
+		{"VARCHAR", 50, true},
+		{"VARCHAR", math.MaxInt64, true},
+		{"NVARCHAR", 10, true},
+		{"NVARCHAR", math.MaxInt64, true},
+		{"VARBINARY", math.MaxInt64, true},
+		{"INT", 0, false},
+	}
+	for i, ct := range cts {
+		w := want[i]
+		if name := ct.DatabaseTypeName(); name != w.name {
+			t.Errorf("column %d: expect type name %q, but got %q", i, w.name, name)
 		}
-
-		driverExec(t, dc, `insert into dbo.temp (name) values ('alex')`)
-
-		proxy.pause()
-		time.Sleep(100 * time.Millisecond)
-
-		// the connection is broken, ending the transaction should fail
-		if err := endTx(tx); err == nil {
-			t.Fatal("unexpected success, expected error")
+		if length, ok := ct.Length(); length != w.length || ok != w.ok {
+			t.Errorf("column %d: expect length %d %v, but got %d %v", i, w.length, w.ok, length, ok)
 		}
+	}
+}
 
-		// database/sql might return the broken driver.Conn to the pool in
-		// that case the next operation must fail.
-		if err := nextFn(dc); err == nil {
-			t.Fatal("unexpected success, expected error")
-		}
+func TestMSSQLColumnTypeScanType(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
 	}
+	defer closeDB(t, db, sc, sc)
 
-	beginFn := func(dc driver.Conn) error {
-		tx, err := dc.Begin()
-		if err != nil {
-			return err
+	rows, err := db.Query("select cast(200 as tinyint), cast(1 as smallint), cast(1 as int), cast(1 as bigint), cast(1 as bit), cast(1.5 as float), cast('a' as varchar(10))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	cts, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []reflect.Type{
+		reflect.TypeOf(uint8(0)), // SQL Server tinyint is unsigned
+		reflect.TypeOf(int16(0)),
+		reflect.TypeOf(int32(0)),
+		reflect.TypeOf(int64(0)),
+		reflect.TypeOf(false),
+		reflect.TypeOf(float64(0)),
+		reflect.TypeOf([]byte(nil)),
+	}
+	for i, ct := range cts {
+		if got := ct.ScanType(); got != want[i] {
+			t.Errorf("column %d: expect scan type %v, but got %v", i, want[i], got)
 		}
-		tx.Rollback()
-		return nil
 	}
+	if !rows.Next() {
+		t.Fatal(rows.Err())
+	}
+	var ti uint8
+	var si int16
+	var i int32
+	var bi int64
+	var b bool
+	var f float64
+	var s []byte
+	if err := rows.Scan(&ti, &si, &i, &bi, &b, &f, &s); err != nil {
+		t.Fatal(err)
+	}
+	if ti != 200 {
+		t.Errorf("expect tinyint 200, but got %d", ti)
+	}
+}
 
-	prepareFn := func(dc driver.Conn) error {
-		st, err := dc.Prepare(`insert into dbo.temp (name) values ('alex')`)
-		if err != nil {
-			return err
-		}
-		st.Close()
-		return nil
+func TestMSSQLBoolParamIntegerColumns(t *testing.T) {
+	db, sc, err := mssqlConnect()
+	if err != nil {
+		t.Fatal(err)
 	}
+	defer closeDB(t, db, sc, sc)
 
-	// Test all the permutations.
-	for _, endTx := range []func(driver.Tx) error{
-		driver.Tx.Commit,
-		driver.Tx.Rollback,
-	} {
-		for _, nextFn := range []func(driver.Conn) error{
-			beginFn,
-			prepareFn,
-		} {
-			testFn(endTx, nextFn)
+	db.Exec("drop table dbo.temp")
+	exec(t, db, "create table dbo.temp (b bit, ti tinyint, si smallint, i int, bi bigint)")
+	for _, v := range []bool{true, false} {
+		_, err := db.Exec("insert into dbo.temp (b, ti, si, i, bi) values (?, ?, ?, ?, ?)", v, v, v, v, v)
+		if err != nil {
+			t.Fatal(err)
 		}
 	}
+	var n int
+	err = db.QueryRow("select count(*) from dbo.temp where ti = b and si = b and i = b and bi = b").Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("expect 2 matching rows, but got %d", n)
+	}
+
+	exec(t, db, "drop table dbo.temp")
 }
 
-func TestMSSQLMarkBeginBadConn(t *testing.T) {
+func TestMSSQLConnectorContext(t *testing.T) {
 	params := newConnParams()
+	cn, err := drv.OpenConnector(params.makeODBCConnectionString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(cn)
+	defer db.Close()
+	if err := db.QueryRow("select 1").Scan(new(int)); err != nil {
+		t.Fatal(err)
+	}
 
-	testFn := func(label string, nextFn func(driver.Conn) error) {
-		cc, sc := drv.Stats.ConnCount, drv.Stats.StmtCount
-		defer func() {
-			if should, is := sc, drv.Stats.StmtCount; should != is {
-				t.Errorf("leaked statement, should=%d, is=%d", should, is)
-			}
-			if should, is := cc, drv.Stats.ConnCount; should != is {
-				t.Errorf("leaked connection, should=%d, is=%d", should, is)
-			}
-		}()
-
-		dc, err := drv.Open(params.makeODBCConnectionString())
-		if err != nil {
-			t.Fatal(err)
-		}
-		defer func() {
-			if err := dc.Close(); err != nil {
-				t.Fatal(err)
-			}
-		}()
+	// 192.0.2.0/24 is reserved for documentation, nothing answers there.
+	params = newConnParams()
+	params["server"] = "192.0.2.1"
+	delete(params, "port")
+	cn, err = drv.OpenConnector(params.makeODBCConnectionString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err = cn.Connect(ctx)
+	if err == nil {
+		t.Fatal("Unexpected success, expected error")
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("Connect took %v, but context timeout is 1s", d)
+	}
+}
 
-		driverExec(nil, dc, "drop table dbo.temp")
-		driverExec(t, dc, `create table dbo.temp (name varchar(50))`)
+func TestMSSQLNewConnector(t *testing.T) {
+	params := newConnParams()
+	params["decimal"] = "string"
+	db := sql.OpenDB(NewConnector(params.makeODBCConnectionString()))
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	// package attributes of dsn are used
+	var v interface{}
+	if err := db.QueryRow("select cast(1.5 as decimal(5,2))").Scan(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v != "1.50" {
+		t.Errorf("expect %q, but got %#v", "1.50", v)
+	}
 
-		// force an error starting a transaction
-		func() {
-			testBeginErr = errors.New("cannot start tx")
-			defer func() { testBeginErr = nil }()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewConnector(params.makeODBCConnectionString()).Connect(ctx); err != context.Canceled {
+		t.Errorf("expect %v error, but got %v", context.Canceled, err)
+	}
+}
 
-			if _, err := dc.Begin(); err == nil {
-				t.Fatal("unexpected success, expected error")
-			}
-		}()
+func TestMSSQLNewDriver(t *testing.T) {
+	d, err := NewDriver(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	sql.Register("odbc-nopool", d)
 
-		// database/sql might return the broken driver.Conn to the pool. The
-		// next operation on the driver connection must return
-		// driver.ErrBadConn to prevent the bad connection from getting used
-		// again.
-		if should, is := driver.ErrBadConn, nextFn(dc); should != is {
-			t.Errorf("%s: should=\"%v\", is=\"%v\"", label, should, is)
-		}
+	db, err := sql.Open("odbc-nopool", newConnParams().makeODBCConnectionString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_, defConns, _ := drv.Counts()
+	var n int
+	if err := db.QueryRow("select 1").Scan(&n); err != nil {
+		t.Fatal(err)
 	}
-
-	beginFn := func(dc driver.Conn) error {
-		tx, err := dc.Begin()
-		if err != nil {
-			return err
-		}
-		tx.Rollback()
-		return nil
+	if n != 1 {
+		t.Errorf("expect 1, but got %d", n)
 	}
 
-	prepareFn := func(dc driver.Conn) error {
-		st, err := dc.Prepare(`insert into dbo.temp (name) values ('alex')`)
-		if err != nil {
-			return err
-		}
-		st.Close()
-		return nil
+	// handles are counted by d, not by the default driver
+	if env, conns, stmts := d.Counts(); env != 1 || conns != 1 || stmts != 0 {
+		t.Errorf("unexpected handle counts of new driver: env=%v, conn=%v, stmt=%v", env, conns, stmts)
+	}
+	if _, conns, _ := drv.Counts(); conns != defConns {
+		t.Errorf("connection is counted by default driver: should=%v, is=%v", defConns, conns)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, conns, _ := d.Counts(); conns != 0 {
+		t.Errorf("unexpected connection count of new driver after db.Close: %v", conns)
 	}
 
-	// Test all the permutations.
-	for _, next := range []struct {
-		label string
-		fn    func(driver.Conn) error
-	}{
-		{"begin", beginFn},
-		{"prepare", prepareFn},
-	} {
-		testFn(next.label, next.fn)
+	if err := d.SetConnectionPooling(true); err != nil {
+		t.Fatal(err)
 	}
 }
 
-func testMSSQLNextResultSet(t *testing.T, verifyBatch func(rows *sql.Rows)) {
+func TestMSSQLNonASCIIPassword(t *testing.T) {
 	db, sc, err := mssqlConnect()
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer closeDB(t, db, sc, sc)
 
-	db.Exec("drop table dbo.temp")
-	exec(t, db, `create table dbo.temp (name varchar(50))`)
-	exec(t, db, `insert into dbo.temp (name) values ('russ')`)
-	exec(t, db, `insert into dbo.temp (name) values ('brad')`)
+	const login = "odbc_test_login"
+	const pwd = "Пароль_密码_Ä1!"
+	db.Exec("drop login " + login)
+	_, err = db.Exec("create login " + login + " with password = N'" + pwd + "', check_policy = off")
+	if err != nil {
+		t.Skipf("skipping test; cannot create login: %v", err)
+	}
+	defer db.Exec("drop login " + login)
 
-	rows, err := db.Query(`
-select name from dbo.temp where name = 'russ';
-select name from dbo.temp where name = 'brad';
-`)
+	params := newConnParams()
+	delete(params, "trusted_connection")
+	delete(params, "database")
+	params["uid"] = login
+	params["pwd"] = "{" + pwd + "}"
+	db2, err := sql.Open("odbc", params.makeODBCConnectionString())
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer rows.Close()
-
-	verifyBatch(rows)
-
-	exec(t, db, "drop table dbo.temp")
+	defer db2.Close()
+	var name string
+	if err := db2.QueryRow("select suser_sname()").Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	if name != login {
+		t.Errorf("connected as %q, expected %q", name, login)
+	}
 }
 
-func TestMSSQLNextResultSet(t *testing.T) {
-	checkName := func(rows *sql.Rows, name string) {
-		if !rows.Next() {
-			if err := rows.Err(); err != nil {
-				t.Fatalf("executing Next for %q failed: %v", name, err)
-			}
-			t.Fatalf("checking %q: at least one row expected", name)
-		}
-		var have string
-		err := rows.Scan(&have)
+func TestMSSQLSupportsFunction(t *testing.T) {
+	dc, err := drv.Open(newConnParams().makeODBCConnectionString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dc.Close()
+	c := dc.(*Conn)
+
+	for _, f := range []uint16{api.SQL_API_SQLMORERESULTS, api.SQL_API_SQLPROCEDURECOLUMNS} {
+		ok, err := c.SupportsFunction(f)
 		if err != nil {
-			t.Fatalf("executing Scan for %q failed: %v", name, err)
+			t.Fatal(err)
 		}
-		if name != have {
-			t.Fatalf("want %q, but %q found", name, have)
+		if !ok {
+			t.Errorf("function %d should be supported", f)
 		}
 	}
-	testMSSQLNextResultSet(t,
-		func(rows *sql.Rows) {
-			checkName(rows, "russ")
-			if !rows.NextResultSet() {
-				if err := rows.Err(); err != nil {
-					t.Fatal(err)
-				}
-				t.Fatal("more result sets expected")
-			}
-			checkName(rows, "brad")
-			if isFreeTDS() { // not sure why it does not work on FreeTDS
-				t.Log("skipping broken part of the test on FreeTDS")
-				return
-			}
-			if rows.NextResultSet() {
-				t.Fatal("unexpected result set found")
-			} else if err := rows.Err(); err != nil {
-				t.Fatal(err)
-			}
-		})
 }
 
-func TestMSSQLNextResultSetWithDifferentColumnsInResultSets(t *testing.T) {
-	db, sc, err := mssqlConnect()
+func TestMSSQLHandle(t *testing.T) {
+	dc, err := drv.Open(newConnParams().makeODBCConnectionString())
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer closeDB(t, db, sc, sc)
-	rows, err := db.Query("select 1 select 2,3")
-	if err != nil {
-		t.Fatal(err)
+	defer dc.Close()
+	h := dc.(HandleConn).Handle()
+
+	b := make([]uint16, 256)
+	var l api.SQLSMALLINT
+	ret := api.SQLGetInfo(h, api.SQL_DBMS_NAME, api.SQLPOINTER(unsafe.Pointer(&b[0])), api.SQLSMALLINT(len(b)*2), &l)
+	if IsError(ret) {
+		t.Fatal(NewError("SQLGetInfo", h))
 	}
-	defer rows.Close()
-	if !rows.Next() {
-		t.Fatal("expected at least 1 result")
+	if name := api.UTF16ToString(b); !strings.Contains(name, "SQL Server") {
+		t.Errorf("unexpected DBMS name %q", name)
 	}
-	var v1, v2 int
-	err = rows.Scan(&v1)
+}
+
+// Run with -race flag.
+func TestMSSQLBadConnConcurrent(t *testing.T) {
+	dc, err := drv.Open(newConnParams().makeODBCConnectionString())
 	if err != nil {
-		t.Fatalf("unable to scan select 1 underlying error: %v", err)
-	}
-	if v1 != 1 {
-		t.Fatalf("expected: %v got %v", 1, v1)
-	}
-	if rows.Next() {
-		t.Fatal("unexpected row")
-	}
-	if !rows.NextResultSet() {
-		t.Fatal("expected another result set")
+		t.Fatal(err)
 	}
-	if !rows.Next() {
-		t.Fatal("expected a single row")
+	defer dc.Close()
+	c := dc.(*Conn)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			c.bad.Store(true)
+		}()
+		go func() {
+			defer wg.Done()
+			c.Ping(context.Background())
+		}()
 	}
-	err = rows.Scan(&v1, &v2)
-	if err != nil {
-		t.Fatalf("unable to scan select 2,3 underlying error: %v", err)
+	wg.Wait()
+	if err := c.Ping(context.Background()); err != driver.ErrBadConn {
+		t.Errorf("expected %v, but got %v", driver.ErrBadConn, err)
 	}
-	if v1 != 2 || v2 != 3 {
-		t.Fatalf("got wrong values expected v1=%v v2=%v. got v1=%v v2=%v", 2, 3, v1, v2)
+	if _, err := c.Prepare("select 1"); err != driver.ErrBadConn {
+		t.Errorf("expected %v, but got %v", driver.ErrBadConn, err)
 	}
-
 }
 
-func TestMSSQLHasNextResultSet(t *testing.T) {
-	checkName := func(rows *sql.Rows, name string) {
-		var reccount int
-		for rows.Next() { // reading till the end of data set to trigger call into HasNextResultSet
-			var have string
-			err := rows.Scan(&have)
-			if err != nil {
-				t.Fatalf("executing Scan for %q failed: %v", name, err)
-			}
-			if name != have {
-				t.Fatalf("want %q, but %q found", name, have)
-			}
-			reccount++
+func TestMSSQLDataSources(t *testing.T) {
+	env, conn, stmt := drv.Counts()
+	for i := 0; i < 10; i++ {
+		if _, err := DataSources(context.Background()); err != nil {
+			t.Fatal(err)
 		}
-		if err := rows.Err(); err != nil {
-			t.Fatalf("executing Next for %q failed: %v", name, err)
+		ds, err := Drivers(context.Background())
+		if err != nil {
+			t.Fatal(err)
 		}
-		if reccount != 1 {
-			t.Fatalf("checking %q: expected 1 row returned, but %v found", name, reccount)
+		if len(ds) == 0 {
+			t.Fatal("no ODBC drivers found")
 		}
 	}
-	testMSSQLNextResultSet(t,
-		func(rows *sql.Rows) {
-			checkName(rows, "russ")
-			if !rows.NextResultSet() {
-				if err := rows.Err(); err != nil {
-					t.Fatal(err)
-				}
-				t.Fatal("more result sets expected")
-			}
-			checkName(rows, "brad")
-			if rows.NextResultSet() {
-				t.Fatal("unexpected result set found")
-			} else {
-				if err := rows.Err(); err != nil {
-					t.Fatal(err)
-				}
-			}
-		})
+	env2, conn2, stmt2 := drv.Counts()
+	if env != env2 || conn != conn2 || stmt != stmt2 {
+		t.Errorf("handle counts changed from %d/%d/%d to %d/%d/%d", env, conn, stmt, env2, conn2, stmt2)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := DataSources(ctx); err != context.Canceled {
+		t.Errorf("expected %v, but got %v", context.Canceled, err)
+	}
 }
 
-func TestMSSQLIssue127(t *testing.T) {
-	db, sc, err := mssqlConnect()
+func TestMSSQLGetAttr(t *testing.T) {
+	dc, err := drv.Open(newConnParams().makeODBCConnectionString())
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer closeDB(t, db, sc, sc)
-
-	db.Exec("drop table dbo.temp")
-	exec(t, db, "create table dbo.temp (id int, a varchar(255))")
+	defer dc.Close()
+	c := dc.(*Conn)
 
-	tx, err := db.Begin()
+	v, err := c.GetAttr(api.SQL_ATTR_AUTOCOMMIT)
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	stmt, err := tx.Prepare(`
-DECLARE @id INT, @a VARCHAR(255)
-SELECT @id = ?, @a = ?
-UPDATE dbo.temp SET a = @a WHERE id = @id
-IF @@ROWCOUNT = 0
-  INSERT INTO dbo.temp (id, a) VALUES (@id, @a)
-`)
+	if v != api.SQL_AUTOCOMMIT_ON {
+		t.Errorf("expect autocommit on, but got %d", v)
+	}
+	tx, err := c.Begin()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if _, err = stmt.Exec(1, "test"); err != nil {
-		t.Errorf("Failed to insert record with ID 1: %s", err)
+	v, err = c.GetAttr(api.SQL_ATTR_AUTOCOMMIT)
+	if err != nil {
+		t.Fatal(err)
 	}
-	if _, err = stmt.Exec(1, "test2"); err != nil {
-		t.Errorf("Failed to update record with ID 1: %s", err)
+	if v != api.SQL_AUTOCOMMIT_OFF {
+		t.Errorf("expect autocommit off inside transaction, but got %d", v)
 	}
-
-	if err = tx.Commit(); err != nil {
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Ping(context.Background()); err != nil {
 		t.Fatal(err)
 	}
 }
 
-func TestMSSQLQueryContextTimeout(t *testing.T) {
-	db, sc, err := mssqlConnect()
+func TestMSSQLPingStmtCount(t *testing.T) {
+	dc, err := drv.Open(newConnParams().makeODBCConnectionString())
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer closeDB(t, db, sc, sc)
-
-	contextTimeout := time.Millisecond * 500
-	queryWaitFor := time.Second * 1
+	defer dc.Close()
+	c := dc.(*Conn)
+	ctx := context.Background()
 
-	ctx, cancel := context.WithTimeout(context.Background(), contextTimeout)
-	defer cancel()
-
-	start := time.Now()
-	_, err = db.QueryContext(ctx, "WAITFOR DELAY '00:01';")
-	elapsed := time.Since(start)
+	for _, noDeadAttr := range []bool{false, true} {
+		// pretend driver does not support SQL_ATTR_CONNECTION_DEAD
+		c.noDeadAttr = noDeadAttr
+		if err := c.Ping(ctx); err != nil {
+			t.Fatal(err)
+		}
+		_, _, before := drv.Counts()
+		for i := 0; i < 100; i++ {
+			if err := c.Ping(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+		_, _, after := drv.Counts()
+		if after != before {
+			t.Errorf("noDeadAttr=%v: statement count changed from %d to %d", noDeadAttr, before, after)
+		}
+	}
+}
 
-	if err == nil {
-		t.Fatal("Unexpected success, expected error")
+func TestMSSQLPingQuery(t *testing.T) {
+	var tests = []struct {
+		dbms, want string
+	}{
+		{"Microsoft SQL Server", "select 1"},
+		{"Oracle", "select 1 from dual"},
+		{"DB2/LINUXX8664", "select 1 from sysibm.sysdummy1"},
+		{"PostgreSQL", "select 1"},
 	}
-	if err != context.DeadlineExceeded {
-		t.Fatalf("Unexpected error value: should=%s, is=%s", context.DeadlineExceeded, err)
+	for _, test := range tests {
+		if got := defaultPingQuery(test.dbms); got != test.want {
+			t.Errorf("defaultPingQuery(%q): expect %q, but got %q", test.dbms, test.want, got)
+		}
 	}
-	if elapsed > queryWaitFor {
-		t.Fatalf("Unexpected query duration: should=>%s, is=%s", queryWaitFor, elapsed)
+
+	params := newConnParams()
+	params["ping_query"] = "select 1 from no_such_table"
+	dc, err := drv.Open(params.makeODBCConnectionString())
+	if err != nil {
+		t.Fatal(err)
 	}
-	if elapsed < contextTimeout {
-		t.Fatalf("Query did not delay: should=<%s, is=%s", contextTimeout, elapsed)
+	defer dc.Close()
+	c := dc.(*Conn)
+	// pretend driver does not support SQL_ATTR_CONNECTION_DEAD
+	c.noDeadAttr = true
+	if err := c.Ping(context.Background()); err == nil {
+		t.Fatal("Ping should execute ping_query and fail, but succeeded")
 	}
 }
-func TestMSSQLQueryContextCancel(t *testing.T) {
-	db, sc, err := mssqlConnect()
+
+func TestMSSQLIsValid(t *testing.T) {
+	dc, err := drv.Open(newConnParams().makeODBCConnectionString())
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer closeDB(t, db, sc, sc)
+	defer dc.Close()
+	c := dc.(*Conn)
 
-	ctx, cancel := context.WithCancel(context.Background())
+	if !c.IsValid() {
+		t.Fatal("new connection is not valid")
+	}
+	c.bad.Store(true)
+	if c.IsValid() {
+		t.Error("broken connection is valid")
+	}
+}
 
-	cancel()
-	_, err = db.QueryContext(ctx, "WAITFOR DELAY '00:01';")
+func TestMSSQLConnBusy(t *testing.T) {
+	dc, err := drv.Open(newConnParams().makeODBCConnectionString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dc.Close()
+
+	st1, err := dc.Prepare("select 1 union all select 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st1.Close()
+	rows, err := st1.Query(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
 
+	// results of st1 are still pending
+	st2, err := dc.Prepare("select 3")
 	if err == nil {
-		t.Fatal("Unexpected success, expected error")
+		defer st2.Close()
+		_, err = st2.Query(nil)
 	}
-	if err != context.Canceled {
-		t.Fatalf("Unexpected error value: should=%s, is=%s", context.Canceled, err)
+	if err == nil {
+		t.Skip("skipping test; driver allows multiple active statements")
+	}
+	if !errors.Is(err, ErrConnBusy) {
+		t.Errorf("expect ErrConnBusy, but got %v", err)
 	}
 }
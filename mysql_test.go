@@ -6,6 +6,7 @@ package odbc
 
 import (
 	"database/sql"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"testing"
@@ -58,3 +59,84 @@ func TestMYSQLTime(t *testing.T) {
 
 	exec(t, db, "drop table temp")
 }
+
+func TestMYSQLTinyint(t *testing.T) {
+	db, sc, err := mysqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	db.Exec("drop table temp")
+	exec(t, db, "create table temp(id int not null auto_increment primary key, s tinyint, u tinyint unsigned)")
+	tests := []struct {
+		s, u int64
+	}{
+		{-128, 0},
+		{127, 127},
+		{-1, 128},
+		{0, 255},
+	}
+	for _, test := range tests {
+		_, err = db.Exec("insert into temp (s, u) values(?, ?)", test.s, test.u)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, test := range tests {
+		var s, u interface{}
+		if err := db.QueryRow("select s, u from temp where id = ?", i+1).Scan(&s, &u); err != nil {
+			t.Fatal(err)
+		}
+		if s != int32(test.s) || u != int32(test.u) {
+			t.Errorf("unexpected return value: want=(%d, %d), is=(%v, %v)", test.s, test.u, s, u)
+		}
+	}
+
+	exec(t, db, "drop table temp")
+}
+
+func TestMYSQLJSON(t *testing.T) {
+	db, sc, err := mysqlConnect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, db, sc, sc)
+
+	db.Exec("drop table temp")
+	exec(t, db, "create table temp(id int not null auto_increment primary key, doc json)")
+	defer exec(t, db, "drop table temp")
+
+	const doc = `{"name": "Grüße"}`
+	_, err = db.Exec("insert into temp (doc) values(?)", doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := db.Query("select doc from temp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	cts, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := cts[0].DatabaseTypeName(); name != "JSON" {
+		t.Errorf("unexpected database type name: want=%q, is=%q", "JSON", name)
+	}
+	if !rows.Next() {
+		t.Fatal(rows.Err())
+	}
+	var ret json.RawMessage
+	if err := rows.Scan(&ret); err != nil {
+		t.Fatal(err)
+	}
+	var v struct{ Name string }
+	if err := json.Unmarshal(ret, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Name != "Grüße" {
+		t.Errorf("unexpected return value: want=%q, is=%q", "Grüße", v.Name)
+	}
+}
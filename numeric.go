@@ -0,0 +1,133 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package odbc
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"unsafe"
+
+	"github.com/alexbrainman/odbc/api"
+)
+
+// Numeric is exact decimal parameter, that is bound as
+// SQL_NUMERIC_STRUCT (SQL_C_NUMERIC) instead of text, so its
+// value does not depend on how driver parses numbers. Use
+// NewNumeric to create Numeric values.
+type Numeric api.SQL_NUMERIC_STRUCT
+
+// NewNumeric converts decimal number s, like "-123.45", into Numeric.
+func NewNumeric(s string) (Numeric, error) {
+	var n Numeric
+	digits := s
+	n.Sign = 1
+	if strings.HasPrefix(digits, "-") {
+		n.Sign = 0
+		digits = digits[1:]
+	}
+	digits = strings.Replace(digits, ".", "", 1)
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return n, fmt.Errorf("invalid decimal value %q", s)
+	}
+	precision, scale := decimalPrecision(s)
+	if precision > maxDecimalPrecision {
+		return n, fmt.Errorf("decimal value %q has more than %d digits", s, maxDecimalPrecision)
+	}
+	v, _ := new(big.Int).SetString(digits, 10)
+	b := v.Bytes()
+	if len(b) > len(n.Val) {
+		return n, fmt.Errorf("decimal value %q is too large", s)
+	}
+	// Val is little endian, but big.Int.Bytes are big endian
+	for i, c := range b {
+		n.Val[len(b)-1-i] = c
+	}
+	n.Precision = uint8(precision)
+	n.Scale = int8(scale)
+	return n, nil
+}
+
+// String returns n as decimal number, like "-123.45".
+func (n Numeric) String() string {
+	return numericString((*api.SQL_NUMERIC_STRUCT)(&n))
+}
+
+// numericString converts n into exact decimal text representation.
+func numericString(n *api.SQL_NUMERIC_STRUCT) string {
+	var b [api.SQL_MAX_NUMERIC_LEN]byte
+	for i, c := range n.Val {
+		b[len(b)-1-i] = c
+	}
+	v := new(big.Int).SetBytes(b[:])
+	if v.Sign() == 0 {
+		// keep scale digits, like "0.00", but never "-0"
+		if n.Scale <= 0 {
+			return "0"
+		}
+		return "0." + strings.Repeat("0", int(n.Scale))
+	}
+	s := v.String()
+	switch scale := int(n.Scale); {
+	case scale > 0:
+		if len(s) <= scale {
+			s = strings.Repeat("0", scale-len(s)+1) + s
+		}
+		s = s[:len(s)-scale] + "." + s[len(s)-scale:]
+	case scale < 0:
+		s += strings.Repeat("0", -scale)
+	}
+	if n.Sign == 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// setNumericDesc sets precision and scale of SQL_C_NUMERIC parameter
+// or column idx. Drivers use default precision and zero scale for
+// SQL_NUMERIC_STRUCT values otherwise. descAttr selects application
+// parameter (SQL_ATTR_APP_PARAM_DESC) or row (SQL_ATTR_APP_ROW_DESC)
+// descriptor. Setting SQL_DESC_TYPE unbinds data, so data is bound
+// again, unless it is nil.
+func setNumericDesc(h api.SQLHSTMT, descAttr int32, idx int, precision, scale int, data unsafe.Pointer) error {
+	desc, err := stmtDesc(h, descAttr)
+	if err != nil {
+		return err
+	}
+	rec := api.SQLSMALLINT(idx + 1)
+	fields := []struct {
+		id api.SQLSMALLINT
+		v  uintptr
+	}{
+		{api.SQL_DESC_TYPE, uintptr(api.SQL_C_NUMERIC)},
+		{api.SQL_DESC_PRECISION, uintptr(precision)},
+		{api.SQL_DESC_SCALE, uintptr(scale)},
+	}
+	for _, f := range fields {
+		ret := api.SQLSetDescUIntPtrField(desc, rec, f.id, f.v, 0)
+		if IsError(ret) {
+			return NewError("SQLSetDescUIntPtrField", desc)
+		}
+	}
+	if data == nil {
+		return nil
+	}
+	ret := api.SQLSetDescField(desc, rec, api.SQL_DESC_DATA_PTR, api.SQLPOINTER(data), 0)
+	if IsError(ret) {
+		return NewError("SQLSetDescField", desc)
+	}
+	return nil
+}
+
+// stmtDesc returns descriptor of statement h, selected
+// by descAttr, like SQL_ATTR_APP_PARAM_DESC.
+func stmtDesc(h api.SQLHSTMT, descAttr int32) (api.SQLHDESC, error) {
+	var desc api.SQLHDESC
+	ret := api.SQLGetStmtAttr(h, api.SQLINTEGER(descAttr), api.SQLPOINTER(unsafe.Pointer(&desc)), 0, nil)
+	if IsError(ret) {
+		return desc, NewError("SQLGetStmtAttr", h)
+	}
+	return desc, nil
+}
@@ -5,6 +5,7 @@
 package odbc
 
 import (
+	"context"
 	"database/sql/driver"
 	"errors"
 	"fmt"
@@ -17,10 +18,26 @@ import (
 
 // TODO(brainman): see if I could use SQLExecDirect anywhere
 
+// ErrNoResultSet is returned when statement, that is expected to
+// produce rows, did not create a result set. Query does not return
+// it, but returns empty rows instead.
+var ErrNoResultSet = errors.New("Stmt did not create a result set")
+
 type ODBCStmt struct {
 	h          api.SQLHSTMT
+	drv        *Driver // counts h, see Conn.drv
 	Parameters []Parameter
 	Cols       []Column
+	opts       connOptions // options of connection that created s
+	// jsonTypes is set, if DBMS reports JSON columns
+	// as character columns, see Conn.hasJSONType.
+	jsonTypes bool
+	// streamLongData makes Rows.Next return io.Reader
+	// for last unbound column (see WithStreamedLongData).
+	streamLongData bool
+	fetches        int // number of SQLFetch calls made by Rows.Next
+	// updatable is set, if s cursor allows positioned updates.
+	updatable bool
 	// locking/lifetime
 	mu         sync.Mutex
 	usedByStmt bool
@@ -28,35 +45,110 @@ type ODBCStmt struct {
 }
 
 func (c *Conn) PrepareODBCStmt(query string) (*ODBCStmt, error) {
+	return c.prepareODBCStmt(context.Background(), query, false)
+}
+
+// prepareODBCStmt prepares query. Statement cursor is made
+// updatable, if updatable is set (see WithUpdatableCursor).
+// Preparation is cancelled, once ctx is done, see prepare.
+func (c *Conn) prepareODBCStmt(ctx context.Context, query string, updatable bool) (*ODBCStmt, error) {
 	var out api.SQLHANDLE
 	ret := api.SQLAllocHandle(api.SQL_HANDLE_STMT, api.SQLHANDLE(c.h), &out)
 	if IsError(ret) {
 		return nil, c.newError("SQLAllocHandle", c.h)
 	}
 	h := api.SQLHSTMT(out)
-	err := drv.Stats.updateHandleCount(api.SQL_HANDLE_STMT, 1)
+	err := c.drv.Stats.updateHandleCount(api.SQL_HANDLE_STMT, out, 1)
 	if err != nil {
 		return nil, err
 	}
 
-	b := api.StringToUTF16(query)
-	ret = api.SQLPrepare(h, (*api.SQLWCHAR)(unsafe.Pointer(&b[0])), api.SQL_NTS)
-	if IsError(ret) {
-		defer releaseHandle(h)
-		return nil, c.newError("SQLPrepare", h)
+	if updatable {
+		// Cursor attributes cannot be changed after statement is prepared.
+		if err := setUpdatableCursor(h); err != nil {
+			defer c.drv.releaseHandle(h)
+			return nil, err
+		}
 	}
-	ps, err := ExtractParameters(h)
+	ps, err := c.prepare(ctx, h, query)
 	if err != nil {
-		defer releaseHandle(h)
 		return nil, err
 	}
+	c.setProcParamDirections(query, ps)
 	return &ODBCStmt{
 		h:          h,
+		drv:        c.drv,
 		Parameters: ps,
+		opts:       c.opts,
+		jsonTypes:  c.hasJSONType(),
+		updatable:  updatable,
 		usedByStmt: true,
 	}, nil
 }
 
+// prepare calls SQLPrepare for statement h and describes its
+// parameters. If ctx is done first, the statement is cancelled with
+// SQLCancel, and prepare waits for SQLPrepare to return, so h can
+// be released. If it does not return promptly, the connection is
+// marked bad, and h is released in the background, once SQLPrepare
+// returns, like cancelQuery does. h is released, if prepare fails.
+func (c *Conn) prepare(ctx context.Context, h api.SQLHSTMT, query string) ([]Parameter, error) {
+	run := func() ([]Parameter, error) {
+		if c.hooks.beforePrepare != nil {
+			c.hooks.beforePrepare(query)
+		}
+		b := api.StringToUTF16(query)
+		ret := api.SQLPrepare(h, (*api.SQLWCHAR)(unsafe.Pointer(&b[0])), api.SQL_NTS)
+		if IsError(ret) {
+			return nil, c.newError("SQLPrepare", h)
+		}
+		return extractParameters(h, c.describeParam)
+	}
+	if ctx.Done() == nil {
+		// ctx is never cancelled
+		ps, err := run()
+		if err != nil {
+			c.drv.releaseHandle(h)
+		}
+		return ps, err
+	}
+	if err := ctx.Err(); err != nil {
+		c.drv.releaseHandle(h)
+		return nil, err
+	}
+
+	type result struct {
+		ps  []Parameter
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		ps, err := run()
+		done <- result{ps, err}
+	}()
+	select {
+	case r := <-done:
+		if r.err != nil {
+			c.drv.releaseHandle(h)
+		}
+		return r.ps, r.err
+	case <-ctx.Done():
+	}
+	api.SQLCancel(h)
+	t := time.NewTimer(queryCancelWait)
+	defer t.Stop()
+	select {
+	case <-done:
+		c.drv.releaseHandle(h)
+	case <-t.C:
+		c.abandon(func() {
+			<-done
+			c.drv.releaseHandle(h)
+		})
+	}
+	return nil, ctx.Err()
+}
+
 func (s *ODBCStmt) closeByStmt() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -75,6 +167,14 @@ func (s *ODBCStmt) closeByRows() error {
 	if s.usedByRows {
 		defer func() { s.usedByRows = false }()
 		if s.usedByStmt {
+			if len(s.Cols) == 0 {
+				// there is no cursor to close
+				ret := api.SQLFreeStmt(s.h, api.SQL_CLOSE)
+				if IsError(ret) {
+					return NewError("SQLFreeStmt", s.h)
+				}
+				return nil
+			}
 			ret := api.SQLCloseCursor(s.h)
 			if IsError(ret) {
 				return NewError("SQLCloseCursor", s.h)
@@ -90,12 +190,47 @@ func (s *ODBCStmt) closeByRows() error {
 func (s *ODBCStmt) releaseHandle() error {
 	h := s.h
 	s.h = api.SQLHSTMT(api.SQL_NULL_HSTMT)
-	return releaseHandle(h)
+	return s.drv.releaseHandle(h)
 }
 
-var testingIssue5 bool // used during tests
+// setAttr sets integer statement attribute attr to v.
+func (s *ODBCStmt) setAttr(attr int32, v uintptr) error {
+	ret := api.SQLSetStmtUIntPtrAttr(s.h, api.SQLINTEGER(attr), v, api.SQL_IS_UINTEGER)
+	if IsError(ret) {
+		return NewError("SQLSetStmtUIntPtrAttr", s.h)
+	}
+	return nil
+}
+
+// getAttr returns value of integer statement attribute attr.
+func (s *ODBCStmt) getAttr(attr int32) (uintptr, error) {
+	var v uintptr
+	ret := api.SQLGetStmtAttr(s.h, api.SQLINTEGER(attr), api.SQLPOINTER(unsafe.Pointer(&v)), 0, nil)
+	if IsError(ret) {
+		return 0, NewError("SQLGetStmtAttr", s.h)
+	}
+	return v, nil
+}
 
 func (s *ODBCStmt) Exec(args []driver.Value, conn *Conn) error {
+	if err := s.bindArgs(args, conn); err != nil {
+		return err
+	}
+	if conn.hooks.beforeExecute != nil {
+		conn.hooks.beforeExecute()
+	}
+	ret := api.SQLExecute(s.h)
+	if ret == api.SQL_NO_DATA {
+		// success but no data to report
+		return nil
+	}
+	if IsError(ret) {
+		return NewError("SQLExecute", s.h)
+	}
+	return nil
+}
+
+func (s *ODBCStmt) bindArgs(args []driver.Value, conn *Conn) error {
 	if len(args) != len(s.Parameters) {
 		return fmt.Errorf("wrong number of arguments %d, %d expected", len(args), len(s.Parameters))
 	}
@@ -109,18 +244,126 @@ func (s *ODBCStmt) Exec(args []driver.Value, conn *Conn) error {
 			return err
 		}
 	}
-	if testingIssue5 {
-		time.Sleep(10 * time.Microsecond)
+	return nil
+}
+
+// Delays between SQLExecute calls, while asynchronous statement
+// is still executing.
+const (
+	asyncPollMinDelay = time.Millisecond
+	asyncPollMaxDelay = 50 * time.Millisecond
+)
+
+// enableAsync switches s into asynchronous mode, where SQLExecute
+// returns SQL_STILL_EXECUTING instead of waiting for the statement
+// to complete. It returns false, if driver does not support that.
+func (s *ODBCStmt) enableAsync() bool {
+	if err := s.setAttr(api.SQL_ATTR_ASYNC_ENABLE, api.SQL_ASYNC_ENABLE_ON); err != nil {
+		return false
 	}
+	// driver can substitute similar value with SQLSTATE 01S02
+	v, err := s.getAttr(api.SQL_ATTR_ASYNC_ENABLE)
+	return err == nil && v == api.SQL_ASYNC_ENABLE_ON
+}
+
+// execAsync is like Exec, but s must be in asynchronous mode.
+// It polls the statement with SQLExecute until it completes, and
+// calls SQLCancel once ctx is done, so no extra goroutine is needed
+// to cancel it. s is switched back into synchronous mode afterwards,
+// so rows are fetched as usual.
+func (s *ODBCStmt) execAsync(ctx context.Context, args []driver.Value, conn *Conn) error {
+	if err := s.bindArgs(args, conn); err != nil {
+		return err
+	}
+	cancelled := false
+	delay := asyncPollMinDelay
 	ret := api.SQLExecute(s.h)
-	if ret == api.SQL_NO_DATA {
-		// success but no data to report
+	for ret == api.SQL_STILL_EXECUTING {
+		if !cancelled && ctx.Err() != nil {
+			// SQLExecute will fail with SQLSTATE HY008 now
+			api.SQLCancel(s.h)
+			cancelled = true
+		}
+		time.Sleep(delay)
+		if delay *= 2; delay > asyncPollMaxDelay {
+			delay = asyncPollMaxDelay
+		}
+		ret = api.SQLExecute(s.h)
+	}
+	var err error
+	if ret != api.SQL_NO_DATA && IsError(ret) {
+		err = NewError("SQLExecute", s.h)
+	}
+	if err2 := s.setAttr(api.SQL_ATTR_ASYNC_ENABLE, api.SQL_ASYNC_ENABLE_OFF); err == nil {
+		err = err2
+	}
+	if cancelled {
+		return ctx.Err()
+	}
+	return err
+}
+
+// storeOutParams stores values of output parameters into their
+// destinations. It must be called after all results are processed,
+// because drivers return output parameters last.
+func (s *ODBCStmt) storeOutParams() {
+	for i := range s.Parameters {
+		s.Parameters[i].storeOut()
+	}
+}
+
+// rebindColumns prepares columns of s for next result set. Columns
+// of previous result set are reused (they are still bound), if new
+// result set has the same number of columns of the same type, size
+// and decimal digits. Otherwise all columns are unbound, described and bound again.
+func (s *ODBCStmt) rebindColumns() error {
+	same, err := s.sameShape()
+	if err != nil {
+		return err
+	}
+	if same {
 		return nil
 	}
+	if len(s.Cols) > 0 {
+		ret := api.SQLFreeStmt(s.h, api.SQL_UNBIND)
+		if IsError(ret) {
+			return NewError("SQLFreeStmt", s.h)
+		}
+	}
+	return s.BindColumns()
+}
+
+// sameShape reports whether current result set of s has the same
+// columns as s.Cols. Column names are updated, if they differ.
+func (s *ODBCStmt) sameShape() (bool, error) {
+	var n api.SQLSMALLINT
+	ret := api.SQLNumResultCols(s.h, &n)
 	if IsError(ret) {
-		return NewError("SQLExecute", s.h)
+		return false, NewError("SQLNumResultCols", s.h)
 	}
-	return nil
+	if n < 1 || int(n) != len(s.Cols) {
+		return false, nil
+	}
+	namebuf := make([]uint16, s.opts.nameBufferSize())
+	names := make([]string, n)
+	for i, c := range s.Cols {
+		b := baseColumn(c)
+		if b == nil {
+			return false, nil
+		}
+		namelen, sqltype, size, decimal, ret := describeColumn(s.h, i, namebuf)
+		if IsError(ret) {
+			return false, NewError("SQLDescribeCol", s.h)
+		}
+		if sqltype != b.SQLType || size != b.size || decimal != b.decimal || namelen > len(namebuf) {
+			return false, nil
+		}
+		names[i] = api.UTF16ToString(namebuf[:namelen])
+	}
+	for i, c := range s.Cols {
+		baseColumn(c).name = names[i]
+	}
+	return true, nil
 }
 
 func (s *ODBCStmt) BindColumns() error {
@@ -131,13 +374,14 @@ func (s *ODBCStmt) BindColumns() error {
 		return NewError("SQLNumResultCols", s.h)
 	}
 	if n < 1 {
-		return errors.New("Stmt did not create a result set")
+		s.Cols = nil
+		return ErrNoResultSet
 	}
 	// fetch column descriptions
 	s.Cols = make([]Column, n)
 	binding := true
 	for i := range s.Cols {
-		c, err := NewColumn(s.h, i)
+		c, err := newColumn(s.h, i, &s.opts, s.jsonTypes)
 		if err != nil {
 			return err
 		}
@@ -160,6 +404,12 @@ func (s *ODBCStmt) BindColumns() error {
 }
 
 func (s *ODBCStmt) Cancel() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.usedByStmt && !s.usedByRows {
+		// handle is released
+		return errStmtClosed
+	}
 	ret := api.SQLCancel(s.h)
 	if IsError(ret) {
 		return NewError("SQLCancel", s.h)
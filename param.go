@@ -5,19 +5,74 @@
 package odbc
 
 import (
+	"database/sql"
 	"database/sql/driver"
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
 	"time"
 	"unsafe"
 
 	"github.com/alexbrainman/odbc/api"
 )
 
+// Decimal is implemented by arbitrary-precision decimal types, like
+// github.com/shopspring/decimal.Decimal. Parameters of that type are
+// bound as text, so the server parses exact decimal value.
+type Decimal interface {
+	// String returns decimal value without exponent, like "-123.45".
+	String() string
+	Exponent() int32
+}
+
+// Typed is a parameter value, that is bound with SQLType instead
+// of SQL type chosen by the driver. Non zero Size and Decimal
+// replace parameter column size and decimal digits too. Use it
+// for ODBC drivers that do not accept default choice, like
+//
+//	db.Exec("insert into t values (?)", odbc.Typed{Value: s, SQLType: api.SQL_VARCHAR})
+type Typed struct {
+	Value   interface{}
+	SQLType api.SQLSMALLINT
+	Size    api.SQLULEN
+	Decimal api.SQLSMALLINT
+}
+
+// XML is a string parameter, that is bound as SQL Server xml type,
+// so server validates the document, like
+//
+//	db.Exec("insert into t values (?)", odbc.XML("<root>hello</root>"))
+//
+// xml columns are returned as UTF-8 text and can be scanned into
+// string or []byte.
+type XML string
+
+// JSON is a parameter, that is marshaled with json.Marshal and bound
+// as text, so maps, slices and structs can be stored in JSON columns
+// (like SQL Server nvarchar with JSON text, or PostgreSQL and MySQL
+// json) without extra code, like
+//
+//	db.Exec("insert into t (doc) values (?)", odbc.JSON{Value: map[string]int{"a": 1}})
+//
+// Values, that implement json.Marshaler, are passed as JSON text
+// without the wrapper.
+type JSON struct {
+	Value interface{}
+}
+
 type Parameter struct {
 	SQLType     api.SQLSMALLINT
 	Decimal     api.SQLSMALLINT
 	Size        api.SQLULEN
 	isDescribed bool
+	// direction is procedure parameter direction, like
+	// SQL_PARAM_OUTPUT, or 0 if unknown.
+	direction api.SQLSMALLINT
+	// out is sql.Out destination, if parameter is bound for output.
+	out interface{}
 	// Following fields store data used later by SQLExecute.
 	// The fields keep data alive and away from gc.
 	Data             interface{}
@@ -32,6 +87,46 @@ func (p *Parameter) StoreStrLen_or_IndPtr(v api.SQLLEN) *api.SQLLEN {
 
 }
 
+// isNilPointer reports whether v is nil pointer of any type.
+func isNilPointer(v driver.Value) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+// basicTypes are types, that values of named types
+// (like type Status int) are converted to by basicValue.
+var basicTypes = map[reflect.Kind]reflect.Type{
+	reflect.Bool:    reflect.TypeOf(false),
+	reflect.Int:     reflect.TypeOf(int(0)),
+	reflect.Int8:    reflect.TypeOf(int8(0)),
+	reflect.Int16:   reflect.TypeOf(int16(0)),
+	reflect.Int32:   reflect.TypeOf(int32(0)),
+	reflect.Int64:   reflect.TypeOf(int64(0)),
+	reflect.Uint:    reflect.TypeOf(uint(0)),
+	reflect.Uint8:   reflect.TypeOf(uint8(0)),
+	reflect.Uint16:  reflect.TypeOf(uint16(0)),
+	reflect.Uint32:  reflect.TypeOf(uint32(0)),
+	reflect.Uint64:  reflect.TypeOf(uint64(0)),
+	reflect.Float32: reflect.TypeOf(float32(0)),
+	reflect.Float64: reflect.TypeOf(float64(0)),
+	reflect.String:  reflect.TypeOf(""),
+}
+
+// basicValue converts v of named type, like type Status int,
+// into value of its underlying type, like int. It reports
+// false, if v underlying type is not boolean, number, string
+// or []byte.
+func basicValue(v driver.Value) (driver.Value, bool) {
+	rv := reflect.ValueOf(v)
+	if t, ok := basicTypes[rv.Kind()]; ok && rv.Type() != t {
+		return rv.Convert(t).Interface(), true
+	}
+	if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 && rv.Type() != reflect.TypeOf([]byte(nil)) {
+		return rv.Bytes(), true
+	}
+	return nil, false
+}
+
 func (p *Parameter) BindValue(h api.SQLHSTMT, idx int, v driver.Value, conn *Conn) error {
 	// TODO(brainman): Reuse memory for previously bound values. If memory
 	// is reused, we, probably, do not need to call SQLBindParameter either.
@@ -40,7 +135,44 @@ func (p *Parameter) BindValue(h api.SQLHSTMT, idx int, v driver.Value, conn *Con
 	var buflen api.SQLLEN
 	var plen *api.SQLLEN
 	var buf unsafe.Pointer
+	p.out = nil
+	typed, isTyped := v.(Typed)
+	if isTyped {
+		v = typed.Value
+	}
+	if isNilPointer(v) {
+		// direct driver users can pass typed nil, like (*string)(nil)
+		v = nil
+	}
+	// Integers are bound as int16, int32 or int64,
+	// whichever is the smallest to fit every value.
+	switch d := v.(type) {
+	case int8:
+		v = int16(d)
+	case uint8:
+		v = int16(d)
+	case uint16:
+		v = int32(d)
+	case int:
+		v = int64(d)
+	case uint32:
+		v = int64(d)
+	case uint:
+		if uint64(d) > math.MaxInt64 {
+			return fmt.Errorf("uint value %d is too large", d)
+		}
+		v = int64(d)
+	case uint64:
+		if d > math.MaxInt64 {
+			return fmt.Errorf("uint64 value %d is too large", d)
+		}
+		v = int64(d)
+	}
 	switch d := v.(type) {
+	case sql.Out:
+		return p.bindOut(h, idx, d)
+	case TVP:
+		return p.bindTVP(h, idx, d)
 	case nil:
 		ctype = api.SQL_C_WCHAR
 		p.Data = nil
@@ -63,6 +195,8 @@ func (p *Parameter) BindValue(h api.SQLHSTMT, idx int, v driver.Value, conn *Con
 		}
 		l *= 2 // every char takes 2 bytes
 		buflen = api.SQLLEN(l)
+		// Always pass exact length (0 for empty string), not SQL_NTS,
+		// so empty string is stored as zero-length value, not NULL.
 		plen = p.StoreStrLen_or_IndPtr(buflen)
 		if !conn.isMSAccessDriver {
 			switch {
@@ -80,6 +214,18 @@ func (p *Parameter) BindValue(h api.SQLHSTMT, idx int, v driver.Value, conn *Con
 			// https://docs.microsoft.com/en-us/sql/odbc/microsoft/microsoft-access-data-types
 			sqltype = api.SQL_WLONGVARCHAR
 		}
+	case int16:
+		ctype = api.SQL_C_SHORT
+		p.Data = &d
+		buf = unsafe.Pointer(&d)
+		sqltype = api.SQL_SMALLINT
+		size = 2
+	case int32:
+		ctype = api.SQL_C_LONG
+		p.Data = &d
+		buf = unsafe.Pointer(&d)
+		sqltype = api.SQL_INTEGER
+		size = 4
 	case int64:
 		if -0x80000000 < d && d < 0x7fffffff {
 			// Some ODBC drivers do not support SQL_BIGINT.
@@ -99,6 +245,20 @@ func (p *Parameter) BindValue(h api.SQLHSTMT, idx int, v driver.Value, conn *Con
 			size = 8
 		}
 	case bool:
+		if p.isDescribed && isIntegerSQLType(p.SQLType) {
+			// Some drivers (like FreeTDS) refuse to convert
+			// SQL_BIT into integer columns. Pass 0 or 1 instead.
+			var d2 int32
+			if d {
+				d2 = 1
+			}
+			ctype = api.SQL_C_LONG
+			p.Data = &d2
+			buf = unsafe.Pointer(&d2)
+			sqltype = api.SQL_INTEGER
+			size = 4
+			break
+		}
 		var b byte
 		if d {
 			b = 1
@@ -114,7 +274,44 @@ func (p *Parameter) BindValue(h api.SQLHSTMT, idx int, v driver.Value, conn *Con
 		buf = unsafe.Pointer(&d)
 		sqltype = api.SQL_DOUBLE
 		size = 8
+	case float32:
+		ctype = api.SQL_C_FLOAT
+		p.Data = &d
+		buf = unsafe.Pointer(&d)
+		sqltype = api.SQL_REAL
+		size = 4
 	case time.Time:
+		if p.isDescribed && p.SQLType == api.SQL_SS_TIME2 || isTyped && typed.SQLType == api.SQL_SS_TIME2 {
+			// SQL Server TIME(n) parameter is bound as
+			// SQL_SS_TIME2_STRUCT to keep fractional seconds.
+			ctype = api.SQL_C_BINARY
+			b := api.SQL_SS_TIME2_STRUCT{
+				Hour:     api.SQLUSMALLINT(d.Hour()),
+				Minute:   api.SQLUSMALLINT(d.Minute()),
+				Second:   api.SQLUSMALLINT(d.Second()),
+				Fraction: api.SQLUINTEGER(d.Nanosecond()),
+			}
+			p.Data = &b
+			buf = unsafe.Pointer(&b)
+			buflen = api.SQLLEN(unsafe.Sizeof(b))
+			plen = p.StoreStrLen_or_IndPtr(buflen)
+			sqltype = api.SQL_SS_TIME2
+			decimal = 7
+			if p.isDescribed && p.SQLType == api.SQL_SS_TIME2 {
+				decimal = p.Decimal
+			}
+			if isTyped && typed.Decimal > 0 {
+				decimal = typed.Decimal
+			}
+			unit := api.SQLUINTEGER(math.Pow10(9 - int(decimal)))
+			b.Fraction -= b.Fraction % unit
+			// hh:mm:ss[.fffffff]
+			size = 8
+			if decimal > 0 {
+				size = 9 + api.SQLULEN(decimal)
+			}
+			break
+		}
 		ctype = api.SQL_C_TYPE_TIMESTAMP
 		y, m, day := d.Date()
 		b := api.SQL_TIMESTAMP_STRUCT{
@@ -133,10 +330,57 @@ func (p *Parameter) BindValue(h api.SQLHSTMT, idx int, v driver.Value, conn *Con
 			decimal = p.Decimal
 		}
 		if decimal <= 0 {
-			// represented as yyyy-mm-dd hh:mm:ss.fff format in ms sql server
-			decimal = 3
+			// Parameter is not described, so keep as many
+			// fractional second digits as d has.
+			decimal = timeFractionDigits(d.Nanosecond())
+		}
+		if decimal < 9 {
+			// Drivers refuse fractions, that do not fit decimal digits.
+			unit := api.SQLUINTEGER(math.Pow10(9 - int(decimal)))
+			b.Fraction -= b.Fraction % unit
 		}
 		size = 20 + api.SQLULEN(decimal)
+	case *big.Int, *big.Rat, Decimal:
+		s, err := decimalString(d)
+		if err != nil {
+			return err
+		}
+		ctype = api.SQL_C_CHAR
+		b := append([]byte(s), 0)
+		p.Data = b
+		buf = unsafe.Pointer(&b[0])
+		buflen = api.SQLLEN(len(s))
+		plen = p.StoreStrLen_or_IndPtr(buflen)
+		size, decimal = decimalPrecision(s)
+		if p.isDescribed && (p.SQLType == api.SQL_NUMERIC || p.SQLType == api.SQL_DECIMAL) {
+			sqltype = p.SQLType
+		} else {
+			sqltype = api.SQL_DECIMAL
+		}
+	case Numeric:
+		ctype = api.SQL_C_NUMERIC
+		n := api.SQL_NUMERIC_STRUCT(d)
+		p.Data = &n
+		buf = unsafe.Pointer(&n)
+		buflen = api.SQLLEN(unsafe.Sizeof(n))
+		plen = p.StoreStrLen_or_IndPtr(buflen)
+		size, decimal = api.SQLULEN(n.Precision), api.SQLSMALLINT(n.Scale)
+		if p.isDescribed && (p.SQLType == api.SQL_NUMERIC || p.SQLType == api.SQL_DECIMAL) {
+			sqltype = p.SQLType
+		} else {
+			sqltype = api.SQL_NUMERIC
+		}
+	case Date:
+		ctype = api.SQL_C_DATE
+		b := api.SQL_DATE_STRUCT{
+			Year:  api.SQLSMALLINT(d.Year),
+			Month: api.SQLUSMALLINT(d.Month),
+			Day:   api.SQLUSMALLINT(d.Day),
+		}
+		p.Data = &b
+		buf = unsafe.Pointer(&b)
+		sqltype = api.SQL_TYPE_DATE
+		size = 10
 	case []byte:
 		ctype = api.SQL_C_BINARY
 		b := make([]byte, len(d))
@@ -157,7 +401,27 @@ func (p *Parameter) BindValue(h api.SQLHSTMT, idx int, v driver.Value, conn *Con
 			sqltype = api.SQL_BINARY
 		}
 	default:
-		return fmt.Errorf("unsupported type %T", v)
+		u, ok := basicValue(v)
+		if !ok {
+			return fmt.Errorf("unsupported type %T", v)
+		}
+		if isTyped {
+			typed.Value = u
+			return p.BindValue(h, idx, typed, conn)
+		}
+		return p.BindValue(h, idx, u, conn)
+	}
+	if isTyped {
+		sqltype = typed.SQLType
+		if typed.Size > 0 {
+			size = typed.Size
+		}
+		if typed.Decimal > 0 {
+			decimal = typed.Decimal
+		}
+		if sqltype == api.SQL_SS_XML {
+			size = 0 // xml length is unlimited
+		}
 	}
 	ret := api.SQLBindParameter(h, api.SQLUSMALLINT(idx+1),
 		api.SQL_PARAM_INPUT, ctype, sqltype, size, decimal,
@@ -165,10 +429,226 @@ func (p *Parameter) BindValue(h api.SQLHSTMT, idx int, v driver.Value, conn *Con
 	if IsError(ret) {
 		return NewError("SQLBindParameter", h)
 	}
+	if ctype == api.SQL_C_NUMERIC {
+		n := p.Data.(*api.SQL_NUMERIC_STRUCT)
+		return setNumericDesc(h, api.SQL_ATTR_APP_PARAM_DESC, idx, int(n.Precision), int(n.Scale), buf)
+	}
+	return nil
+}
+
+// timeFractionDigits returns number of fractional second digits
+// needed to represent ns nanoseconds. It returns at least 3, because
+// values are represented as yyyy-mm-dd hh:mm:ss.fff format in ms sql
+// server, and no more than 7 (100ns), precision of datetime2 type.
+func timeFractionDigits(ns int) api.SQLSMALLINT {
+	n := api.SQLSMALLINT(9)
+	for n > 3 && ns%10 == 0 {
+		ns /= 10
+		n--
+	}
+	if n > 7 {
+		n = 7
+	}
+	return n
+}
+
+// isOutput reports whether p is procedure OUT or INPUT_OUTPUT
+// parameter, or return value.
+func (p *Parameter) isOutput() bool {
+	switch p.direction {
+	case api.SQL_PARAM_OUTPUT, api.SQL_PARAM_INPUT_OUTPUT, api.SQL_RETURN_VALUE:
+		return true
+	}
+	return false
+}
+
+// isOutDest reports whether v can be used as sql.Out destination.
+func isOutDest(v interface{}) bool {
+	switch v.(type) {
+	case *string, *int64, *int32, *int, *float64, *bool, *time.Time, *[]byte:
+		return true
+	}
+	return false
+}
+
+// bindOut binds output parameter o. The value is stored
+// in o.Dest by storeOut after statement is executed.
+func (p *Parameter) bindOut(h api.SQLHSTMT, idx int, o sql.Out) error {
+	dir := api.SQLSMALLINT(api.SQL_PARAM_OUTPUT)
+	if o.In && p.direction != api.SQL_RETURN_VALUE {
+		dir = api.SQL_PARAM_INPUT_OUTPUT
+	}
+	var ctype, sqltype, decimal api.SQLSMALLINT
+	var size api.SQLULEN
+	var buflen api.SQLLEN
+	var buf unsafe.Pointer
+	ind := api.SQLLEN(0)
+	switch d := o.Dest.(type) {
+	case *string:
+		n := 4000
+		if p.isDescribed && p.Size > 0 {
+			n = int(p.Size)
+		}
+		var b []uint16
+		if o.In {
+			b = api.StringToUTF16(*d)
+			ind = api.SQLLEN(len(b)-1) * 2
+		}
+		if len(b) < n+1 {
+			b = append(b, make([]uint16, n+1-len(b))...)
+		}
+		ctype = api.SQL_C_WCHAR
+		p.Data = b
+		buf = unsafe.Pointer(&b[0])
+		buflen = api.SQLLEN(len(b) * 2)
+		size = api.SQLULEN(len(b) - 1)
+		sqltype = api.SQL_WVARCHAR
+	case *int64, *int32, *int:
+		var v int64
+		if o.In {
+			switch d := d.(type) {
+			case *int64:
+				v = *d
+			case *int32:
+				v = int64(*d)
+			case *int:
+				v = int64(*d)
+			}
+		}
+		ctype = api.SQL_C_SBIGINT
+		p.Data = &v
+		buf = unsafe.Pointer(&v)
+		sqltype = api.SQL_BIGINT
+		size = 8
+	case *float64:
+		v := *d
+		ctype = api.SQL_C_DOUBLE
+		p.Data = &v
+		buf = unsafe.Pointer(&v)
+		sqltype = api.SQL_DOUBLE
+		size = 8
+	case *bool:
+		var b byte
+		if *d {
+			b = 1
+		}
+		ctype = api.SQL_C_BIT
+		p.Data = &b
+		buf = unsafe.Pointer(&b)
+		sqltype = api.SQL_BIT
+		size = 1
+	case *time.Time:
+		var b api.SQL_TIMESTAMP_STRUCT
+		if o.In {
+			t := *d
+			y, m, day := t.Date()
+			b = api.SQL_TIMESTAMP_STRUCT{
+				Year:     api.SQLSMALLINT(y),
+				Month:    api.SQLUSMALLINT(m),
+				Day:      api.SQLUSMALLINT(day),
+				Hour:     api.SQLUSMALLINT(t.Hour()),
+				Minute:   api.SQLUSMALLINT(t.Minute()),
+				Second:   api.SQLUSMALLINT(t.Second()),
+				Fraction: api.SQLUINTEGER(t.Nanosecond()),
+			}
+		}
+		ctype = api.SQL_C_TYPE_TIMESTAMP
+		p.Data = &b
+		buf = unsafe.Pointer(&b)
+		sqltype = api.SQL_TYPE_TIMESTAMP
+		decimal = 3
+		if p.isDescribed && p.SQLType == api.SQL_TYPE_TIMESTAMP && p.Decimal > 0 {
+			decimal = p.Decimal
+		}
+		size = 20 + api.SQLULEN(decimal)
+	case *[]byte:
+		n := 8000
+		if p.isDescribed && p.Size > 0 {
+			n = int(p.Size)
+		}
+		var b []byte
+		if o.In {
+			b = append(b, *d...)
+			ind = api.SQLLEN(len(b))
+		}
+		if len(b) < n {
+			b = append(b, make([]byte, n-len(b))...)
+		}
+		ctype = api.SQL_C_BINARY
+		p.Data = b
+		buf = unsafe.Pointer(&b[0])
+		buflen = api.SQLLEN(len(b))
+		size = api.SQLULEN(len(b))
+		sqltype = api.SQL_VARBINARY
+	default:
+		return fmt.Errorf("unsupported sql.Out destination type %T", o.Dest)
+	}
+	if p.isDescribed {
+		sqltype = p.SQLType
+	}
+	if !o.In {
+		ind = api.SQL_NULL_DATA
+	}
+	plen := p.StoreStrLen_or_IndPtr(ind)
+	ret := api.SQLBindParameter(h, api.SQLUSMALLINT(idx+1),
+		dir, ctype, sqltype, size, decimal,
+		api.SQLPOINTER(buf), buflen, plen)
+	if IsError(ret) {
+		return NewError("SQLBindParameter", h)
+	}
+	p.out = o.Dest
 	return nil
 }
 
+// storeOut stores value of output parameter p into its sql.Out
+// destination. NULL is stored as zero value.
+func (p *Parameter) storeOut() {
+	if p.out == nil {
+		return
+	}
+	if p.StrLen_or_IndPtr == api.SQL_NULL_DATA {
+		v := reflect.ValueOf(p.out).Elem()
+		v.Set(reflect.Zero(v.Type()))
+		return
+	}
+	switch d := p.out.(type) {
+	case *string:
+		b := p.Data.([]uint16)
+		n := int(p.StrLen_or_IndPtr) / 2
+		if n < 0 || n > len(b)-1 {
+			// value is truncated
+			n = len(b) - 1
+		}
+		*d = api.UTF16ToString(b[:n])
+	case *int64:
+		*d = *p.Data.(*int64)
+	case *int32:
+		*d = int32(*p.Data.(*int64))
+	case *int:
+		*d = int(*p.Data.(*int64))
+	case *float64:
+		*d = *p.Data.(*float64)
+	case *bool:
+		*d = *p.Data.(*byte) != 0
+	case *time.Time:
+		*d = timestampValue(p.Data.(*api.SQL_TIMESTAMP_STRUCT))
+	case *[]byte:
+		b := p.Data.([]byte)
+		n := int(p.StrLen_or_IndPtr)
+		if n < 0 || n > len(b) {
+			n = len(b)
+		}
+		*d = append([]byte(nil), b[:n]...)
+	}
+}
+
 func ExtractParameters(h api.SQLHSTMT) ([]Parameter, error) {
+	return extractParameters(h, true)
+}
+
+// extractParameters returns parameters of prepared statement h.
+// Parameters are described with SQLDescribeParam, if describe is set.
+func extractParameters(h api.SQLHSTMT, describe bool) ([]Parameter, error) {
 	// count parameters
 	var n, nullable api.SQLSMALLINT
 	ret := api.SQLNumParams(h, &n)
@@ -180,6 +660,9 @@ func ExtractParameters(h api.SQLHSTMT) ([]Parameter, error) {
 		return nil, nil
 	}
 	ps := make([]Parameter, n)
+	if !describe {
+		return ps, nil
+	}
 	// fetch param descriptions
 	for i := range ps {
 		p := &ps[i]
@@ -207,3 +690,77 @@ func ExtractParameters(h api.SQLHSTMT) ([]Parameter, error) {
 	}
 	return ps, nil
 }
+
+// decimalString converts v into exact decimal text representation.
+func decimalString(v interface{}) (string, error) {
+	var s string
+	switch d := v.(type) {
+	case *big.Int:
+		if d == nil {
+			return "", errors.New("nil *big.Int parameter")
+		}
+		s = d.String()
+	case *big.Rat:
+		if d == nil {
+			return "", errors.New("nil *big.Rat parameter")
+		}
+		if d.IsInt() {
+			s = d.Num().String()
+			break
+		}
+		// Find smallest n, such that 10^n is divisible by
+		// denominator, to print all fraction digits.
+		var twos, fives int
+		q := new(big.Int).Set(d.Denom())
+		for q.Bit(0) == 0 {
+			q.Rsh(q, 1)
+			twos++
+		}
+		five, m := big.NewInt(5), new(big.Int)
+		for m.Mod(q, five).Sign() == 0 {
+			q.Quo(q, five)
+			fives++
+		}
+		if q.Cmp(big.NewInt(1)) != 0 {
+			return "", fmt.Errorf("%v cannot be represented as exact decimal", d)
+		}
+		if twos < fives {
+			twos = fives
+		}
+		s = d.FloatString(twos)
+	case Decimal:
+		s = d.String()
+	default:
+		return "", fmt.Errorf("unsupported decimal type %T", v)
+	}
+	for i, c := range s {
+		if ('0' <= c && c <= '9') || c == '.' || (c == '-' && i == 0) {
+			continue
+		}
+		return "", fmt.Errorf("invalid decimal value %q", s)
+	}
+	return s, nil
+}
+
+// decimalPrecision returns precision and scale of decimal number s.
+func decimalPrecision(s string) (precision api.SQLULEN, scale api.SQLSMALLINT) {
+	s = strings.TrimPrefix(s, "-")
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		scale = api.SQLSMALLINT(len(s) - i - 1)
+		s = s[:i] + s[i+1:]
+	}
+	precision = api.SQLULEN(len(s))
+	if precision < 1 {
+		precision = 1
+	}
+	return precision, scale
+}
+
+// isIntegerSQLType reports whether t is one of integer SQL types.
+func isIntegerSQLType(t api.SQLSMALLINT) bool {
+	switch t {
+	case api.SQL_TINYINT, api.SQL_SMALLINT, api.SQL_INTEGER, api.SQL_BIGINT:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,232 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package odbc
+
+import (
+	"strings"
+	"unsafe"
+
+	"github.com/alexbrainman/odbc/api"
+)
+
+// parseProcCall parses ODBC procedure call escape sequence, like
+// "{call name(?, ?)}" or "{? = call name(?)}". It returns procedure
+// name and, for every parameter marker in query, ordinal position of
+// procedure parameter the marker is passed to (0 is return value).
+func parseProcCall(query string) (name string, ordinals []int, ok bool) {
+	q := strings.TrimSpace(query)
+	if !strings.HasPrefix(q, "{") || !strings.HasSuffix(q, "}") {
+		return "", nil, false
+	}
+	q = strings.TrimSpace(q[1 : len(q)-1])
+	if strings.HasPrefix(q, "?") {
+		q = strings.TrimSpace(q[1:])
+		if !strings.HasPrefix(q, "=") {
+			return "", nil, false
+		}
+		q = strings.TrimSpace(q[1:])
+		ordinals = append(ordinals, 0)
+	}
+	if len(q) < 5 || !strings.EqualFold(q[:4], "call") || !strings.ContainsAny(q[4:5], " \t\r\n") {
+		return "", nil, false
+	}
+	q = strings.TrimSpace(q[4:])
+	args := ""
+	if i := strings.IndexByte(q, '('); i >= 0 {
+		if !strings.HasSuffix(q, ")") {
+			return "", nil, false
+		}
+		name, args = strings.TrimSpace(q[:i]), q[i+1:len(q)-1]
+	} else {
+		name = q
+	}
+	if name == "" {
+		return "", nil, false
+	}
+	if strings.TrimSpace(args) == "" {
+		return name, ordinals, true
+	}
+	pos := 1
+	start := 0
+	depth := 0
+	marker := false // marker is seen in current argument
+	for i := 0; i <= len(args); i++ {
+		if i < len(args) {
+			switch args[i] {
+			case '\'':
+				j := strings.IndexByte(args[i+1:], '\'')
+				if j < 0 {
+					return "", nil, false
+				}
+				i += j + 1
+				continue
+			case '?':
+				marker = true
+				continue
+			case '(':
+				depth++
+				continue
+			case ')':
+				depth--
+				continue
+			case ',':
+				if depth > 0 {
+					continue
+				}
+			default:
+				continue
+			}
+		}
+		a := strings.TrimSpace(args[start:i])
+		if a == "?" {
+			ordinals = append(ordinals, pos)
+		} else if marker {
+			// marker is part of an expression
+			return "", nil, false
+		}
+		marker = false
+		pos++
+		start = i + 1
+	}
+	return name, ordinals, true
+}
+
+// splitProcName splits procedure name, like "db.dbo.name",
+// into catalog, schema and name parts with quotes removed.
+func splitProcName(s string) (catalog, schema, name string) {
+	parts := strings.Split(s, ".")
+	for i, p := range parts {
+		p = strings.TrimSpace(p)
+		if len(p) >= 2 && (p[0] == '[' && p[len(p)-1] == ']' || p[0] == '"' && p[len(p)-1] == '"') {
+			p = p[1 : len(p)-1]
+		}
+		parts[i] = p
+	}
+	switch len(parts) {
+	case 1:
+		return "", "", parts[0]
+	case 2:
+		return "", parts[0], parts[1]
+	default:
+		n := len(parts)
+		return parts[n-3], parts[n-2], parts[n-1]
+	}
+}
+
+// procParamDirections returns directions (SQL_PARAM_INPUT,
+// SQL_PARAM_OUTPUT and others) of procedure name parameters,
+// as reported by SQLProcedureColumns. The slice is indexed
+// by parameter ordinal position, 0 being return value.
+func (c *Conn) procParamDirections(procName string) ([]api.SQLSMALLINT, error) {
+	var out api.SQLHANDLE
+	ret := api.SQLAllocHandle(api.SQL_HANDLE_STMT, api.SQLHANDLE(c.h), &out)
+	if IsError(ret) {
+		return nil, c.newError("SQLAllocHandle", c.h)
+	}
+	h := api.SQLHSTMT(out)
+	err := c.drv.Stats.updateHandleCount(api.SQL_HANDLE_STMT, out, 1)
+	if err != nil {
+		return nil, err
+	}
+	defer c.drv.releaseHandle(h)
+
+	catalog, schema, name := splitProcName(procName)
+	arg := func(s string) (*api.SQLWCHAR, api.SQLSMALLINT) {
+		if s == "" {
+			return nil, 0
+		}
+		b := api.StringToUTF16(s)
+		return (*api.SQLWCHAR)(unsafe.Pointer(&b[0])), api.SQL_NTS
+	}
+	cp, cl := arg(catalog)
+	sp, sl := arg(schema)
+	np, nl := arg(name)
+	ret = api.SQLProcedureColumns(h, cp, cl, sp, sl, np, nl, nil, 0)
+	if IsError(ret) {
+		return nil, c.newError("SQLProcedureColumns", h)
+	}
+	var dirs []api.SQLSMALLINT
+	for {
+		ret = api.SQLFetch(h)
+		if ret == api.SQL_NO_DATA {
+			break
+		}
+		if IsError(ret) {
+			return nil, c.newError("SQLFetch", h)
+		}
+		// Procedure name is a search pattern, so skip
+		// procedures that just match it. SQL Server
+		// returns names like "name;1".
+		var nameBuf [256]uint16
+		var l api.SQLLEN
+		ret = api.SQLGetData(h, 3, api.SQL_C_WCHAR, api.SQLPOINTER(unsafe.Pointer(&nameBuf[0])), api.SQLLEN(len(nameBuf)*2), &l)
+		if IsError(ret) {
+			return nil, c.newError("SQLGetData", h)
+		}
+		n := api.UTF16ToString(nameBuf[:])
+		if i := strings.IndexByte(n, ';'); i >= 0 {
+			n = n[:i]
+		}
+		if !strings.EqualFold(n, name) {
+			continue
+		}
+		var colType api.SQLSMALLINT
+		ret = api.SQLGetData(h, 5, api.SQL_C_SHORT, api.SQLPOINTER(unsafe.Pointer(&colType)), 0, &l)
+		if IsError(ret) {
+			return nil, c.newError("SQLGetData", h)
+		}
+		if colType == api.SQL_RESULT_COL {
+			continue
+		}
+		var pos int32
+		ret = api.SQLGetData(h, 18, api.SQL_C_LONG, api.SQLPOINTER(unsafe.Pointer(&pos)), 0, &l)
+		if IsError(ret) {
+			return nil, c.newError("SQLGetData", h)
+		}
+		if pos < 0 {
+			continue
+		}
+		for len(dirs) <= int(pos) {
+			dirs = append(dirs, 0)
+		}
+		dirs[pos] = colType
+	}
+	return dirs, nil
+}
+
+// setProcParamDirections sets direction of parameters ps of
+// procedure call query. Direction is left unknown, if query
+// is not a procedure call or procedure cannot be found.
+// Directions are looked up once per procedure name, and
+// cached for the life of connection c.
+func (c *Conn) setProcParamDirections(query string, ps []Parameter) {
+	name, ordinals, ok := parseProcCall(query)
+	if !ok || len(ordinals) != len(ps) || len(ps) == 0 {
+		return
+	}
+	dirs, ok := c.procDirs[name]
+	if !ok {
+		if ok, err := c.SupportsFunction(api.SQL_API_SQLPROCEDURECOLUMNS); err == nil && !ok {
+			return
+		}
+		var err error
+		dirs, err = c.procParamDirections(name)
+		if err != nil {
+			return
+		}
+		if c.procDirs == nil {
+			c.procDirs = make(map[string][]api.SQLSMALLINT)
+		}
+		c.procDirs[name] = dirs
+	}
+	for i, pos := range ordinals {
+		switch {
+		case pos == 0:
+			ps[i].direction = api.SQL_RETURN_VALUE
+		case pos < len(dirs):
+			ps[i].direction = dirs[pos]
+		}
+	}
+}
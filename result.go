@@ -9,14 +9,45 @@ import (
 )
 
 type Result struct {
-	rowCount int64
+	rowCount  int64
+	rowCounts []int64
 }
 
+// ErrLastInsertIdUnsupported is returned by LastInsertId. ODBC has
+// no portable way to get id of inserted row, so use database specific
+// SQL instead, like SQL Server OUTPUT clause or SCOPE_IDENTITY(), or
+// RETURNING clause of PostgreSQL. Code, that works with many drivers,
+// can check for this error with errors.Is.
+var ErrLastInsertIdUnsupported = errors.New("odbc: LastInsertId is not supported")
+
+// LastInsertId always returns ErrLastInsertIdUnsupported.
 func (r *Result) LastInsertId() (int64, error) {
-	// TODO(brainman): implement (*Result).LastInsertId
-	return 0, errors.New("not implemented")
+	return 0, ErrLastInsertIdUnsupported
 }
 
 func (r *Result) RowsAffected() (int64, error) {
 	return r.rowCount, nil
 }
+
+// RowCounts returns number of rows affected by every statement of
+// executed batch, in the order statements were run. RowsAffected
+// returns sum of these numbers. Drivers report -1 for statements,
+// that do not change rows. database/sql hides driver results, so
+// use sql.Conn.Raw to get them, like
+//
+//	err := conn.Raw(func(dc interface{}) error {
+//		st, err := dc.(*odbc.Conn).Prepare("update a set x = 1; delete from b")
+//		if err != nil {
+//			return err
+//		}
+//		defer st.Close()
+//		r, err := st.Exec(nil)
+//		if err != nil {
+//			return err
+//		}
+//		counts := r.(*odbc.Result).RowCounts()
+//		...
+//	})
+func (r *Result) RowCounts() []int64 {
+	return r.rowCounts
+}
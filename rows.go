@@ -5,8 +5,11 @@
 package odbc
 
 import (
+	"database/sql"
 	"database/sql/driver"
+	"errors"
 	"io"
+	"reflect"
 
 	"github.com/alexbrainman/odbc/api"
 )
@@ -23,15 +26,90 @@ func (r *Rows) Columns() []string {
 	return names
 }
 
+// ColumnTypeSQLType returns ODBC SQL data type code of column
+// index i, as reported by SQLDescribeCol. Unlike database type
+// name, it tells apart Unicode (api.SQL_WVARCHAR) and ANSI
+// (api.SQL_VARCHAR) character columns.
+func (r *Rows) ColumnTypeSQLType(i int) int16 {
+	if b := baseColumn(r.os.Cols[i]); b != nil {
+		return int16(b.SQLType)
+	}
+	return api.SQL_UNKNOWN_TYPE
+}
+
+// ColumnTypeDatabaseTypeName implements
+// driver.RowsColumnTypeDatabaseTypeName interface. Columns,
+// that do not have DatabaseTypeName method, report empty name.
+func (r *Rows) ColumnTypeDatabaseTypeName(index int) string {
+	if c, ok := r.os.Cols[index].(interface{ DatabaseTypeName() string }); ok {
+		return c.DatabaseTypeName()
+	}
+	return ""
+}
+
+// ColumnTypeLength implements
+// driver.RowsColumnTypeLength interface.
+func (r *Rows) ColumnTypeLength(index int) (length int64, ok bool) {
+	if b := baseColumn(r.os.Cols[index]); b != nil {
+		return b.Length()
+	}
+	return 0, false
+}
+
+// ColumnTypeScanType implements
+// driver.RowsColumnTypeScanType interface. Sign of integer
+// columns is looked up here, see lookupUnsigned.
+func (r *Rows) ColumnTypeScanType(index int) reflect.Type {
+	if b := baseColumn(r.os.Cols[index]); b != nil {
+		if !r.isClosed() {
+			b.lookupUnsigned(r.os.h, index)
+		}
+		return b.ScanType()
+	}
+	return scanTypeInterface
+}
+
+var errRowsClosed = errors.New("Rows are closed")
+
+// isClosed reports whether r is closed. Cursor of closed rows is
+// closed, or statement handle is even released, so the handle must
+// not be used to fetch more data.
+func (r *Rows) isClosed() bool {
+	r.os.mu.Lock()
+	defer r.os.mu.Unlock()
+	return !r.os.usedByRows
+}
+
 func (r *Rows) Next(dest []driver.Value) error {
+	if r.isClosed() {
+		return errRowsClosed
+	}
+	if len(r.os.Cols) == 0 {
+		// statement did not create a result set
+		return io.EOF
+	}
 	ret := api.SQLFetch(r.os.h)
+	r.os.fetches++
 	if ret == api.SQL_NO_DATA {
 		return io.EOF
 	}
 	if IsError(ret) {
 		return NewError("SQLFetch", r.os.h)
 	}
+	resetCachedValues(r.os.Cols)
+	streamed := -1
+	if r.os.streamLongData {
+		streamed = r.os.streamedColumn()
+	}
 	for i := range dest {
+		if i == streamed {
+			v, err := newLongDataReader(r, i)
+			if err != nil {
+				return err
+			}
+			dest[i] = v
+			continue
+		}
 		v, err := r.os.Cols[i].Value(r.os.h, i)
 		if err != nil {
 			return err
@@ -50,17 +128,49 @@ func (r *Rows) HasNextResultSet() bool {
 }
 
 func (r *Rows) NextResultSet() error {
+	if r.isClosed() {
+		return errRowsClosed
+	}
 	ret := api.SQLMoreResults(r.os.h)
 	if ret == api.SQL_NO_DATA {
+		// all results are processed, so output
+		// parameter values are available now
+		r.os.storeOutParams()
 		return io.EOF
 	}
 	if IsError(ret) {
 		return NewError("SQLMoreResults", r.os.h)
 	}
 
-	err := r.os.BindColumns()
+	err := r.os.rebindColumns()
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// ForEachResultSet calls fn for every result set of rows, starting
+// with current one. columns are names of result set columns, so
+// fn can scan rows without knowing result set shape in advance.
+// fn should iterate rows with rows.Next, rows left unread are
+// discarded when moving to next result set. ForEachResultSet stops
+// and returns first error returned by fn or by rows. It does not
+// close rows.
+func ForEachResultSet(rows *sql.Rows, fn func(columns []string, rows *sql.Rows) error) error {
+	for {
+		columns, err := rows.Columns()
+		if err != nil {
+			return err
+		}
+		err = fn(columns, rows)
+		if err != nil {
+			return err
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if !rows.NextResultSet() {
+			return rows.Err()
+		}
+	}
+}
@@ -6,19 +6,31 @@ package odbc
 
 import (
 	"fmt"
+	"runtime"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/alexbrainman/odbc/api"
 )
 
+// Stats keeps track of ODBC handles allocated by the driver.
 type Stats struct {
 	EnvCount  int
 	ConnCount int
 	StmtCount int
 	mu        sync.Mutex
+
+	// allocation sites of open handles, when leak tracking is on
+	sites map[api.SQLHANDLE]handleSite
+}
+
+type handleSite struct {
+	handleType api.SQLSMALLINT
+	stack      string
 }
 
-func (s *Stats) updateHandleCount(handleType api.SQLSMALLINT, change int) error {
+func (s *Stats) updateHandleCount(handleType api.SQLSMALLINT, h api.SQLHANDLE, change int) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	switch handleType {
@@ -31,5 +43,82 @@ func (s *Stats) updateHandleCount(handleType api.SQLSMALLINT, change int) error
 	default:
 		return fmt.Errorf("unexpected handle type %d", handleType)
 	}
+	if s.sites != nil {
+		if change > 0 {
+			s.sites[h] = handleSite{handleType: handleType, stack: callers()}
+		} else {
+			delete(s.sites, h)
+		}
+	}
 	return nil
 }
+
+// callers returns formatted stack of the goroutine that allocates
+// new handle, starting from the function that called SQLAllocHandle.
+func callers() string {
+	pc := make([]uintptr, 32)
+	n := runtime.Callers(3, pc)
+	frames := runtime.CallersFrames(pc[:n])
+	var b strings.Builder
+	for {
+		f, more := frames.Next()
+		fmt.Fprintf(&b, "\n\t%s\n\t\t%s:%d", f.Function, f.File, f.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+// Counts returns snapshot of number of environment, connection
+// and statement handles currently allocated by d. Use it, instead
+// of reading Stats fields directly, to check for handle leaks:
+//
+//	_, conn, stmt := db.Driver().(*odbc.Driver).Counts()
+func (d *Driver) Counts() (env, conn, stmt int32) {
+	d.Stats.mu.Lock()
+	defer d.Stats.mu.Unlock()
+	return int32(d.Stats.EnvCount), int32(d.Stats.ConnCount), int32(d.Stats.StmtCount)
+}
+
+// SetLeakTracking turns handle leak tracking on or off. While
+// it is on, every connection and statement handle allocated by
+// the driver remembers stack of its caller, so LeakReport can
+// tell where handles, that are still open, come from. Tracking
+// is expensive, and should not be left on in production unless
+// you are chasing a leak. Handles allocated before tracking was
+// turned on are not reported.
+func (d *Driver) SetLeakTracking(on bool) {
+	d.Stats.mu.Lock()
+	defer d.Stats.mu.Unlock()
+	if !on {
+		d.Stats.sites = nil
+		return
+	}
+	if d.Stats.sites == nil {
+		d.Stats.sites = make(map[api.SQLHANDLE]handleSite)
+	}
+}
+
+// LeakReport lists connection and statement handles that are
+// still open, together with their allocation stacks. It returns
+// nil, if leak tracking is off. See SetLeakTracking for details.
+func (d *Driver) LeakReport() []string {
+	d.Stats.mu.Lock()
+	defer d.Stats.mu.Unlock()
+	var r []string
+	for h, site := range d.Stats.sites {
+		var kind string
+		switch site.handleType {
+		case api.SQL_HANDLE_ENV:
+			kind = "environment"
+		case api.SQL_HANDLE_DBC:
+			kind = "connection"
+		case api.SQL_HANDLE_STMT:
+			kind = "statement"
+		}
+		r = append(r, fmt.Sprintf("%s handle %#x allocated at:%s", kind, h, site.stack))
+	}
+	sort.Strings(r)
+	return r
+}
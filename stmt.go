@@ -5,6 +5,8 @@
 package odbc
 
 import (
+	"context"
+	"database/sql"
 	"database/sql/driver"
 	"errors"
 	"sync"
@@ -16,14 +18,41 @@ type Stmt struct {
 	c     *Conn
 	query string
 	os    *ODBCStmt
-	mu    sync.Mutex
+	mu    sync.Mutex        // held by Exec, Query and Close
+	osMu  sync.Mutex        // guards os, see setOS and currentOS
+	attrs map[int32]uintptr // set by SetAttr
+}
+
+// setOS replaces statement handle of s. s.mu must be held.
+func (s *Stmt) setOS(os *ODBCStmt) {
+	s.osMu.Lock()
+	s.os = os
+	s.osMu.Unlock()
+}
+
+// currentOS returns statement handle of s. Unlike s.os, it can be
+// used without s.mu, while Exec or Query runs in another goroutine.
+func (s *Stmt) currentOS() *ODBCStmt {
+	s.osMu.Lock()
+	defer s.osMu.Unlock()
+	return s.os
 }
 
 func (c *Conn) Prepare(query string) (driver.Stmt, error) {
-	if c.bad {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext implements driver.ConnPrepareContext interface.
+// SQLPrepare is cancelled with SQLCancel, when ctx is done, so
+// prepare against unresponsive server does not block forever.
+func (c *Conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if c.bad.Load() {
 		return nil, driver.ErrBadConn
 	}
-	os, err := c.PrepareODBCStmt(query)
+	if err := c.checkReadOnly(query); err != nil {
+		return nil, err
+	}
+	os, err := c.prepareODBCStmt(ctx, query, false)
 	if err != nil {
 		return nil, err
 	}
@@ -31,41 +60,154 @@ func (c *Conn) Prepare(query string) (driver.Stmt, error) {
 }
 
 func (s *Stmt) NumInput() int {
-	if s.os == nil {
+	os := s.currentOS()
+	if os == nil {
 		return -1
 	}
-	return len(s.os.Parameters)
+	return len(os.Parameters)
 }
 
+var errStmtClosed = errors.New("Stmt is closed")
+
+// Close closes statement s. It waits for Exec or Query, that
+// runs in another goroutine, to finish, so the statement handle
+// is never released while it is used.
 func (s *Stmt) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.os == nil {
 		return errors.New("Stmt is already closed")
 	}
 	ret := s.os.closeByStmt()
-	s.os = nil
+	s.setOS(nil)
 	return ret
 }
 
-func (s *Stmt) Exec(args []driver.Value) (driver.Result, error) {
+// Cancel cancels statement s, that is executed by Exec or
+// Query in another goroutine. Interrupted Exec or Query returns
+// an error. Cancel calls SQLCancel, so it can be used without
+// context, for example, from user own timeout handler.
+func (s *Stmt) Cancel() error {
+	os := s.currentOS()
+	if os == nil {
+		return errStmtClosed
+	}
+	return os.Cancel()
+}
+
+// SetAttr sets integer statement attribute attr, like
+// api.SQL_ATTR_QUERY_TIMEOUT or api.SQL_ATTR_MAX_ROWS, to v.
+// The attribute applies to all following executions of s.
+func (s *Stmt) SetAttr(attr int32, v uintptr) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.os == nil {
-		return nil, errors.New("Stmt is closed")
+		return errStmtClosed
 	}
+	if err := s.os.setAttr(attr, v); err != nil {
+		return err
+	}
+	if s.attrs == nil {
+		s.attrs = make(map[int32]uintptr)
+	}
+	s.attrs[attr] = v
+	return nil
+}
+
+// GetAttr returns current value of integer statement attribute attr.
+func (s *Stmt) GetAttr(attr int32) (uintptr, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if s.os == nil {
+		return 0, errStmtClosed
+	}
+	return s.os.getAttr(attr)
+}
+
+// applyAttrs sets attributes, that were set by SetAttr,
+// on newly prepared statement handle of s.
+func (s *Stmt) applyAttrs() error {
+	for attr, v := range s.attrs {
+		if err := s.os.setAttr(attr, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepareExpanded prepares query, that is s query with slice
+// arguments expanded, for single Exec or Query. Attributes set
+// by SetAttr apply to the new statement too.
+func (s *Stmt) prepareExpanded(query string) (*Stmt, error) {
+	ds, err := s.c.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	st := ds.(*Stmt)
+	st.attrs = s.attrs
+	if err := st.applyAttrs(); err != nil {
+		st.Close()
+		return nil, err
+	}
+	return st, nil
+}
+
+// CheckNamedValue implements driver.NamedValueChecker interface.
+// Pointer arguments passed to procedure OUT and INPUT_OUTPUT
+// parameters of "{call name(?, ?)}" query are handled as sql.Out,
+// so there is no need to wrap them. Directions of parameters are
+// discovered with SQLProcedureColumns when statement is prepared.
+func (s *Stmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if os := s.currentOS(); os != nil && 0 < nv.Ordinal && nv.Ordinal <= len(os.Parameters) {
+		p := &os.Parameters[nv.Ordinal-1]
+		if p.isOutput() && isOutDest(nv.Value) {
+			nv.Value = sql.Out{
+				Dest: nv.Value,
+				In:   p.direction == api.SQL_PARAM_INPUT_OUTPUT,
+			}
+			return nil
+		}
+	}
+	return s.c.CheckNamedValue(nv)
+}
+
+func (s *Stmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.os == nil {
+		return nil, errStmtClosed
+	}
+	query, eargs, err := expandSliceArgs(s.query, args, s.c.bracketQuotes())
+	if err != nil {
+		return nil, err
+	}
+	if query != s.query {
+		// slices were expanded, use new statement for this query
+		st, err := s.prepareExpanded(query)
+		if err != nil {
+			return nil, err
+		}
+		defer st.Close()
+		return st.Exec(eargs)
+	}
 	if s.os.usedByRows {
 		s.os.closeByStmt()
-		s.os = nil
+		s.setOS(nil)
 		os, err := s.c.PrepareODBCStmt(s.query)
 		if err != nil {
 			return nil, err
 		}
-		s.os = os
+		s.setOS(os)
+		if err := s.applyAttrs(); err != nil {
+			return nil, err
+		}
 	}
-	err := s.os.Exec(args, s.c)
+	err = s.os.Exec(args, s.c)
 	if err != nil {
 		return nil, err
 	}
 	var sumRowCount int64
+	var rowCounts []int64
 	for {
 		var c api.SQLLEN
 		ret := api.SQLRowCount(s.os.h, &c)
@@ -73,34 +215,54 @@ func (s *Stmt) Exec(args []driver.Value) (driver.Result, error) {
 			return nil, NewError("SQLRowCount", s.os.h)
 		}
 		sumRowCount += int64(c)
+		rowCounts = append(rowCounts, int64(c))
 		if ret = api.SQLMoreResults(s.os.h); ret == api.SQL_NO_DATA {
 			break
 		}
 	}
-	return &Result{rowCount: sumRowCount}, nil
+	s.os.storeOutParams()
+	return &Result{rowCount: sumRowCount, rowCounts: rowCounts}, nil
 }
 
 func (s *Stmt) Query(args []driver.Value) (driver.Rows, error) {
-	if s.os == nil {
-		return nil, errors.New("Stmt is closed")
-	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if s.os == nil {
+		return nil, errStmtClosed
+	}
+	query, eargs, err := expandSliceArgs(s.query, args, s.c.bracketQuotes())
+	if err != nil {
+		return nil, err
+	}
+	if query != s.query {
+		// slices were expanded, use new statement for this query
+		st, err := s.prepareExpanded(query)
+		if err != nil {
+			return nil, err
+		}
+		defer st.Close()
+		return st.Query(eargs)
+	}
 	if s.os.usedByRows {
 		s.os.closeByStmt()
-		s.os = nil
+		s.setOS(nil)
 		os, err := s.c.PrepareODBCStmt(s.query)
 		if err != nil {
 			return nil, err
 		}
-		s.os = os
+		s.setOS(os)
+		if err := s.applyAttrs(); err != nil {
+			return nil, err
+		}
 	}
-	err := s.os.Exec(args, s.c)
+	err = s.os.Exec(args, s.c)
 	if err != nil {
 		return nil, err
 	}
+	// Statement without result set (like UPDATE) is executed
+	// anyway, so return empty rows instead of an error.
 	err = s.os.BindColumns()
-	if err != nil {
+	if err != nil && err != ErrNoResultSet {
 		return nil, err
 	}
 	s.os.usedByRows = true // now both Stmt and Rows refer to it
@@ -0,0 +1,158 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package odbc
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"unsafe"
+
+	"github.com/alexbrainman/odbc/api"
+)
+
+type streamLongDataKey struct{}
+
+// WithStreamedLongData returns copy of ctx, that makes rows returned
+// by QueryContext stream data of long character and binary columns,
+// like nvarchar(max) or text, instead of reading whole column value
+// into memory. Streamed column value is an io.Reader, that reads
+// column data with repeated SQLGetData calls. Scan such column into
+// sql.Scanner or *interface{} destination. Character data is read
+// as UTF-8.
+//
+// ODBC drivers return unbound columns data in column order only, so
+// just one column of a row is streamed - last column, that is not
+// bound (see max_bind_width connection string option). Other long
+// columns are read into memory as usual. The reader is valid until
+// next call to Rows.Next or Rows.Close.
+func WithStreamedLongData(ctx context.Context) context.Context {
+	return context.WithValue(ctx, streamLongDataKey{}, true)
+}
+
+// streamedColumn returns index of column, that can be streamed,
+// or -1, if there is no such column. The column is not bound,
+// while all columns after it are bound, so their data does not
+// need to be read with SQLGetData.
+func (s *ODBCStmt) streamedColumn() int {
+	for i := len(s.Cols) - 1; i >= 0; i-- {
+		switch c := s.Cols[i].(type) {
+		case *NonBindableColumn:
+			return i
+		case *BindableColumn:
+			if !c.IsBound {
+				return -1
+			}
+		default:
+			return -1
+		}
+	}
+	return -1
+}
+
+var errStaleReader = errors.New("column data reader used after Rows.Next or Rows.Close")
+
+// longDataReader reads data of unbound column idx of current row.
+type longDataReader struct {
+	r       *Rows
+	idx     int
+	ctype   api.SQLSMALLINT
+	fetches int      // r.os.fetches when reader was created
+	chunk   []byte   // SQLGetData buffer
+	buf     []byte   // data read, but not returned by Read yet
+	surr    []uint16 // high surrogate left at the end of previous chunk
+	done    bool     // all column data is read
+}
+
+// newLongDataReader returns reader of column idx data, or
+// nil, if column value is NULL. First chunk of data is read
+// immediately, so NULL values can be recognized.
+func newLongDataReader(r *Rows, idx int) (driver.Value, error) {
+	lr := &longDataReader{
+		r:       r,
+		idx:     idx,
+		ctype:   r.os.Cols[idx].(*NonBindableColumn).CType,
+		fetches: r.os.fetches,
+		chunk:   make([]byte, 8192),
+	}
+	isNull, err := lr.readChunk()
+	if err != nil {
+		return nil, err
+	}
+	if isNull {
+		return nil, nil
+	}
+	return lr, nil
+}
+
+// readChunk reads next chunk of column data into lr.buf.
+func (lr *longDataReader) readChunk() (isNull bool, err error) {
+	h := lr.r.os.h
+	var l BufferLen
+	ret := l.GetData(h, lr.idx, lr.ctype, lr.chunk)
+	var n int
+	switch ret {
+	case api.SQL_NO_DATA:
+		lr.done = true
+	case api.SQL_SUCCESS:
+		if l.IsNull() {
+			return true, nil
+		}
+		if int(l) > len(lr.chunk) {
+			return false, fmt.Errorf("too much data returned: %d bytes returned, but buffer size is %d", l, len(lr.chunk))
+		}
+		n = int(l)
+		lr.done = true
+	case api.SQL_SUCCESS_WITH_INFO:
+		err := NewError("SQLGetData", h).(*Error)
+		if len(err.Diag) > 0 && err.Diag[0].State != "01004" {
+			return false, err
+		}
+		if l.IsNull() {
+			return true, nil
+		}
+		n = chunkDataLen(lr.ctype, len(lr.chunk))
+	default:
+		return false, NewError("SQLGetData", h)
+	}
+	if lr.ctype != api.SQL_C_WCHAR {
+		lr.buf = append(lr.buf, lr.chunk[:n]...)
+		return false, nil
+	}
+	s := append(lr.surr, (*[1 << 28]uint16)(unsafe.Pointer(&lr.chunk[0]))[:n/2:n/2]...)
+	lr.surr = nil
+	if k := len(s); !lr.done && k > 0 && isHighSurrogate(s[k-1]) {
+		// keep high surrogate, until low surrogate is read
+		lr.surr = []uint16{s[k-1]}
+		s = s[:k-1]
+	}
+	lr.buf = append(lr.buf, api.UTF16ToUTF8(s)...)
+	return false, nil
+}
+
+// isHighSurrogate reports whether c is the first
+// half of UTF-16 surrogate pair.
+func isHighSurrogate(c uint16) bool {
+	return 0xd800 <= c && c < 0xdc00
+}
+
+func (lr *longDataReader) Read(p []byte) (int, error) {
+	for len(lr.buf) == 0 {
+		if lr.done {
+			return 0, io.EOF
+		}
+		if lr.r.isClosed() || lr.fetches != lr.r.os.fetches {
+			return 0, errStaleReader
+		}
+		if _, err := lr.readChunk(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, lr.buf)
+	lr.buf = lr.buf[n:]
+	return n, nil
+}
@@ -0,0 +1,265 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package odbc
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"time"
+	"unsafe"
+
+	"github.com/alexbrainman/odbc/api"
+)
+
+// TVP is SQL Server table-valued parameter. It passes many rows
+// to the server in a single parameter, like
+//
+//	type item struct {
+//		ID   int64
+//		Name string
+//	}
+//	items := []item{{1, "a"}, {2, "b"}}
+//	db.Exec("{call dbo.AddItems(?)}", odbc.TVP{TypeName: "dbo.ItemList", Value: items})
+//
+// TypeName is name of user-defined table type, optionally qualified
+// with schema name. Value must be a slice of structs. Exported struct
+// fields, in order of declaration, are table type columns. Fields
+// tagged with `tvp:"-"` are skipped. Field values are converted with
+// driver.DefaultParameterConverter, so fields can be of integer,
+// float, bool, string, []byte and time.Time types, pointers to these
+// types (nil pointer is NULL), or implement driver.Valuer.
+type TVP struct {
+	TypeName string
+	Value    interface{}
+}
+
+// tvpColumn stores values of single table-valued parameter column
+// for all rows. Values are bound as parameter array.
+type tvpColumn struct {
+	ctype   api.SQLSMALLINT
+	sqltype api.SQLSMALLINT
+	size    api.SQLULEN
+	decimal api.SQLSMALLINT
+	width   int          // size of single value in data
+	data    []byte       // values of all rows
+	ind     []api.SQLLEN // length or indicator of every value
+}
+
+// tvpData keeps table-valued parameter data alive
+// and away from gc until statement is executed.
+type tvpData struct {
+	schema   []uint16
+	typeName []uint16
+	cols     []*tvpColumn
+}
+
+// tvpValues converts rows, a slice of structs, into column values.
+// values[i][j] is value of column i in row j.
+func tvpValues(rows interface{}) (values [][]driver.Value, n int, err error) {
+	rv := reflect.ValueOf(rows)
+	if rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() != reflect.Struct {
+		return nil, 0, fmt.Errorf("TVP value must be a slice of structs, not %T", rows)
+	}
+	t := rv.Type().Elem()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || f.Tag.Get("tvp") == "-" {
+			continue
+		}
+		vs := make([]driver.Value, rv.Len())
+		for j := range vs {
+			v, err := driver.DefaultParameterConverter.ConvertValue(rv.Index(j).Field(i).Interface())
+			if err != nil {
+				return nil, 0, fmt.Errorf("TVP field %s: %v", f.Name, err)
+			}
+			vs[j] = v
+		}
+		values = append(values, vs)
+	}
+	if len(values) == 0 {
+		return nil, 0, fmt.Errorf("TVP struct %v has no exported fields", t)
+	}
+	return values, rv.Len(), nil
+}
+
+// newTVPColumn returns column with values vs. Column type is
+// chosen by type of first value, that is not NULL. All other
+// values must be of the same type. Column of NULLs is bound as
+// character column.
+func newTVPColumn(vs []driver.Value) (*tvpColumn, error) {
+	var first driver.Value
+	for _, v := range vs {
+		if v != nil {
+			first = v
+			break
+		}
+	}
+	n := len(vs)
+	if n == 0 {
+		n = 1 // buffers cannot be empty
+	}
+	c := &tvpColumn{ind: make([]api.SQLLEN, n)}
+	var strs [][]uint16
+	switch first.(type) {
+	case nil, string:
+		strs = make([][]uint16, len(vs))
+		l := 1 // size cannot be less then 1 even for empty fields
+		for j, v := range vs {
+			if s, ok := v.(string); ok {
+				strs[j] = api.StringToUTF16(s)
+				if len(strs[j])-1 > l {
+					l = len(strs[j]) - 1
+				}
+			}
+		}
+		c.ctype, c.sqltype, c.size = api.SQL_C_WCHAR, api.SQL_WVARCHAR, api.SQLULEN(l)
+		if l > 4000 {
+			c.sqltype = api.SQL_WLONGVARCHAR
+		}
+		c.width = (l + 1) * 2
+	case []byte:
+		l := 1
+		for _, v := range vs {
+			if b, ok := v.([]byte); ok && len(b) > l {
+				l = len(b)
+			}
+		}
+		c.ctype, c.sqltype, c.size = api.SQL_C_BINARY, api.SQL_VARBINARY, api.SQLULEN(l)
+		if l > 8000 {
+			c.sqltype = api.SQL_LONGVARBINARY
+		}
+		c.width = l
+	case int64:
+		c.ctype, c.sqltype, c.size, c.width = api.SQL_C_SBIGINT, api.SQL_BIGINT, 8, 8
+	case float64:
+		c.ctype, c.sqltype, c.size, c.width = api.SQL_C_DOUBLE, api.SQL_DOUBLE, 8, 8
+	case bool:
+		c.ctype, c.sqltype, c.size, c.width = api.SQL_C_BIT, api.SQL_BIT, 1, 1
+	case time.Time:
+		c.ctype, c.sqltype = api.SQL_C_TYPE_TIMESTAMP, api.SQL_TYPE_TIMESTAMP
+		c.size, c.decimal = 27, 7 // datetime2 precision
+		c.width = int(unsafe.Sizeof(api.SQL_TIMESTAMP_STRUCT{}))
+	default:
+		return nil, fmt.Errorf("unsupported TVP column type %T", first)
+	}
+	c.data = make([]byte, n*c.width)
+	for j, v := range vs {
+		if v == nil {
+			c.ind[j] = api.SQL_NULL_DATA
+			continue
+		}
+		if reflect.TypeOf(v) != reflect.TypeOf(first) {
+			return nil, fmt.Errorf("TVP column has values of different types: %T and %T", first, v)
+		}
+		p := unsafe.Pointer(&c.data[j*c.width])
+		c.ind[j] = api.SQLLEN(c.width)
+		switch d := v.(type) {
+		case string:
+			s := strs[j][:len(strs[j])-1] // remove terminating 0
+			copy((*[1 << 28]uint16)(p)[:len(s):len(s)], s)
+			c.ind[j] = api.SQLLEN(len(s) * 2)
+		case []byte:
+			copy(c.data[j*c.width:], d)
+			c.ind[j] = api.SQLLEN(len(d))
+		case int64:
+			*(*int64)(p) = d
+		case float64:
+			*(*float64)(p) = d
+		case bool:
+			if d {
+				c.data[j] = 1
+			}
+		case time.Time:
+			y, m, day := d.Date()
+			*(*api.SQL_TIMESTAMP_STRUCT)(p) = api.SQL_TIMESTAMP_STRUCT{
+				Year:     api.SQLSMALLINT(y),
+				Month:    api.SQLUSMALLINT(m),
+				Day:      api.SQLUSMALLINT(day),
+				Hour:     api.SQLUSMALLINT(d.Hour()),
+				Minute:   api.SQLUSMALLINT(d.Minute()),
+				Second:   api.SQLUSMALLINT(d.Second()),
+				Fraction: api.SQLUINTEGER(d.Nanosecond() / 100 * 100),
+			}
+		}
+	}
+	return c, nil
+}
+
+// bindTVP binds table-valued parameter tvp. Parameter itself is bound
+// as SQL_SS_TABLE, and then, with parameter focus set to it, every
+// table column is bound as array of all rows values.
+func (p *Parameter) bindTVP(h api.SQLHSTMT, idx int, tvp TVP) error {
+	values, n, err := tvpValues(tvp.Value)
+	if err != nil {
+		return err
+	}
+	_, schema, name := splitProcName(tvp.TypeName)
+	if name == "" {
+		return fmt.Errorf("TVP type name is empty")
+	}
+	d := &tvpData{typeName: api.StringToUTF16(name)}
+	for _, vs := range values {
+		c, err := newTVPColumn(vs)
+		if err != nil {
+			return err
+		}
+		d.cols = append(d.cols, c)
+	}
+	p.Data = d
+	p.out = nil
+
+	rows, maxRows := api.SQLLEN(n), api.SQLULEN(n)
+	if n == 0 {
+		rows, maxRows = api.SQL_DEFAULT_PARAM, 1
+	}
+	ret := api.SQLBindParameter(h, api.SQLUSMALLINT(idx+1),
+		api.SQL_PARAM_INPUT, api.SQL_C_DEFAULT, api.SQL_SS_TABLE, maxRows, 0,
+		api.SQLPOINTER(unsafe.Pointer(&d.typeName[0])), api.SQL_NTS, p.StoreStrLen_or_IndPtr(rows))
+	if IsError(ret) {
+		return NewError("SQLBindParameter", h)
+	}
+	if schema != "" {
+		desc, err := stmtDesc(h, api.SQL_ATTR_IMP_PARAM_DESC)
+		if err != nil {
+			return err
+		}
+		d.schema = api.StringToUTF16(schema)
+		ret = api.SQLSetDescField(desc, api.SQLSMALLINT(idx+1), api.SQL_CA_SS_SCHEMA_NAME,
+			api.SQLPOINTER(unsafe.Pointer(&d.schema[0])), api.SQL_NTS)
+		if IsError(ret) {
+			return NewError("SQLSetDescField", desc)
+		}
+	}
+
+	ret = api.SQLSetStmtUIntPtrAttr(h, api.SQL_SOPT_SS_PARAM_FOCUS, uintptr(idx+1), api.SQL_IS_INTEGER)
+	if IsError(ret) {
+		return NewError("SQLSetStmtUIntPtrAttr", h)
+	}
+	err = d.bindColumns(h)
+	// Always return focus to statement parameters.
+	ret = api.SQLSetStmtUIntPtrAttr(h, api.SQL_SOPT_SS_PARAM_FOCUS, 0, api.SQL_IS_INTEGER)
+	if err != nil {
+		return err
+	}
+	if IsError(ret) {
+		return NewError("SQLSetStmtUIntPtrAttr", h)
+	}
+	return nil
+}
+
+// bindColumns binds table-valued parameter columns. Parameter
+// focus must be set to table-valued parameter already.
+func (d *tvpData) bindColumns(h api.SQLHSTMT) error {
+	for i, c := range d.cols {
+		ret := api.SQLBindParameter(h, api.SQLUSMALLINT(i+1),
+			api.SQL_PARAM_INPUT, c.ctype, c.sqltype, c.size, c.decimal,
+			api.SQLPOINTER(unsafe.Pointer(&c.data[0])), api.SQLLEN(c.width), &c.ind[0])
+		if IsError(ret) {
+			return NewError("SQLBindParameter", h)
+		}
+	}
+	return nil
+}
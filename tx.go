@@ -5,21 +5,25 @@
 package odbc
 
 import (
+	"context"
+	"database/sql"
 	"database/sql/driver"
 	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/alexbrainman/odbc/api"
 )
 
 type Tx struct {
-	c *Conn
+	c        *Conn
+	readOnly bool
+	name     string // name of SQL Server transaction, see WithTxName
 }
 
-var testBeginErr error // used during tests
-
 func (c *Conn) setAutoCommitAttr(a uintptr) error {
-	if testBeginErr != nil {
-		return testBeginErr
+	if c.hooks.setAutoCommit != nil {
+		return c.hooks.setAutoCommit(a)
 	}
 	ret := api.SQLSetConnectUIntPtrAttr(c.h, api.SQL_ATTR_AUTOCOMMIT, a, api.SQL_IS_UINTEGER)
 	if IsError(ret) {
@@ -29,16 +33,234 @@ func (c *Conn) setAutoCommitAttr(a uintptr) error {
 }
 
 func (c *Conn) Begin() (driver.Tx, error) {
-	if c.bad {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+var sqlIsolationLevel = map[sql.IsolationLevel]uintptr{
+	sql.LevelReadUncommitted: api.SQL_TXN_READ_UNCOMMITTED,
+	sql.LevelReadCommitted:   api.SQL_TXN_READ_COMMITTED,
+	sql.LevelRepeatableRead:  api.SQL_TXN_REPEATABLE_READ,
+	sql.LevelSerializable:    api.SQL_TXN_SERIALIZABLE,
+}
+
+// setIsolationLevel sets transaction isolation level of connection c.
+// sql.LevelDefault leaves isolation level unchanged. sql.LevelSnapshot
+// is only supported by SQL Server, where it is mapped to
+// SQL_TXN_SS_SNAPSHOT. Any other level, that has no ODBC equivalent,
+// is an error.
+func (c *Conn) setIsolationLevel(level sql.IsolationLevel) error {
+	if level == sql.LevelDefault {
+		return nil
+	}
+	v, ok := sqlIsolationLevel[level]
+	if level == sql.LevelSnapshot {
+		mssql, err := c.isMSSQL()
+		if err != nil {
+			return err
+		}
+		if !mssql {
+			return fmt.Errorf("%v isolation level is only supported by SQL Server", level)
+		}
+		v, ok = api.SQL_TXN_SS_SNAPSHOT, true
+	}
+	if !ok {
+		return fmt.Errorf("unsupported isolation level %v", level)
+	}
+	if !c.isolationChanged {
+		saved, err := c.GetAttr(api.SQL_ATTR_TXN_ISOLATION)
+		if err != nil {
+			return err
+		}
+		c.savedIsolation = saved
+	}
+	ret := api.SQLSetConnectUIntPtrAttr(c.h, api.SQL_ATTR_TXN_ISOLATION, v, api.SQL_IS_UINTEGER)
+	if IsError(ret) {
+		return c.newError("SQLSetConnectUIntPtrAttr", c.h)
+	}
+	c.isolationChanged = true
+	return nil
+}
+
+// SetIsolationLevel sets transaction isolation level of connection c
+// for statements executed outside of transactions (in autocommit
+// mode). It cannot be called inside transaction, use sql.TxOptions
+// with BeginTx instead. Levels are the same as BeginTx accepts.
+// database/sql restores original level, before connection is
+// reused, see ResetSession. Use sql.Conn.Raw to call it, like
+//
+//	err := conn.Raw(func(dc interface{}) error {
+//		return dc.(*odbc.Conn).SetIsolationLevel(sql.LevelReadUncommitted)
+//	})
+func (c *Conn) SetIsolationLevel(level sql.IsolationLevel) error {
+	if c.tx != nil {
+		return errors.New("cannot change isolation level inside transaction")
+	}
+	return c.setIsolationLevel(level)
+}
+
+// resetIsolationLevel restores isolation level of connection c,
+// if it was changed by setIsolationLevel.
+func (c *Conn) resetIsolationLevel() error {
+	if !c.isolationChanged {
+		return nil
+	}
+	ret := api.SQLSetConnectUIntPtrAttr(c.h, api.SQL_ATTR_TXN_ISOLATION, c.savedIsolation, api.SQL_IS_UINTEGER)
+	if IsError(ret) {
+		return c.newError("SQLSetConnectUIntPtrAttr", c.h)
+	}
+	c.isolationChanged = false
+	return nil
+}
+
+func (c *Conn) setAccessMode(readOnly bool) error {
+	mode := api.SQL_MODE_READ_WRITE
+	if readOnly {
+		mode = api.SQL_MODE_READ_ONLY
+	}
+	ret := api.SQLSetConnectUIntPtrAttr(c.h, api.SQL_ATTR_ACCESS_MODE, mode, api.SQL_IS_UINTEGER)
+	if IsError(ret) {
+		return c.newError("SQLSetConnectUIntPtrAttr", c.h)
+	}
+	return nil
+}
+
+// writeKeywords are keywords of statements, that can change data or
+// schema. Procedure calls are included, because they can do anything.
+// REPLACE (MySQL and SQLite) is only a keyword at the start of
+// statement, elsewhere it is usually REPLACE string function.
+var writeKeywords = map[string]bool{
+	"INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true,
+	"UPSERT": true, "TRUNCATE": true,
+	"CREATE": true, "ALTER": true, "DROP": true, "RENAME": true,
+	"GRANT": true, "REVOKE": true,
+	"EXEC": true, "EXECUTE": true, "CALL": true,
+}
+
+// findWriteKeyword returns first of writeKeywords found in query,
+// or empty string. Words inside string literals, quoted identifiers
+// and comments are ignored, see quoteEnd for brackets.
+func findWriteKeyword(query string, brackets bool) string {
+	isWordChar := func(c byte) bool {
+		return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+	}
+	first := true
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		end := quoteEnd(query, i, brackets)
+		switch {
+		case end != "":
+		case isWordChar(c):
+			j := i + 1
+			for j < len(query) && isWordChar(query[j]) {
+				j++
+			}
+			w := strings.ToUpper(query[i:j])
+			if writeKeywords[w] || first && w == "REPLACE" {
+				return w
+			}
+			first = false
+			i = j - 1
+			continue
+		case c == ';':
+			first = true
+			continue
+		default:
+			continue
+		}
+		j := strings.Index(query[i+1:], end)
+		if j < 0 {
+			return ""
+		}
+		i += j + len(end)
+	}
+	return ""
+}
+
+// checkReadOnly returns error, if connection c is in read-only
+// transaction, and query looks like it changes something. The
+// check is only done with read_only_check=true connection option,
+// because many drivers ignore SQL_MODE_READ_ONLY access mode.
+func (c *Conn) checkReadOnly(query string) error {
+	if !c.opts.readOnlyCheck || c.tx == nil || !c.tx.readOnly {
+		return nil
+	}
+	if w := findWriteKeyword(query, c.bracketQuotes()); w != "" {
+		return fmt.Errorf("%s statement is not allowed in read-only transaction", w)
+	}
+	return nil
+}
+
+type txNameKey struct{}
+
+// WithTxName returns copy of ctx, that makes BeginTx start SQL Server
+// transaction called name. Transaction name is visible in
+// sys.dm_tran_active_transactions, so it helps to correlate
+// transactions while debugging. Name can be up to 32 characters
+// long, and only letters, digits and underscores are allowed.
+func WithTxName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, txNameKey{}, name)
+}
+
+// checkTxName verifies that transaction name can be used
+// with connection c.
+func (c *Conn) checkTxName(name string) error {
+	if !isSavepointName(name) || len(name) > 32 {
+		return fmt.Errorf("invalid transaction name %q", name)
+	}
+	mssql, err := c.isMSSQL()
+	if err != nil {
+		return err
+	}
+	if !mssql {
+		return errors.New("transaction names are only supported by SQL Server")
+	}
+	return nil
+}
+
+// BeginTx implements driver.ConnBeginTx interface.
+// Use sql.LevelSnapshot to start SQL Server SNAPSHOT transaction,
+// and WithTxName to give SQL Server transaction a name.
+func (c *Conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if c.bad.Load() {
 		return nil, driver.ErrBadConn
 	}
 	if c.tx != nil {
 		return nil, errors.New("already in a transaction")
 	}
-	c.tx = &Tx{c: c}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	name, _ := ctx.Value(txNameKey{}).(string)
+	if name != "" {
+		if err := c.checkTxName(name); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.setIsolationLevel(sql.IsolationLevel(opts.Isolation)); err != nil {
+		return nil, err
+	}
+	if opts.ReadOnly {
+		if err := c.setAccessMode(true); err != nil {
+			return nil, err
+		}
+	}
+	c.tx = &Tx{c: c, readOnly: opts.ReadOnly, name: name}
+	if name != "" {
+		// Transaction, that driver starts when autocommit is off,
+		// cannot be named. So leave autocommit on, and use
+		// BEGIN, COMMIT and ROLLBACK TRANSACTION statements.
+		if err := c.execQuery("BEGIN TRANSACTION " + name); err != nil {
+			c.tx = nil
+			if opts.ReadOnly {
+				c.setAccessMode(false)
+			}
+			return nil, err
+		}
+		return c.tx, nil
+	}
 	err := c.setAutoCommitAttr(api.SQL_AUTOCOMMIT_OFF)
 	if err != nil {
-		c.bad = true
+		c.bad.Store(true)
 		return nil, err
 	}
 	return c.tx, nil
@@ -48,6 +270,17 @@ func (c *Conn) endTx(commit bool) error {
 	if c.tx == nil {
 		return errors.New("not in a transaction")
 	}
+	if name := c.tx.name; name != "" {
+		q := "ROLLBACK TRANSACTION " + name
+		if commit {
+			q = "COMMIT TRANSACTION " + name
+		}
+		if err := c.execQuery(q); err != nil {
+			c.bad.Store(true)
+			return err
+		}
+		return c.restoreAfterTx()
+	}
 	var howToEnd api.SQLSMALLINT
 	if commit {
 		howToEnd = api.SQL_COMMIT
@@ -56,18 +289,80 @@ func (c *Conn) endTx(commit bool) error {
 	}
 	ret := api.SQLEndTran(api.SQL_HANDLE_DBC, api.SQLHANDLE(c.h), howToEnd)
 	if IsError(ret) {
-		c.bad = true
+		c.bad.Store(true)
 		return c.newError("SQLEndTran", c.h)
 	}
-	c.tx = nil
+	if err := c.restoreAfterTx(); err != nil {
+		return err
+	}
 	err := c.setAutoCommitAttr(api.SQL_AUTOCOMMIT_ON)
 	if err != nil {
-		c.bad = true
+		c.bad.Store(true)
 		return err
 	}
 	return nil
 }
 
+// restoreAfterTx forgets ended transaction and
+// restores connection access mode, if needed.
+func (c *Conn) restoreAfterTx() error {
+	readOnly := c.tx.readOnly
+	c.tx = nil
+	if readOnly {
+		if err := c.setAccessMode(false); err != nil {
+			c.bad.Store(true)
+			return err
+		}
+	}
+	return nil
+}
+
+// resetState returns connection c to the state it had after it
+// was opened, see ResetSession.
+func (c *Conn) resetState() error {
+	if c.tx != nil {
+		if err := c.endTx(false); err != nil {
+			return err
+		}
+	}
+	if err := c.resetAutoCommit(); err != nil {
+		return err
+	}
+	// not every driver reports access mode
+	if mode, err := c.GetAttr(api.SQL_ATTR_ACCESS_MODE); err == nil && mode != api.SQL_MODE_READ_WRITE {
+		if err := c.setAccessMode(false); err != nil {
+			return err
+		}
+	}
+	if err := c.resetIsolationLevel(); err != nil {
+		return err
+	}
+	return c.resetCatalog()
+}
+
+// resetAutoCommit rolls back work of c and turns autocommit mode
+// on, if autocommit was turned off outside of transaction.
+// Autocommit mode is assumed unchanged, if driver does not
+// report it.
+func (c *Conn) resetAutoCommit() error {
+	ac, err := c.GetAttr(api.SQL_ATTR_AUTOCOMMIT)
+	if err != nil {
+		if isNotSupported(err) {
+			return nil
+		}
+		return err
+	}
+	if ac == api.SQL_AUTOCOMMIT_ON {
+		return nil
+	}
+	// autocommit was turned off behind package back
+	ret := api.SQLEndTran(api.SQL_HANDLE_DBC, api.SQLHANDLE(c.h), api.SQL_ROLLBACK)
+	if IsError(ret) {
+		return c.newError("SQLEndTran", c.h)
+	}
+	return c.setAutoCommitAttr(api.SQL_AUTOCOMMIT_ON)
+}
+
 func (tx *Tx) Commit() error {
 	return tx.c.endTx(true)
 }
@@ -75,3 +370,162 @@ func (tx *Tx) Commit() error {
 func (tx *Tx) Rollback() error {
 	return tx.c.endTx(false)
 }
+
+// ErrTxRolledBack is reported by CommitAll for transactions, that
+// are rolled back, because commit of previous transaction failed.
+var ErrTxRolledBack = errors.New("transaction is rolled back, because previous commit failed")
+
+// TxEndError is returned by CommitAll and RollbackAll, when not
+// every transaction is ended as requested.
+type TxEndError struct {
+	// Errs has error of every transaction in the order transactions
+	// are passed. It is nil for transactions ended as requested.
+	Errs []error
+	// Committed is number of transactions committed by CommitAll.
+	Committed int
+}
+
+func (e *TxEndError) Error() string {
+	n := 0
+	var first error
+	for _, err := range e.Errs {
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			n++
+		}
+	}
+	return fmt.Sprintf("%d of %d transactions failed (%d committed): %v", n, len(e.Errs), e.Committed, first)
+}
+
+// CommitAll commits transactions txs, that usually belong to different
+// connections, one after another. If commit fails, the rest of
+// transactions are rolled back, and *TxEndError is returned, that
+// tells which transactions are committed. This is not a distributed
+// transaction: transactions committed before the failure stay
+// committed.
+func CommitAll(txs ...*Tx) error {
+	for i, tx := range txs {
+		err := tx.Commit()
+		if err == nil {
+			continue
+		}
+		errs := make([]error, len(txs))
+		errs[i] = err
+		for j := i + 1; j < len(txs); j++ {
+			errs[j] = ErrTxRolledBack
+			if err := txs[j].Rollback(); err != nil {
+				errs[j] = err
+			}
+		}
+		return &TxEndError{Errs: errs, Committed: i}
+	}
+	return nil
+}
+
+// RollbackAll rolls back every transaction of txs. It returns
+// *TxEndError, if any of them fails.
+func RollbackAll(txs ...*Tx) error {
+	errs := make([]error, len(txs))
+	failed := false
+	for i, tx := range txs {
+		if err := tx.Rollback(); err != nil {
+			errs[i] = err
+			failed = true
+		}
+	}
+	if failed {
+		return &TxEndError{Errs: errs}
+	}
+	return nil
+}
+
+// isSavepointName reports whether name can be used as savepoint name.
+// Only letters, digits and underscores are allowed, because name is
+// inserted into SQL text as is.
+func isSavepointName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_', 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z':
+		case '0' <= r && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+const (
+	savepointCreate = iota
+	savepointRollback
+	savepointRelease
+)
+
+// savepointSQL returns SQL statement that performs savepoint
+// operation op on savepoint name. It returns empty string, if
+// the backend has no use for the operation.
+func (c *Conn) savepointSQL(op int, name string) (string, error) {
+	mssql, err := c.isMSSQL()
+	if err != nil {
+		return "", err
+	}
+	if mssql {
+		switch op {
+		case savepointCreate:
+			return "SAVE TRANSACTION " + name, nil
+		case savepointRollback:
+			return "ROLLBACK TRANSACTION " + name, nil
+		}
+		// SQL Server releases savepoints at the end of transaction.
+		return "", nil
+	}
+	switch op {
+	case savepointCreate:
+		return "SAVEPOINT " + name, nil
+	case savepointRollback:
+		return "ROLLBACK TO SAVEPOINT " + name, nil
+	}
+	return "RELEASE SAVEPOINT " + name, nil
+}
+
+func (tx *Tx) savepoint(op int, name string) error {
+	c := tx.c
+	if c.tx != tx {
+		return errors.New("not in a transaction")
+	}
+	if c.bad.Load() {
+		return driver.ErrBadConn
+	}
+	if !isSavepointName(name) {
+		return fmt.Errorf("invalid savepoint name %q", name)
+	}
+	q, err := c.savepointSQL(op, name)
+	if err != nil || q == "" {
+		return err
+	}
+	return c.execQuery(q)
+}
+
+// Savepoint creates savepoint name inside transaction tx.
+// SAVE TRANSACTION is used on SQL Server, and SAVEPOINT
+// everywhere else.
+func (tx *Tx) Savepoint(name string) error {
+	return tx.savepoint(savepointCreate, name)
+}
+
+// RollbackToSavepoint undoes all changes made in transaction tx
+// after savepoint name was created. Transaction remains open.
+func (tx *Tx) RollbackToSavepoint(name string) error {
+	return tx.savepoint(savepointRollback, name)
+}
+
+// ReleaseSavepoint removes savepoint name from transaction tx.
+// It does nothing on SQL Server, that has no way to release
+// savepoints before transaction ends.
+func (tx *Tx) ReleaseSavepoint(name string) error {
+	return tx.savepoint(savepointRelease, name)
+}